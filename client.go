@@ -2,9 +2,10 @@ package stereoscope
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/wagoodman/go-partybus"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/anchore/go-logger"
 	"github.com/anchore/stereoscope/internal/bus"
 	"github.com/anchore/stereoscope/internal/log"
+	"github.com/anchore/stereoscope/pkg/event"
 	"github.com/anchore/stereoscope/pkg/file"
 	"github.com/anchore/stereoscope/pkg/image"
 )
@@ -64,6 +66,75 @@ func WithPlatform(platform string) Option {
 	}
 }
 
+// WithOffline guarantees that no network access is attempted: network-capable providers (those tagged with
+// RegistryTag) are not considered, daemon providers will not pull missing images, and any registry access fails with
+// image.ErrOffline.
+func WithOffline() Option {
+	return func(c *config) error {
+		c.Offline = true
+		return nil
+	}
+}
+
+// WithPullPolicy sets when daemon providers (docker, podman and containerd) pull the image into the daemon
+// (image.PullIfMissing by default).
+func WithPullPolicy(policy image.PullPolicy) Option {
+	return func(c *config) error {
+		policy, err := image.ParsePullPolicy(string(policy))
+		if err != nil {
+			return err
+		}
+		c.PullPolicy = policy
+		return nil
+	}
+}
+
+// WithRemovePulledImages removes images from the daemon once they have been read, but only for images that were
+// pulled into the daemon by stereoscope (images that were already present are left untouched).
+func WithRemovePulledImages() Option {
+	return func(c *config) error {
+		c.RemovePulledImages = true
+		return nil
+	}
+}
+
+// WithRequireDigest requires image references to be pinned to a digest (e.g. "alpine@sha256:..."). Providers that
+// resolve image references (all but file providers) fail with image.ErrReferenceNotPinned otherwise. Note: the image
+// obtained for a digest-pinned reference is always verified against the pinned digest.
+func WithRequireDigest() Option {
+	return func(c *config) error {
+		c.RequireDigest = true
+		return nil
+	}
+}
+
+// WithReadLimits bounds the resources consumed while reading the image, overriding any default limits (see
+// image.ReadLimits and image.SetDefaultReadLimits).
+func WithReadLimits(limits image.ReadLimits) Option {
+	return func(c *config) error {
+		c.ReadOptions = append(c.ReadOptions, image.WithReadLimits(limits))
+		return nil
+	}
+}
+
+// WithDerivedFileIDs assigns file references IDs derived from the layer digest and file path, so that the same image
+// yields the same file IDs each time it is read (see image.WithDerivedFileIDs).
+func WithDerivedFileIDs() Option {
+	return func(c *config) error {
+		c.ReadOptions = append(c.ReadOptions, image.WithDerivedFileIDs())
+		return nil
+	}
+}
+
+// WithLayerVerification verifies the content of each layer against the digests within the image config and manifest
+// while the layer is cached, overriding the verification the provider would otherwise perform.
+func WithLayerVerification(verification image.LayerVerification) Option {
+	return func(c *config) error {
+		c.ReadOptions = append(c.ReadOptions, image.WithLayerVerification(verification))
+		return nil
+	}
+}
+
 // GetImage parses the user provided image string and provides an image object;
 // note: the source where the image should be referenced from is automatically inferred.
 func GetImage(ctx context.Context, imgStr string, options ...Option) (*image.Image, error) {
@@ -95,28 +166,65 @@ func getImageFromSource(ctx context.Context, imgStr string, source image.Source,
 			UserInput: imgStr,
 			Platform:  cfg.Platform,
 			Registry:  cfg.Registry,
+			Offline:   cfg.Offline,
+
+			PullPolicy:         cfg.PullPolicy,
+			RemovePulledImages: cfg.RemovePulledImages,
+			ReadOptions:        cfg.ReadOptions,
 		})...,
 	)
 	if source != "" {
 		source = strings.ToLower(strings.TrimSpace(source))
 		providers = providers.Select(source)
 		if len(providers) == 0 {
+			if cfg.Offline && slices.Contains(allProviderTags(), source) {
+				return nil, fmt.Errorf("%w: image providers matching '%s' require network access", image.ErrOffline, source)
+			}
 			return nil, fmt.Errorf("unable to find image providers matching: '%s'", source)
 		}
 	}
 
-	var errs []error
-	for _, provider := range providers.Values() {
-		img, err := provider.Provide(ctx)
-		if err != nil {
-			errs = append(errs, err)
-		}
+	report := image.ProviderResolutionReport{
+		UserInput: imgStr,
+		Source:    source,
+	}
+
+	for _, tagged := range providers {
+		provider := tagged.Value
+		startTime := time.Now()
+		img, err := provideImage(ctx, provider, imgStr, cfg.RequireDigest && !tagged.HasTag(FileTag))
+		attempt := image.NewProviderAttempt(provider.Name(), time.Since(startTime), err)
+		report.Attempts = append(report.Attempts, attempt)
+		log.WithFields("provider", attempt.Provider, "applicable", attempt.Applicable, "time", attempt.Duration, "error", err).Trace("attempted image provider")
+
 		if img != nil {
+			publishProviderResolution(report)
 			err = applyAdditionalMetadata(img, cfg.AdditionalMetadata...)
 			return img, err
 		}
 	}
-	return nil, fmt.Errorf("unable to detect input for '%s', errs: %w", imgStr, errors.Join(errs...))
+	publishProviderResolution(report)
+	return nil, &image.ErrProviderResolution{Report: report}
+}
+
+// provideImage gets the image from the given provider. When requireDigest is set, user input that is not pinned to a
+// digest is rejected (note: providers verify the image against any digest the user input is pinned to).
+func provideImage(ctx context.Context, provider image.Provider, imgStr string, requireDigest bool) (*image.Image, error) {
+	if requireDigest {
+		if err := image.RequirePinnedDigest(imgStr); err != nil {
+			return nil, err
+		}
+	}
+	return provider.Provide(ctx)
+}
+
+// publishProviderResolution notifies consumers of which image providers were tried and the outcome of each attempt.
+func publishProviderResolution(report image.ProviderResolutionReport) {
+	bus.Publish(partybus.Event{
+		Type:   event.ResolveImageProvider,
+		Source: report.UserInput,
+		Value:  report,
+	})
 }
 
 func SetLogger(logger logger.Logger) {
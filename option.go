@@ -12,7 +12,12 @@ type Option func(*config) error
 type config struct {
 	Registry           image.RegistryOptions
 	AdditionalMetadata []image.AdditionalMetadata
+	ReadOptions        []image.AdditionalMetadata
 	Platform           *image.Platform
+	Offline            bool
+	PullPolicy         image.PullPolicy
+	RemovePulledImages bool
+	RequireDigest      bool
 }
 
 func applyOptions(cfg *config, options ...Option) error {
@@ -5,9 +5,11 @@ import (
 )
 
 const (
-	PullDockerImage     partybus.EventType = "pull-docker-image-event"
-	PullContainerdImage partybus.EventType = "pull-containerd-image-event"
-	FetchImage          partybus.EventType = "fetch-image-event"
-	ReadImage           partybus.EventType = "read-image-event"
-	ReadLayer           partybus.EventType = "read-layer-event"
+	PullDockerImage      partybus.EventType = "pull-docker-image-event"
+	PullContainerdImage  partybus.EventType = "pull-containerd-image-event"
+	FetchImage           partybus.EventType = "fetch-image-event"
+	ReadImage            partybus.EventType = "read-image-event"
+	ReadLayer            partybus.EventType = "read-layer-event"
+	ResolveImageProvider partybus.EventType = "resolve-image-provider-event"
+	RegistryRateLimit    partybus.EventType = "registry-rate-limit-event"
 )
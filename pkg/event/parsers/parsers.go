@@ -126,3 +126,29 @@ func ParseReadLayer(e partybus.Event) (*image.LayerMetadata, progress.Monitorabl
 
 	return &layerMetadata, prog, nil
 }
+
+func ParseResolveImageProvider(e partybus.Event) (*image.ProviderResolutionReport, error) {
+	if err := checkEventType(e.Type, event.ResolveImageProvider); err != nil {
+		return nil, err
+	}
+
+	report, ok := e.Value.(image.ProviderResolutionReport)
+	if !ok {
+		return nil, newPayloadErr(e.Type, "Value", e.Value)
+	}
+
+	return &report, nil
+}
+
+func ParseRegistryRateLimit(e partybus.Event) (*image.RateLimitStatus, error) {
+	if err := checkEventType(e.Type, event.RegistryRateLimit); err != nil {
+		return nil, err
+	}
+
+	status, ok := e.Value.(image.RateLimitStatus)
+	if !ok {
+		return nil, newPayloadErr(e.Type, "Value", e.Value)
+	}
+
+	return &status, nil
+}
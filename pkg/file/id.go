@@ -1,9 +1,18 @@
 package file
 
-import "sync/atomic"
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"strconv"
+	"sync/atomic"
+)
 
 var nextID atomic.Uint64 // note: this is governed by the reference constructor
 
+// derivedIDBit is set on all IDs from NewDerivedID, which keeps them disjoint from the sequential IDs assigned by
+// NewFileReference (thus both schemes can be mixed within a process without collisions).
+const derivedIDBit = 1 << 63
+
 // ID is used for file tree manipulation to uniquely identify tree nodes.
 type ID uint64
 
@@ -20,3 +29,22 @@ func (ids IDs) Less(i, j int) bool {
 func (ids IDs) Swap(i, j int) {
 	ids[i], ids[j] = ids[j], ids[i]
 }
+
+// NewDerivedID returns a deterministic ID derived from the digest of the layer providing the file, the real path of
+// the file, and the sequence of the file entry within the layer. This means the same file in the same layer always
+// has the same ID, regardless of the process or the order layers are read in (which is useful for caching or diffing
+// results across processes).
+func NewDerivedID(layerDigest string, realPath Path, sequence int64) ID {
+	h := sha256.New()
+	h.Write([]byte(layerDigest))
+	h.Write([]byte{0})
+	h.Write([]byte(realPath))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatInt(sequence, 10)))
+	return ID(binary.BigEndian.Uint64(h.Sum(nil)) | derivedIDBit)
+}
+
+// IsDerived indicates if the ID was created with NewDerivedID.
+func (i ID) IsDerived() bool {
+	return i&derivedIDBit != 0
+}
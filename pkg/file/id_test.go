@@ -0,0 +1,21 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDerivedID(t *testing.T) {
+	id := NewDerivedID("sha256:abc", "/etc/passwd", 3)
+
+	assert.Equal(t, id, NewDerivedID("sha256:abc", "/etc/passwd", 3), "derived IDs must be deterministic")
+	assert.True(t, id.IsDerived())
+
+	assert.NotEqual(t, id, NewDerivedID("sha256:def", "/etc/passwd", 3), "layer digest must be considered")
+	assert.NotEqual(t, id, NewDerivedID("sha256:abc", "/etc/group", 3), "path must be considered")
+	assert.NotEqual(t, id, NewDerivedID("sha256:abc", "/etc/passwd", 4), "sequence must be considered")
+
+	// sequential IDs never collide with derived IDs
+	assert.False(t, NewFileReference("/etc/passwd").ID().IsDerived())
+}
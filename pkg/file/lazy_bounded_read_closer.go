@@ -76,33 +76,36 @@ func (d *lazyBoundedReadCloser) Close() error {
 
 func (d *lazyBoundedReadCloser) Seek(offset int64, whence int) (int64, error) {
 	// let Read determine further EOF state
+	wasEOF := d.isEOF
 	d.isEOF = false
 
 	if err := d.openFile(); err != nil {
 		return 0, err
 	}
 
+	if wasEOF && whence == io.SeekCurrent {
+		// the reader is released at EOF, so the current position is the end of the section (not the start of the
+		// newly opened reader)
+		offset += d.size
+		whence = io.SeekStart
+	}
+
 	return d.reader.Seek(offset, whence)
 }
 
+// ReadAt implements the io.ReaderAt interface. Note: this does not depend on or affect the offset used by Read and
+// Seek, thus reaching the end of the section does not release the file descriptor.
 func (d *lazyBoundedReadCloser) ReadAt(b []byte, off int64) (n int, err error) {
-	// let Read determine further EOF state
-	d.isEOF = false
-
-	if err := d.openFile(); err != nil {
-		return 0, err
+	if d.isClosed {
+		return 0, os.ErrClosed
 	}
-
-	n, err = d.reader.ReadAt(b, off)
-	if err != nil && errors.Is(err, io.EOF) {
-		d.isEOF = true
-		d.reader = nil // IMPORTANT: this needs to be unset so opneFile continues to work when appropriate
-		// we've reached the end of the file, release of the file descriptor. continue to return EOF
-		if closeErr := d.file.Close(); closeErr != nil {
-			log.Tracef("unable to close: %v: %v", d.path, closeErr)
+	if d.reader == nil {
+		if err := d.open(); err != nil {
+			return 0, err
 		}
 	}
-	return n, err
+
+	return d.reader.ReadAt(b, off)
 }
 
 func (d *lazyBoundedReadCloser) openFile() error {
@@ -115,7 +118,10 @@ func (d *lazyBoundedReadCloser) openFile() error {
 	if d.reader != nil {
 		return nil
 	}
+	return d.open()
+}
 
+func (d *lazyBoundedReadCloser) open() error {
 	file, err := os.Open(d.path)
 	if err != nil {
 		return err
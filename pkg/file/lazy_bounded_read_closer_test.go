@@ -87,3 +87,34 @@ func TestDeferredPartialReadCloser_PartialRead(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, contents[start:start+size], actualContents)
 }
+
+func TestDeferredPartialReadCloser_ReadAtAndSeekAfterEOF(t *testing.T) {
+	p := testutil.GetFixturePath(t, "a-file.txt")
+	contents := getFixture(t, p)
+	size := int64(len(contents))
+
+	dReader := newLazyBoundedReadCloser(p, 0, size)
+
+	// reading past the end with ReadAt does not affect sequential reads
+	buf := make([]byte, 4)
+	n, err := dReader.ReadAt(buf, size-2)
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, 2, n)
+
+	actualContents, err := io.ReadAll(dReader)
+	require.NoError(t, err)
+	require.Equal(t, contents, actualContents)
+
+	// seeking relative to the current position after EOF is relative to the end of the section
+	pos, err := dReader.Seek(-3, io.SeekCurrent)
+	require.NoError(t, err)
+	require.Equal(t, size-3, pos)
+
+	tail, err := io.ReadAll(dReader)
+	require.NoError(t, err)
+	require.Equal(t, contents[size-3:], tail)
+
+	require.NoError(t, dReader.Close())
+	_, err = dReader.ReadAt(buf, 0)
+	require.ErrorIs(t, err, os.ErrClosed)
+}
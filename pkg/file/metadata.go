@@ -4,18 +4,27 @@ import (
 	"archive/tar"
 	"io"
 	"io/fs"
+	"maps"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/sylabs/squashfs"
+	"github.com/sylabs/squashfs/low/inode"
 
 	"github.com/anchore/stereoscope/internal/log"
 )
 
 var _ fs.FileInfo = (*ManualInfo)(nil)
 
+// CapabilitiesXattr is the extended attribute holding the file capabilities of an executable.
+const CapabilitiesXattr = "security.capability"
+
+// paxXattrPrefix is the PAX record prefix used to store extended attributes in a tar header.
+const paxXattrPrefix = "SCHILY.xattr."
+
 // Metadata represents all file metadata of interest.
 type Metadata struct {
 	fs.FileInfo
@@ -28,6 +37,19 @@ type Metadata struct {
 	GroupID         int
 	Type            Type
 	MIMEType        string
+	// Xattrs are the extended attributes of the file (e.g. file capabilities, SELinux labels, and ACLs), keyed by
+	// attribute name (e.g. "security.capability"). Values are the raw attribute bytes.
+	Xattrs map[string]string
+	// DeviceMajor is the device major number, populated only for character and block devices
+	DeviceMajor int64
+	// DeviceMinor is the device minor number, populated only for character and block devices
+	DeviceMinor int64
+}
+
+// HasCapabilities indicates if the file has file capabilities set (see CapabilitiesXattr).
+func (m Metadata) HasCapabilities() bool {
+	_, ok := m.Xattrs[CapabilitiesXattr]
+	return ok
 }
 
 type ManualInfo struct {
@@ -63,15 +85,77 @@ func (m ManualInfo) Sys() any {
 }
 
 func NewMetadata(header tar.Header, content io.Reader) Metadata {
-	return Metadata{
+	ty := TypeFromTarType(header.Typeflag)
+	m := Metadata{
 		FileInfo:        header.FileInfo(),
 		Path:            path.Clean(DirSeparator + header.Name),
-		Type:            TypeFromTarType(header.Typeflag),
+		Type:            ty,
 		LinkDestination: header.Linkname,
 		UserID:          header.Uid,
 		GroupID:         header.Gid,
 		MIMEType:        MIMEType(content),
+		Xattrs:          xattrsFromPAXRecords(header.PAXRecords),
 	}
+	if ty == TypeCharacterDevice || ty == TypeBlockDevice {
+		m.DeviceMajor = header.Devmajor
+		m.DeviceMinor = header.Devminor
+	}
+	return m
+}
+
+// xattrsFromPAXRecords returns all extended attributes stored within the given PAX records (nil if there are none).
+func xattrsFromPAXRecords(records map[string]string) map[string]string {
+	var xattrs map[string]string
+	for key, value := range records {
+		name, ok := strings.CutPrefix(key, paxXattrPrefix)
+		if !ok || name == "" {
+			continue
+		}
+		if xattrs == nil {
+			xattrs = make(map[string]string)
+		}
+		xattrs[name] = value
+	}
+	return xattrs
+}
+
+// NewMetadataFromSquashFS populates Metadata for the entry at path within the given SquashFS filesystem, with details
+// from f. Unlike NewMetadataFromSquashFSFile, this additionally populates device numbers when fsys is a
+// *squashfs.Reader. Note: extended attributes are not populated since they are not exposed by the SquashFS reader.
+func NewMetadataFromSquashFS(fsys fs.FS, path string, f *squashfs.File) (Metadata, error) {
+	md, err := NewMetadataFromSquashFSFile(path, f)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	r, ok := fsys.(*squashfs.Reader)
+	if !ok || (md.Type != TypeCharacterDevice && md.Type != TypeBlockDevice) {
+		return md, nil
+	}
+
+	base, err := r.Low.Root.Open(&r.Low, path)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	var dev uint32
+	switch data := base.Inode.Data.(type) {
+	case inode.Device:
+		dev = data.Dev
+	case inode.EDevice:
+		dev = data.Dev
+	}
+	md.DeviceMajor, md.DeviceMinor = squashFSDeviceNumbers(dev)
+
+	return md, nil
+}
+
+// squashFSDeviceNumbers decodes the major and minor numbers from a SquashFS device number, which uses the Linux
+// "new_encode_dev" encoding.
+func squashFSDeviceNumbers(dev uint32) (int64, int64) {
+	major := (dev >> 8) & 0xfff
+	minor := (dev & 0xff) | ((dev >> 12) & 0xfff00)
+	return int64(major), int64(minor)
 }
 
 // NewMetadataFromSquashFSFile populates Metadata for the entry at path, with details from f.
@@ -161,6 +245,9 @@ func (m Metadata) Equal(other Metadata) bool {
 		m.GroupID == other.GroupID &&
 		m.Type == other.Type &&
 		m.MIMEType == other.MIMEType &&
+		maps.Equal(m.Xattrs, other.Xattrs) &&
+		m.DeviceMajor == other.DeviceMajor &&
+		m.DeviceMinor == other.DeviceMinor &&
 		m.Name() == other.Name() &&
 		m.IsDir() == other.IsDir() &&
 		m.Mode() == other.Mode() &&
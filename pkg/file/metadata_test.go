@@ -3,6 +3,7 @@
 package file
 
 import (
+	"archive/tar"
 	"io"
 	"os"
 	"strings"
@@ -205,3 +206,100 @@ func TestFileMetadataFromPath(t *testing.T) {
 		})
 	}
 }
+
+func TestNewMetadata_XattrsAndDevices(t *testing.T) {
+	tests := []struct {
+		name             string
+		header           tar.Header
+		wantXattrs       map[string]string
+		wantMajor        int64
+		wantMinor        int64
+		wantCapabilities bool
+	}{
+		{
+			name: "regular file with capabilities and selinux label",
+			header: tar.Header{
+				Name:     "usr/bin/ping",
+				Typeflag: tar.TypeReg,
+				PAXRecords: map[string]string{
+					"SCHILY.xattr.security.capability": "\x01\x00\x00\x02",
+					"SCHILY.xattr.security.selinux":    "system_u:object_r:ping_exec_t:s0",
+					"path":                             "usr/bin/ping",
+				},
+			},
+			wantXattrs: map[string]string{
+				"security.capability": "\x01\x00\x00\x02",
+				"security.selinux":    "system_u:object_r:ping_exec_t:s0",
+			},
+			wantCapabilities: true,
+		},
+		{
+			name: "character device",
+			header: tar.Header{
+				Name:     "dev/null",
+				Typeflag: tar.TypeChar,
+				Devmajor: 1,
+				Devminor: 3,
+			},
+			wantMajor: 1,
+			wantMinor: 3,
+		},
+		{
+			name: "block device",
+			header: tar.Header{
+				Name:     "dev/sda",
+				Typeflag: tar.TypeBlock,
+				Devmajor: 8,
+				Devminor: 0,
+			},
+			wantMajor: 8,
+		},
+		{
+			name: "device numbers ignored for regular files",
+			header: tar.Header{
+				Name:     "etc/passwd",
+				Typeflag: tar.TypeReg,
+				Devmajor: 1,
+				Devminor: 3,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := NewMetadata(tt.header, nil)
+			assert.Equal(t, tt.wantXattrs, actual.Xattrs)
+			assert.Equal(t, tt.wantMajor, actual.DeviceMajor)
+			assert.Equal(t, tt.wantMinor, actual.DeviceMinor)
+			assert.Equal(t, tt.wantCapabilities, actual.HasCapabilities())
+		})
+	}
+}
+
+func Test_squashFSDeviceNumbers(t *testing.T) {
+	tests := []struct {
+		name      string
+		dev       uint32
+		wantMajor int64
+		wantMinor int64
+	}{
+		{
+			name:      "dev/null",
+			dev:       0x0103,
+			wantMajor: 1,
+			wantMinor: 3,
+		},
+		{
+			name:      "large minor",
+			dev:       0x12345678,
+			wantMajor: 0x456,
+			wantMinor: 0x12378,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			major, minor := squashFSDeviceNumbers(tt.dev)
+			assert.Equal(t, tt.wantMajor, major)
+			assert.Equal(t, tt.wantMinor, minor)
+		})
+	}
+}
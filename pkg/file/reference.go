@@ -16,6 +16,15 @@ func NewFileReference(path Path) *Reference {
 	}
 }
 
+// NewFileReferenceWithID creates a new file reference for the given path with the given ID (see NewDerivedID). The
+// caller is responsible for the ID being unique within the file trees the reference is used in.
+func NewFileReferenceWithID(path Path, id ID) *Reference {
+	return &Reference{
+		RealPath: path,
+		id:       id,
+	}
+}
+
 // ID returns the unique ID for this file reference.
 func (f *Reference) ID() ID {
 	return f.id
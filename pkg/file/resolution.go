@@ -2,6 +2,7 @@ package file
 
 import (
 	"sort"
+	"strings"
 
 	"github.com/scylladb/go-set/strset"
 )
@@ -13,8 +14,16 @@ type Resolution struct {
 	// LinkResolutions represents the traversal through the filesystem to access to current reference, including all symlink and hardlink resolution.
 	// note: today this only shows resolutions via the basename of the request path, but in the future it may show all resolutions.
 	LinkResolutions []Resolution
+	// ArchiveChain represents the nested archives containing the current reference, ordered from the outermost archive
+	// (found within the image filesystem) to the innermost archive. This is empty for files that are not within a
+	// nested archive.
+	ArchiveChain []Resolution
 }
 
+// NestedArchiveSeparator separates the path of a nested archive from a path within the archive
+// (e.g. "/app/lib/foo.jar!/META-INF/MANIFEST.MF").
+const NestedArchiveSeparator = "!"
+
 type Resolutions []Resolution
 
 // NewResolution create a new Resolution for the given request path, showing the resolved reference (or
@@ -82,6 +91,18 @@ func (f *Resolution) AllPaths() []Path {
 	return results
 }
 
+// NestedRequestPath returns the request path qualified by the request paths of all containing nested archives
+// (e.g. "/app/lib/foo.jar!/META-INF/MANIFEST.MF").
+func (f *Resolution) NestedRequestPath() Path {
+	var sb strings.Builder
+	for _, archive := range f.ArchiveChain {
+		sb.WriteString(string(archive.RequestPath))
+		sb.WriteString(NestedArchiveSeparator)
+	}
+	sb.WriteString(string(f.RequestPath))
+	return Path(sb.String())
+}
+
 func (f *Resolution) AllRequestPaths() []Path {
 	set := strset.New()
 	set.Add(string(f.RequestPath))
@@ -0,0 +1,110 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var _ SeekableReader = (*sizedReader)(nil)
+var _ SeekableReader = (*spooledReader)(nil)
+
+// SeekableReader is a random-access reader over the contents of a single file, with a known size.
+type SeekableReader interface {
+	io.ReadSeekCloser
+	io.ReaderAt
+	// Size returns the size of the file contents in bytes.
+	Size() int64
+}
+
+// ReaderAtCloser is an io.ReaderAt that must be closed after use.
+type ReaderAtCloser interface {
+	io.ReaderAt
+	io.Closer
+}
+
+type readSeekReaderAtCloser interface {
+	io.ReadSeekCloser
+	io.ReaderAt
+}
+
+// NewSeekableReader returns a SeekableReader for the given file contents. If the given reader already supports
+// random access it is used as-is, otherwise the contents are spooled to a temporary file within tempDir which is
+// removed on Close (tempDir should be a directory from a TempDirGenerator so that the file is also removed on cleanup
+// when the reader is never closed; the default temp directory is used when empty). When the size is unknown
+// (negative) it is determined from the contents.
+func NewSeekableReader(rc io.ReadCloser, size int64, tempDir string) (SeekableReader, error) {
+	if r, ok := rc.(readSeekReaderAtCloser); ok {
+		if size < 0 {
+			var err error
+			if size, err = sizeBySeeking(r); err != nil {
+				_ = rc.Close()
+				return nil, err
+			}
+		}
+		return &sizedReader{
+			readSeekReaderAtCloser: r,
+			size:                   size,
+		}, nil
+	}
+	return newSpooledReader(rc, tempDir)
+}
+
+// sizedReader is a SeekableReader backed by a reader that already supports random access.
+type sizedReader struct {
+	readSeekReaderAtCloser
+	size int64
+}
+
+func (r *sizedReader) Size() int64 {
+	return r.size
+}
+
+// spooledReader is a SeekableReader backed by a temporary copy of the file contents.
+type spooledReader struct {
+	*os.File
+	size int64
+}
+
+func newSpooledReader(rc io.ReadCloser, tempDir string) (*spooledReader, error) {
+	defer rc.Close()
+
+	f, err := os.CreateTemp(tempDir, "stereoscope-spool-")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temp file for file contents: %w", err)
+	}
+
+	size, err := io.Copy(f, rc)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("unable to spool file contents: %w", err), f.Close(), os.Remove(f.Name()))
+	}
+
+	return &spooledReader{
+		File: f,
+		size: size,
+	}, nil
+}
+
+func (r *spooledReader) Size() int64 {
+	return r.size
+}
+
+// Close closes and removes the temporary copy of the file contents.
+func (r *spooledReader) Close() error {
+	return errors.Join(r.File.Close(), os.Remove(r.Name()))
+}
+
+func sizeBySeeking(s io.Seeker) (int64, error) {
+	size, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
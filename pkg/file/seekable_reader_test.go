@@ -0,0 +1,94 @@
+package file
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anchore/stereoscope/internal/testutil"
+)
+
+func TestNewSeekableReader(t *testing.T) {
+	p := testutil.GetFixturePath(t, "a-file.txt")
+	contents := getFixture(t, p)
+
+	tests := []struct {
+		name        string
+		reader      func() io.ReadCloser
+		size        int64
+		wantSpooled bool
+	}{
+		{
+			name: "random access reader with known size",
+			reader: func() io.ReadCloser {
+				return newLazyBoundedReadCloser(p, 0, int64(len(contents)))
+			},
+			size: int64(len(contents)),
+		},
+		{
+			name: "random access reader with unknown size",
+			reader: func() io.ReadCloser {
+				return NewLazyReadCloser(p)
+			},
+			size: -1,
+		},
+		{
+			name: "stream only reader",
+			reader: func() io.ReadCloser {
+				return io.NopCloser(bytes.NewReader(contents))
+			},
+			size:        -1,
+			wantSpooled: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			r, err := NewSeekableReader(tt.reader(), tt.size, tempDir)
+			require.NoError(t, err)
+
+			assert.Equal(t, int64(len(contents)), r.Size())
+
+			buf := make([]byte, 5)
+			n, err := r.ReadAt(buf, 3)
+			require.NoError(t, err)
+			assert.Equal(t, contents[3:3+n], buf)
+
+			_, err = r.Seek(-4, io.SeekEnd)
+			require.NoError(t, err)
+			tail, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, contents[len(contents)-4:], tail)
+
+			spooled, ok := r.(*spooledReader)
+			assert.Equal(t, tt.wantSpooled, ok)
+
+			if ok {
+				assert.Equal(t, tempDir, filepath.Dir(spooled.Name()), "contents should be spooled within the given temp dir")
+			}
+
+			require.NoError(t, r.Close())
+			if ok {
+				_, err := os.Stat(spooled.Name())
+				assert.ErrorIs(t, err, os.ErrNotExist, "spooled contents should be removed on close")
+			}
+		})
+	}
+}
+
+func TestNewSeekableReader_SpoolError(t *testing.T) {
+	_, err := NewSeekableReader(io.NopCloser(io.MultiReader(strings.NewReader("partial"), errReader{})), -1, t.TempDir())
+	require.ErrorContains(t, err, "unable to spool file contents")
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
@@ -1,6 +1,7 @@
 package file
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -15,6 +16,12 @@ type TarIndex struct {
 
 // NewTarIndex creates a new TarIndex that is already indexed.
 func NewTarIndex(tarFilePath string, onIndex TarIndexVisitor) (*TarIndex, error) {
+	return NewTarIndexWithContext(context.Background(), tarFilePath, onIndex)
+}
+
+// NewTarIndexWithContext creates a new TarIndex that is already indexed. Indexing stops with the context error as soon
+// as the given context is done.
+func NewTarIndexWithContext(ctx context.Context, tarFilePath string, onIndex TarIndexVisitor) (*TarIndex, error) {
 	t := &TarIndex{
 		indexByName: make(map[string][]TarIndexEntry),
 	}
@@ -25,6 +32,10 @@ func NewTarIndex(tarFilePath string, onIndex TarIndexVisitor) (*TarIndex, error)
 	defer tarFileHandle.Close()
 
 	visitor := func(entry TarFileEntry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		// keep track of the current location (just after reading the tar header) as this is the file content for the
 		// current entry being processed.
 		entrySeekPosition, err := tarFileHandle.Seek(0, io.SeekCurrent)
@@ -4,6 +4,8 @@ package file
 
 import (
 	"archive/tar"
+	"context"
+	"errors"
 	"io"
 	"os"
 	"strings"
@@ -66,6 +68,25 @@ func TestIndexedTarIndex_GoCase(t *testing.T) {
 	}
 }
 
+func TestIndexedTarIndex_Cancelled(t *testing.T) {
+	fixture := getTarFixture(t, "fixture-1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var visited int
+	_, err := NewTarIndexWithContext(ctx, fixture.Name(), func(TarIndexEntry) error {
+		visited++
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context cancellation error, got: %+v", err)
+	}
+	if visited != 0 {
+		t.Errorf("expected no entries to be visited, got %d", visited)
+	}
+}
+
 func TestIndexedTarReader_DuplicateEntries(t *testing.T) {
 	fixture := duplicateEntryTarballFixture(t)
 
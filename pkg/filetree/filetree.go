@@ -24,7 +24,10 @@ var maxLinkResolutionDepth = 100
 
 // FileTree represents a file/directory Tree
 type FileTree struct {
-	tree *tree.Tree
+	tree            *tree.Tree
+	caseInsensitive bool
+	// referenceFactory creates the file.Reference for paths added to the tree (nil for sequential IDs)
+	referenceFactory func(realPath file.Path) *file.Reference
 }
 
 // NewFileTree creates a new FileTree instance.
@@ -50,6 +53,7 @@ func New() *FileTree {
 func (t *FileTree) Copy() (ReadWriter, error) {
 	ct := New()
 	ct.tree = t.tree.Copy()
+	ct.caseInsensitive = t.caseInsensitive
 	return ct, nil
 }
 
@@ -122,6 +126,65 @@ func (t *FileTree) ListPaths(dir file.Path) ([]file.Path, error) {
 	return listing, nil
 }
 
+// SetReferenceFactory sets the function used to create the file.Reference for each path added to the tree (e.g. to
+// assign IDs with file.NewDerivedID). Given nil, references are created with file.NewFileReference (the default).
+func (t *FileTree) SetReferenceFactory(factory func(realPath file.Path) *file.Reference) {
+	t.referenceFactory = factory
+}
+
+func (t *FileTree) newReference(realPath file.Path) *file.Reference {
+	if t.referenceFactory != nil {
+		return t.referenceFactory(realPath)
+	}
+	return file.NewFileReference(realPath)
+}
+
+// SetCaseInsensitive toggles case-insensitive path lookups (for File, HasPath, and FilesByGlob). This is useful for
+// trees representing case-insensitive filesystems (e.g. Windows container layers). Paths are still stored with their
+// original casing.
+func (t *FileTree) SetCaseInsensitive(caseInsensitive bool) {
+	t.caseInsensitive = caseInsensitive
+}
+
+// CaseInsensitive indicates if path lookups ignore casing.
+func (t *FileTree) CaseInsensitive() bool {
+	return t.caseInsensitive
+}
+
+// CaseFoldCollision describes a set of real paths within a FileTree that only differ by case, thus cannot be
+// distinguished by case-insensitive lookups.
+type CaseFoldCollision struct {
+	// Folded is the lower case form of all colliding paths.
+	Folded file.Path
+	// Paths are all colliding real paths (sorted).
+	Paths []file.Path
+}
+
+// CaseFoldCollisions returns all sets of real paths within the FileTree that collide under case-folding (sorted by
+// the folded path). Case-insensitive lookups for a colliding path always resolve to a single one of the real paths.
+func (t *FileTree) CaseFoldCollisions() []CaseFoldCollision {
+	byFolded := make(map[file.Path][]file.Path)
+	for _, n := range t.tree.Nodes() {
+		realPath := n.(*filenode.FileNode).RealPath
+		folded := file.Path(strings.ToLower(string(realPath)))
+		byFolded[folded] = append(byFolded[folded], realPath)
+	}
+
+	var collisions []CaseFoldCollision
+	for folded, paths := range byFolded {
+		if len(paths) < 2 {
+			continue
+		}
+		sort.Slice(paths, func(i, j int) bool { return paths[i] < paths[j] })
+		collisions = append(collisions, CaseFoldCollision{
+			Folded: folded,
+			Paths:  paths,
+		})
+	}
+	sort.Slice(collisions, func(i, j int) bool { return collisions[i].Folded < collisions[j].Folded })
+	return collisions
+}
+
 // File fetches a file.Reference for the given path. Returns nil if the path does not exist in the FileTree.
 func (t *FileTree) File(path file.Path, options ...LinkResolutionOption) (bool, *file.Resolution, error) {
 	currentNode, err := t.file(path, options...)
@@ -173,9 +236,52 @@ func (t *FileTree) file(path file.Path, options ...LinkResolutionOption) (*nodeA
 	if currentNode.HasFileNode() {
 		return currentNode, err
 	}
+	if err == nil && (t.caseInsensitive || userStrategy.CaseInsensitive) {
+		if folded := t.realCasePath(path); folded != path.Normalize() {
+			return t.file(folded, options...)
+		}
+	}
 	return nil, err
 }
 
+// realCasePath returns the given path with each segment replaced with the casing of a matching (case-insensitive)
+// child node within the tree (following links). An exact match is always preferred, otherwise the lexically smallest
+// match is used so that the result is stable. Segments that cannot be matched (as well as all following segments) are
+// left as-is.
+func (t *FileTree) realCasePath(p file.Path) file.Path {
+	segments := strings.Split(strings.TrimPrefix(string(p.Normalize()), file.DirSeparator), file.DirSeparator)
+	current := file.Path(file.DirSeparator)
+	for i, segment := range segments {
+		// the children are listed from the (link resolved) node, however, the path is kept relative to the request
+		na, err := t.node(current, linkResolutionStrategy{
+			FollowAncestorLinks: true,
+			FollowBasenameLinks: true,
+		})
+		if err != nil || !na.HasFileNode() {
+			return file.Path(path.Join(append([]string{string(current)}, segments[i:]...)...))
+		}
+		var match string
+		for _, child := range t.tree.Children(na.FileNode) {
+			if child == nil {
+				continue
+			}
+			name := child.(*filenode.FileNode).RealPath.Basename()
+			if name == segment {
+				match = name
+				break
+			}
+			if strings.EqualFold(name, segment) && (match == "" || name < match) {
+				match = name
+			}
+		}
+		if match == "" {
+			return file.Path(path.Join(append([]string{string(current)}, segments[i:]...)...))
+		}
+		current = file.Path(path.Join(string(current), match))
+	}
+	return current
+}
+
 func newResolutions(nodePath []nodeAccess) []file.Resolution {
 	var refPath []file.Resolution
 	for i, n := range nodePath {
@@ -439,16 +545,26 @@ func (t *FileTree) FilesByGlob(query string, options ...LinkResolutionOption) ([
 	}
 
 	doNotFollowDeadBasenameLinks := false
+	caseInsensitive := t.caseInsensitive
 	for _, o := range options {
-		if o == DoNotFollowDeadBasenameLinks {
+		switch o {
+		case DoNotFollowDeadBasenameLinks:
 			doNotFollowDeadBasenameLinks = true
+		case CaseInsensitive:
+			caseInsensitive = true
 		}
 	}
 
+	var globOptions []doublestar.GlobOption
+	if caseInsensitive {
+		globOptions = append(globOptions, doublestar.WithCaseInsensitive())
+	}
+
 	matches, err := doublestar.Glob(&osAdapter{
 		filetree:                     t,
 		doNotFollowDeadBasenameLinks: doNotFollowDeadBasenameLinks,
-	}, query)
+		caseInsensitive:              caseInsensitive,
+	}, query, globOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -460,6 +576,10 @@ func (t *FileTree) FilesByGlob(query string, options ...LinkResolutionOption) ([
 		if !path.IsAbs(match) {
 			matchPath = file.Path(path.Join("/", match))
 		}
+		if caseInsensitive {
+			// literal segments of the query are matched as given, so may not have the casing of the tree
+			matchPath = t.realCasePath(matchPath)
+		}
 		fna, err := t.node(matchPath, linkResolutionStrategy{
 			FollowAncestorLinks:          true,
 			FollowBasenameLinks:          true,
@@ -502,7 +622,7 @@ func (t *FileTree) AddFile(realPath file.Path) (*file.Reference, error) {
 		}
 		// this is a regular file, provide a new or existing file.Reference
 		if fna.FileNode.Reference == nil {
-			fna.FileNode.Reference = file.NewFileReference(realPath)
+			fna.FileNode.Reference = t.newReference(realPath)
 		}
 		return fna.FileNode.Reference, nil
 	}
@@ -511,7 +631,7 @@ func (t *FileTree) AddFile(realPath file.Path) (*file.Reference, error) {
 	if err := t.addParentPaths(realPath); err != nil {
 		return nil, err
 	}
-	newFn := filenode.NewFile(realPath, file.NewFileReference(realPath))
+	newFn := filenode.NewFile(realPath, t.newReference(realPath))
 	return newFn.Reference, t.setFileNode(newFn)
 }
 
@@ -530,7 +650,7 @@ func (t *FileTree) AddSymLink(realPath file.Path, linkPath file.Path) (*file.Ref
 		}
 		// this is a symlink file, provide a new or existing file.Reference
 		if fna.FileNode.Reference == nil {
-			fna.FileNode.Reference = file.NewFileReference(realPath)
+			fna.FileNode.Reference = t.newReference(realPath)
 		}
 		return fna.FileNode.Reference, nil
 	}
@@ -539,7 +659,7 @@ func (t *FileTree) AddSymLink(realPath file.Path, linkPath file.Path) (*file.Ref
 	if err := t.addParentPaths(realPath); err != nil {
 		return nil, err
 	}
-	newFn := filenode.NewSymLink(realPath, linkPath, file.NewFileReference(realPath))
+	newFn := filenode.NewSymLink(realPath, linkPath, t.newReference(realPath))
 	return newFn.Reference, t.setFileNode(newFn)
 }
 
@@ -558,7 +678,7 @@ func (t *FileTree) AddHardLink(realPath file.Path, linkPath file.Path) (*file.Re
 		}
 		// this is a symlink file, provide a new or existing file.Reference
 		if fna.FileNode.Reference == nil {
-			fna.FileNode.Reference = file.NewFileReference(realPath)
+			fna.FileNode.Reference = t.newReference(realPath)
 		}
 		return fna.FileNode.Reference, nil
 	}
@@ -568,7 +688,7 @@ func (t *FileTree) AddHardLink(realPath file.Path, linkPath file.Path) (*file.Re
 		return nil, err
 	}
 
-	newFn := filenode.NewHardLink(realPath, linkPath, file.NewFileReference(realPath))
+	newFn := filenode.NewHardLink(realPath, linkPath, t.newReference(realPath))
 
 	return newFn.Reference, t.setFileNode(newFn)
 }
@@ -589,7 +709,7 @@ func (t *FileTree) AddDir(realPath file.Path) (*file.Reference, error) {
 		}
 		// this is a directory, provide a new or existing file.Reference
 		if fna.FileNode.Reference == nil {
-			fna.FileNode.Reference = file.NewFileReference(realPath)
+			fna.FileNode.Reference = t.newReference(realPath)
 		}
 		return fna.FileNode.Reference, nil
 	}
@@ -599,7 +719,7 @@ func (t *FileTree) AddDir(realPath file.Path) (*file.Reference, error) {
 		return nil, err
 	}
 
-	newFn := filenode.NewDir(realPath, file.NewFileReference(realPath))
+	newFn := filenode.NewDir(realPath, t.newReference(realPath))
 	return newFn.Reference, t.setFileNode(newFn)
 }
 
@@ -120,6 +120,110 @@ func TestFileTree_FilesByGlob_AncestorSymlink(t *testing.T) {
 	}
 }
 
+func TestFileTree_CaseInsensitive(t *testing.T) {
+	tr := New()
+
+	ref, err := tr.AddFile("/Windows/System32/kernel32.dll")
+	require.NoError(t, err)
+	_, err = tr.AddSymLink("/Program Files/link", "/Windows/System32")
+	require.NoError(t, err)
+
+	// lookups are case-sensitive by default
+	assert.False(t, tr.HasPath("/windows/system32/KERNEL32.dll"))
+
+	tr.SetCaseInsensitive(true)
+	assert.True(t, tr.CaseInsensitive())
+
+	tests := []struct {
+		name string
+		path file.Path
+		want file.Path
+	}{
+		{
+			name: "exact case",
+			path: "/Windows/System32/kernel32.dll",
+			want: "/Windows/System32/kernel32.dll",
+		},
+		{
+			name: "different case",
+			path: "/windows/SYSTEM32/Kernel32.DLL",
+			want: "/Windows/System32/kernel32.dll",
+		},
+		{
+			name: "different case through link",
+			path: "/program files/LINK/kernel32.dll",
+			want: "/Program Files/link/kernel32.dll",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exists, resolution, err := tr.File(tt.path, FollowBasenameLinks)
+			require.NoError(t, err)
+			require.True(t, exists)
+			assert.Equal(t, tt.want, resolution.RequestPath)
+			assert.Equal(t, ref.ID(), resolution.Reference.ID())
+		})
+	}
+
+	assert.False(t, tr.HasPath("/windows/system32/missing.dll"))
+
+	results, err := tr.FilesByGlob("/windows/**/*.DLL")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, file.Path("/Windows/System32/kernel32.dll"), results[0].RequestPath)
+
+	// the mode is kept when copying the tree (e.g. when squashing)
+	cp, err := tr.Copy()
+	require.NoError(t, err)
+	assert.True(t, cp.HasPath("/WINDOWS/system32/kernel32.dll"))
+}
+
+func TestFileTree_CaseInsensitiveOption(t *testing.T) {
+	tr := New()
+
+	_, err := tr.AddFile("/node_modules/Lodash/package.json")
+	require.NoError(t, err)
+
+	assert.False(t, tr.HasPath("/node_modules/lodash/package.json"))
+	assert.True(t, tr.HasPath("/node_modules/lodash/package.json", CaseInsensitive))
+
+	results, err := tr.FilesByGlob("/node_modules/lodash/*.JSON")
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	results, err = tr.FilesByGlob("/node_modules/lodash/*.JSON", CaseInsensitive)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, file.Path("/node_modules/Lodash/package.json"), results[0].RequestPath)
+}
+
+func TestFileTree_CaseFoldCollisions(t *testing.T) {
+	tr := New()
+
+	for _, p := range []file.Path{"/app/Makefile", "/app/makefile", "/app/lib/a.js", "/app/Lib/b.js", "/app/LIB/a.js"} {
+		_, err := tr.AddFile(p)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []CaseFoldCollision{
+		{Folded: "/app/lib", Paths: []file.Path{"/app/LIB", "/app/Lib", "/app/lib"}},
+		{Folded: "/app/lib/a.js", Paths: []file.Path{"/app/LIB/a.js", "/app/lib/a.js"}},
+		{Folded: "/app/makefile", Paths: []file.Path{"/app/Makefile", "/app/makefile"}},
+	}, tr.CaseFoldCollisions())
+
+	// colliding lookups prefer an exact match, otherwise the lexically smallest real path
+	tr.SetCaseInsensitive(true)
+	_, resolution, err := tr.File("/app/makefile")
+	require.NoError(t, err)
+	assert.Equal(t, file.Path("/app/makefile"), resolution.RequestPath)
+
+	_, resolution, err = tr.File("/APP/MAKEFILE")
+	require.NoError(t, err)
+	assert.Equal(t, file.Path("/app/Makefile"), resolution.RequestPath)
+
+	assert.Empty(t, New().CaseFoldCollisions())
+}
+
 func TestFileTree_FilesByGlob(t *testing.T) {
 	tr := New()
 
@@ -87,7 +87,7 @@ func (f *fileAdapter) ReadDir(n int) ([]fs.DirEntry, error) {
 		return nil, os.ErrInvalid
 	}
 	var ret = make([]fs.DirEntry, 0)
-	fna, err := f.filetree.node(file.Path(f.name), linkResolutionStrategy{
+	fna, err := f.filetree.node(f.os.path(f.name), linkResolutionStrategy{
 		FollowAncestorLinks: true,
 		FollowBasenameLinks: true,
 	})
@@ -98,7 +98,7 @@ func (f *fileAdapter) ReadDir(n int) ([]fs.DirEntry, error) {
 		return ret, nil
 	}
 
-	isInLoop, err := isInPathResolutionLoop(f.name, f.filetree)
+	isInLoop, err := isInPathResolutionLoop(string(f.os.path(f.name)), f.filetree)
 	if err != nil || isInLoop {
 		return ret, err
 	}
@@ -122,11 +122,12 @@ func (f *fileAdapter) ReadDir(n int) ([]fs.DirEntry, error) {
 type osAdapter struct {
 	filetree                     *FileTree
 	doNotFollowDeadBasenameLinks bool
+	caseInsensitive              bool
 }
 
 func (a *osAdapter) ReadDir(name string) ([]fs.DirEntry, error) {
 	var ret = make([]fs.DirEntry, 0)
-	fna, err := a.filetree.node(file.Path(name), linkResolutionStrategy{
+	fna, err := a.filetree.node(a.path(name), linkResolutionStrategy{
 		FollowAncestorLinks: true,
 		FollowBasenameLinks: true,
 	})
@@ -137,7 +138,7 @@ func (a *osAdapter) ReadDir(name string) ([]fs.DirEntry, error) {
 		return ret, nil
 	}
 
-	isInLoop, err := isInPathResolutionLoop(name, a.filetree)
+	isInLoop, err := isInPathResolutionLoop(string(a.path(name)), a.filetree)
 	if err != nil || isInLoop {
 		return ret, err
 	}
@@ -158,7 +159,7 @@ func (a *osAdapter) ReadDir(name string) ([]fs.DirEntry, error) {
 // Lstat returns a FileInfo describing the named file. If the file is a symbolic link, the returned
 // FileInfo describes the symbolic link. Lstat makes no attempt to follow the link.
 func (a *osAdapter) Lstat(name string) (fs.FileInfo, error) {
-	fna, err := a.filetree.node(file.Path(name), linkResolutionStrategy{
+	fna, err := a.filetree.node(a.path(name), linkResolutionStrategy{
 		FollowAncestorLinks: true,
 		// Lstat by definition requires that basename symlinks are not followed
 		FollowBasenameLinks:          false,
@@ -177,6 +178,14 @@ func (a *osAdapter) Lstat(name string) (fs.FileInfo, error) {
 	}, nil
 }
 
+// path returns the given name as a tree path, matching the casing of the tree for case-insensitive trees.
+func (a *osAdapter) path(name string) file.Path {
+	if a.caseInsensitive {
+		return a.filetree.realCasePath(file.Path(name))
+	}
+	return file.Path(name)
+}
+
 // Open the given file path and return a doublestar.File.
 func (a *osAdapter) Open(name string) (fs.File, error) {
 	return &fileAdapter{
@@ -188,7 +197,7 @@ func (a *osAdapter) Open(name string) (fs.File, error) {
 
 // Stat returns a FileInfo describing the named file.
 func (a *osAdapter) Stat(name string) (fs.FileInfo, error) {
-	fna, err := a.filetree.node(file.Path(name), linkResolutionStrategy{
+	fna, err := a.filetree.node(a.path(name), linkResolutionStrategy{
 		FollowAncestorLinks:          true,
 		FollowBasenameLinks:          true,
 		DoNotFollowDeadBasenameLinks: a.doNotFollowDeadBasenameLinks,
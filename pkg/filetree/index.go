@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"sort"
 	"strings"
 	"sync"
 
@@ -29,6 +30,18 @@ type IndexReader interface {
 	GetByBasenameGlob(globs ...string) ([]IndexEntry, error)
 }
 
+// BasenameFoldIndexReader is an IndexReader that can fetch entries by basename, ignoring case.
+type BasenameFoldIndexReader interface {
+	IndexReader
+	GetByBasenameFold(basenames ...string) ([]IndexEntry, error)
+}
+
+// XattrIndexReader is an IndexReader that can fetch entries by extended attribute name.
+type XattrIndexReader interface {
+	IndexReader
+	GetByXattr(names ...string) ([]IndexEntry, error)
+}
+
 type IndexWriter interface {
 	Add(f file.Reference, m file.Metadata)
 }
@@ -42,9 +55,17 @@ type index struct {
 	byMIMEType  map[string]file.IDSet
 	byExtension map[string]file.IDSet
 	byBasename  map[string]file.IDSet
+	byXattr     map[string]file.IDSet
 	basenames   *strset.Set
+	// foldedBasenames maps lower case basenames to all basenames that fold to it
+	foldedBasenames map[string]*strset.Set
 }
 
+var (
+	_ XattrIndexReader        = (*index)(nil)
+	_ BasenameFoldIndexReader = (*index)(nil)
+)
+
 // NewIndex returns an empty Index.
 func NewIndex() Index {
 	return &index{
@@ -54,7 +75,10 @@ func NewIndex() Index {
 		byMIMEType:  make(map[string]file.IDSet),
 		byExtension: make(map[string]file.IDSet),
 		byBasename:  make(map[string]file.IDSet),
+		byXattr:     make(map[string]file.IDSet),
 		basenames:   strset.New(),
+
+		foldedBasenames: make(map[string]*strset.Set),
 	}
 }
 
@@ -94,6 +118,12 @@ func (c *index) Add(f file.Reference, m file.Metadata) {
 	c.byBasename[basename].Add(id)
 	c.basenames.Add(basename)
 
+	folded := strings.ToLower(basename)
+	if _, ok := c.foldedBasenames[folded]; !ok {
+		c.foldedBasenames[folded] = strset.New()
+	}
+	c.foldedBasenames[folded].Add(basename)
+
 	for _, ext := range fileExtensions(string(f.RealPath)) {
 		if _, ok := c.byExtension[ext]; !ok {
 			c.byExtension[ext] = file.NewIDSet()
@@ -101,6 +131,13 @@ func (c *index) Add(f file.Reference, m file.Metadata) {
 		c.byExtension[ext].Add(id)
 	}
 
+	for name := range m.Xattrs {
+		if _, ok := c.byXattr[name]; !ok {
+			c.byXattr[name] = file.NewIDSet()
+		}
+		c.byXattr[name].Add(id)
+	}
+
 	if _, ok := c.byFileType[m.Type]; !ok {
 		c.byFileType[m.Type] = file.NewIDSet()
 	}
@@ -266,6 +303,66 @@ func (c *index) GetByBasenameGlob(globs ...string) ([]IndexEntry, error) {
 	return entries, nil
 }
 
+// GetByBasenameFold fetches all IndexEntries for files with a basename matching one of the given basenames, ignoring
+// case (e.g. "readme.md" matches both "README.md" and "Readme.md"). Entries are grouped by the given basename, then by
+// the real basename (sorted).
+func (c *index) GetByBasenameFold(basenames ...string) ([]IndexEntry, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	var entries []IndexEntry
+
+	for _, basename := range basenames {
+		if strings.Contains(basename, "/") {
+			return nil, fmt.Errorf("found directory separator in a basename")
+		}
+
+		realBasenames, ok := c.foldedBasenames[strings.ToLower(basename)]
+		if !ok {
+			continue
+		}
+
+		for _, realBasename := range sortedSet(realBasenames) {
+			for _, id := range c.byBasename[realBasename].Sorted() {
+				entry, ok := c.index[id]
+				if !ok {
+					return nil, os.ErrNotExist
+				}
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+// GetByXattr fetches all IndexEntries for files that have at least one of the given extended attributes set (e.g.
+// file.CapabilitiesXattr to find all files with capabilities). Entries are grouped by attribute name in the order given,
+// so a file with more than one of the given attributes is returned once per attribute.
+func (c *index) GetByXattr(names ...string) ([]IndexEntry, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	var entries []IndexEntry
+
+	for _, name := range names {
+		fileIDs, ok := c.byXattr[name]
+		if !ok {
+			continue
+		}
+
+		for _, id := range fileIDs.Sorted() {
+			entry, ok := c.index[id]
+			if !ok {
+				return nil, os.ErrNotExist
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
 func fileExtensions(p string) []string {
 	var exts []string
 	p = strings.TrimSpace(p)
@@ -289,3 +386,9 @@ func fileExtensions(p string) []string {
 	}
 	return exts
 }
+
+func sortedSet(s *strset.Set) []string {
+	values := s.List()
+	sort.Strings(values)
+	return values
+}
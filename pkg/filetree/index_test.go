@@ -576,6 +576,60 @@ func TestFileCatalog_GetByBasename(t *testing.T) {
 	}
 }
 
+func TestFileCatalog_GetByBasenameFold(t *testing.T) {
+	tree := New()
+	idx := NewIndex().(*index)
+	for _, p := range []file.Path{"/app/README.md", "/app/docs/readme.md", "/app/Readme.MD", "/app/readme.txt"} {
+		ref, err := tree.AddFile(p)
+		require.NoError(t, err)
+		idx.Add(*ref, file.Metadata{Path: string(p), Type: file.TypeRegular})
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    []file.Path
+		wantErr require.ErrorAssertionFunc
+	}{
+		{
+			name:  "matches all casings",
+			input: "readme.md",
+			want:  []file.Path{"/app/README.md", "/app/Readme.MD", "/app/docs/readme.md"},
+		},
+		{
+			name:  "matches regardless of given casing",
+			input: "README.TXT",
+			want:  []file.Path{"/app/readme.txt"},
+		},
+		{
+			name:  "no match",
+			input: "license",
+		},
+		{
+			name:    "basename with path expression",
+			input:   "app/readme.md",
+			wantErr: require.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr == nil {
+				tt.wantErr = require.NoError
+			}
+			actual, err := idx.GetByBasenameFold(tt.input)
+			tt.wantErr(t, err)
+			if err != nil {
+				return
+			}
+			var paths []file.Path
+			for _, entry := range actual {
+				paths = append(paths, entry.RealPath)
+			}
+			assert.Equal(t, tt.want, paths)
+		})
+	}
+}
+
 func TestFileCatalog_GetByBasenameGlob(t *testing.T) {
 	fileIndex := commonIndexFixture(t)
 
@@ -770,6 +824,53 @@ func TestFileCatalog_GetByMimeType(t *testing.T) {
 	}
 }
 
+func TestFileCatalog_GetByXattr(t *testing.T) {
+	tree := New()
+	idx := NewIndex().(*index)
+
+	addFile := func(path file.Path, xattrs map[string]string) {
+		ref, err := tree.AddFile(path)
+		require.NoError(t, err)
+		idx.Add(*ref, file.Metadata{Path: string(path), Type: file.TypeRegular, Xattrs: xattrs})
+	}
+
+	addFile("/usr/bin/ping", map[string]string{file.CapabilitiesXattr: "\x01\x00\x00\x02", "security.selinux": "system_u:object_r:ping_exec_t:s0"})
+	addFile("/usr/bin/ls", map[string]string{"security.selinux": "system_u:object_r:bin_t:s0"})
+	addFile("/etc/passwd", nil)
+
+	tests := []struct {
+		name  string
+		input []string
+		want  []string
+	}{
+		{
+			name:  "files with capabilities",
+			input: []string{file.CapabilitiesXattr},
+			want:  []string{"/usr/bin/ping"},
+		},
+		{
+			name:  "files with selinux labels",
+			input: []string{"security.selinux"},
+			want:  []string{"/usr/bin/ping", "/usr/bin/ls"},
+		},
+		{
+			name:  "non-existing xattr",
+			input: []string{"user.bogus"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := idx.GetByXattr(tt.input...)
+			require.NoError(t, err)
+			var paths []string
+			for _, entry := range actual {
+				paths = append(paths, string(entry.RealPath))
+			}
+			assert.ElementsMatch(t, tt.want, paths)
+		})
+	}
+}
+
 func TestFileCatalog_GetBasenames(t *testing.T) {
 	fileIndex := commonIndexFixture(t)
 
@@ -0,0 +1,149 @@
+package filetree
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/scylladb/go-set/strset"
+
+	"github.com/anchore/stereoscope/pkg/file"
+	"github.com/anchore/stereoscope/pkg/filetree/filenode"
+)
+
+const (
+	// DeadLink indicates that the link (eventually) points to a path that does not exist in the tree.
+	DeadLink LinkProblem = "dead-link"
+
+	// LinkCycle indicates that resolving the link leads back to a link that has already been visited.
+	LinkCycle LinkProblem = "cycle"
+
+	// LinkDepthExceeded indicates that the link could not be resolved within the maximum link resolution depth.
+	LinkDepthExceeded LinkProblem = "depth-exceeded"
+
+	// LinkEscapesRoot indicates that the link has a relative destination that climbs above the root (e.g. "../../etc"
+	// from /app). Within the tree such destinations are clamped to the root, however, they point elsewhere when the
+	// tree is extracted somewhere other than the root of a filesystem.
+	LinkEscapesRoot LinkProblem = "escapes-root"
+
+	// AbsoluteLink indicates that the link has an absolute destination. Within the tree such destinations are relative
+	// to the root, however, they point outside of the tree when it is extracted somewhere other than the root of a
+	// filesystem.
+	AbsoluteLink LinkProblem = "absolute-destination"
+)
+
+// LinkProblem is the reason a link is reported by AnalyzeLinks.
+type LinkProblem string
+
+// LinkFinding describes a single problem found with a link within a FileTree.
+type LinkFinding struct {
+	// Path is the real path of the link.
+	Path file.Path
+	// Destination is the link destination as recorded on the link (not resolved).
+	Destination file.Path
+	// Problem is the reason the link was reported.
+	Problem LinkProblem
+	// Chain are all paths visited while resolving the link, starting with the link itself. For dead links the last
+	// path is the one that does not exist, and for cycles the last path is the first path visited twice.
+	Chain []file.Path
+}
+
+// AnalyzeLinks walks all symlinks and hardlinks in the FileTree and reports links that are dead, cyclic, exceed the
+// maximum link resolution depth, or point outside the root (via ".." or absolute destinations). A link may be reported
+// more than once (with different problems). Findings are sorted by path then by problem.
+func (t *FileTree) AnalyzeLinks() []LinkFinding {
+	var findings []LinkFinding
+	for _, n := range t.tree.Nodes() {
+		fn := n.(*filenode.FileNode)
+		if !fn.IsLink() {
+			continue
+		}
+
+		finding := func(problem LinkProblem, chain []file.Path) LinkFinding {
+			return LinkFinding{
+				Path:        fn.RealPath,
+				Destination: fn.LinkPath,
+				Problem:     problem,
+				Chain:       chain,
+			}
+		}
+
+		if fn.FileType == file.TypeSymLink {
+			switch {
+			case fn.LinkPath.IsAbsolutePath():
+				findings = append(findings, finding(AbsoluteLink, []file.Path{fn.RealPath, fn.LinkPath}))
+			case escapesRoot(fn.RealPath, fn.LinkPath):
+				findings = append(findings, finding(LinkEscapesRoot, []file.Path{fn.RealPath, fn.RenderLinkDestination()}))
+			}
+		}
+
+		if problem, chain := t.linkChain(fn); problem != "" {
+			findings = append(findings, finding(problem, chain))
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Path == findings[j].Path {
+			return findings[i].Problem < findings[j].Problem
+		}
+		return findings[i].Path < findings[j].Path
+	})
+
+	return findings
+}
+
+// linkChain follows the given link one hop at a time (the same way resolveNodeLinks does), returning all visited paths
+// and the problem found (if any).
+func (t *FileTree) linkChain(fn *filenode.FileNode) (LinkProblem, []file.Path) {
+	chain := []file.Path{fn.RealPath}
+	visited := strset.New(string(fn.RealPath))
+	current := fn
+
+	for depth := 1; ; depth++ {
+		if depth >= maxLinkResolutionDepth {
+			return LinkDepthExceeded, chain
+		}
+
+		next := current.RenderLinkDestination()
+		chain = append(chain, next)
+
+		na, err := t.node(next, linkResolutionStrategy{FollowAncestorLinks: true})
+		switch {
+		case errors.Is(err, ErrLinkCycleDetected):
+			return LinkCycle, chain
+		case errors.Is(err, ErrLinkResolutionDepth):
+			return LinkDepthExceeded, chain
+		case err != nil || !na.HasFileNode():
+			return DeadLink, chain
+		}
+
+		if !na.FileNode.IsLink() {
+			return "", chain
+		}
+
+		if visited.Has(string(na.FileNode.RealPath)) {
+			return LinkCycle, chain
+		}
+		visited.Add(string(na.FileNode.RealPath))
+		current = na.FileNode
+	}
+}
+
+// escapesRoot indicates if the given relative link destination climbs above the root when resolved from the directory
+// of the given link path.
+func escapesRoot(linkPath, destination file.Path) bool {
+	depth := len(strings.Split(strings.Trim(string(linkPath), file.DirSeparator), file.DirSeparator)) - 1
+	for _, segment := range strings.Split(string(destination), file.DirSeparator) {
+		switch segment {
+		case "", ".":
+		case "..":
+			depth--
+			if depth < 0 {
+				return true
+			}
+		default:
+			depth++
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package filetree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anchore/stereoscope/pkg/file"
+)
+
+func TestFileTree_AnalyzeLinks(t *testing.T) {
+	tr := New()
+
+	_, err := tr.AddFile("/usr/bin/python3.11")
+	require.NoError(t, err)
+
+	links := []struct {
+		path, destination file.Path
+	}{
+		// healthy links are not reported
+		{path: "/usr/bin/python3", destination: "python3.11"},
+		{path: "/usr/bin/python", destination: "python3"},
+		// dead links, directly and through another link
+		{path: "/usr/bin/missing", destination: "does-not-exist"},
+		{path: "/usr/bin/to-missing", destination: "missing"},
+		// cycle
+		{path: "/loop/a", destination: "b"},
+		{path: "/loop/b", destination: "c"},
+		{path: "/loop/c", destination: "a"},
+		// escapes the root, but resolves within the tree since ".." is clamped at the root
+		{path: "/usr/escape", destination: "../../usr/bin/python3.11"},
+		// absolute
+		{path: "/bin/python", destination: "/usr/bin/python"},
+	}
+	for _, l := range links {
+		_, err := tr.AddSymLink(l.path, l.destination)
+		require.NoError(t, err)
+	}
+
+	_, err = tr.AddHardLink("/usr/bin/dead-hardlink", "/usr/bin/gone")
+	require.NoError(t, err)
+
+	expected := []LinkFinding{
+		{
+			Path:        "/bin/python",
+			Destination: "/usr/bin/python",
+			Problem:     AbsoluteLink,
+			Chain:       []file.Path{"/bin/python", "/usr/bin/python"},
+		},
+		{
+			Path:        "/loop/a",
+			Destination: "b",
+			Problem:     LinkCycle,
+			Chain:       []file.Path{"/loop/a", "/loop/b", "/loop/c", "/loop/a"},
+		},
+		{
+			Path:        "/loop/b",
+			Destination: "c",
+			Problem:     LinkCycle,
+			Chain:       []file.Path{"/loop/b", "/loop/c", "/loop/a", "/loop/b"},
+		},
+		{
+			Path:        "/loop/c",
+			Destination: "a",
+			Problem:     LinkCycle,
+			Chain:       []file.Path{"/loop/c", "/loop/a", "/loop/b", "/loop/c"},
+		},
+		{
+			Path:        "/usr/bin/dead-hardlink",
+			Destination: "/usr/bin/gone",
+			Problem:     DeadLink,
+			Chain:       []file.Path{"/usr/bin/dead-hardlink", "/usr/bin/gone"},
+		},
+		{
+			Path:        "/usr/bin/missing",
+			Destination: "does-not-exist",
+			Problem:     DeadLink,
+			Chain:       []file.Path{"/usr/bin/missing", "/usr/bin/does-not-exist"},
+		},
+		{
+			Path:        "/usr/bin/to-missing",
+			Destination: "missing",
+			Problem:     DeadLink,
+			Chain:       []file.Path{"/usr/bin/to-missing", "/usr/bin/missing", "/usr/bin/does-not-exist"},
+		},
+		{
+			Path:        "/usr/escape",
+			Destination: "../../usr/bin/python3.11",
+			Problem:     LinkEscapesRoot,
+			Chain:       []file.Path{"/usr/escape", "/usr/bin/python3.11"},
+		},
+	}
+
+	assert.Equal(t, expected, tr.AnalyzeLinks())
+}
+
+func TestFileTree_AnalyzeLinks_DepthExceeded(t *testing.T) {
+	tr := New()
+
+	_, err := tr.AddFile("/target")
+	require.NoError(t, err)
+
+	previous := file.Path("/target")
+	for i := 0; i < maxLinkResolutionDepth+1; i++ {
+		p := file.Path("/link-" + string(rune('a'+i%26)) + "-" + string(rune('a'+i/26)))
+		_, err := tr.AddSymLink(p, previous)
+		require.NoError(t, err)
+		previous = p
+	}
+
+	var problems []LinkProblem
+	for _, f := range tr.AnalyzeLinks() {
+		if f.Problem != AbsoluteLink {
+			problems = append(problems, f.Problem)
+		}
+	}
+	require.NotEmpty(t, problems)
+	for _, p := range problems {
+		assert.Equal(t, LinkDepthExceeded, p)
+	}
+}
+
+func Test_escapesRoot(t *testing.T) {
+	tests := []struct {
+		linkPath    file.Path
+		destination file.Path
+		want        bool
+	}{
+		{linkPath: "/link", destination: "target", want: false},
+		{linkPath: "/link", destination: "../target", want: true},
+		{linkPath: "/a/b/link", destination: "../../target", want: false},
+		{linkPath: "/a/b/link", destination: "../../../target", want: true},
+		{linkPath: "/a/link", destination: "b/../../target", want: false},
+		{linkPath: "/a/link", destination: "./../../target", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.linkPath)+"->"+string(tt.destination), func(t *testing.T) {
+			assert.Equal(t, tt.want, escapesRoot(tt.linkPath, tt.destination))
+		})
+	}
+}
@@ -13,6 +13,11 @@ const (
 	// the non-existing path. This is useful when the caller wants to do custom link resolution (e.g. for container
 	// images: the link is dead in this layer squash, but does it resolve in a higher layer?).
 	DoNotFollowDeadBasenameLinks
+
+	// CaseInsensitive folds case when matching the given path (or glob) against the paths in the tree (see
+	// FileTree.SetCaseInsensitive to enable this for all lookups). When several real paths only differ by case the
+	// exact match is preferred, otherwise the lexically smallest path is used (see FileTree.CaseFoldCollisions).
+	CaseInsensitive
 )
 
 // LinkResolutionOption is a single link resolution rule.
@@ -23,6 +28,7 @@ type linkResolutionStrategy struct {
 	FollowAncestorLinks          bool
 	FollowBasenameLinks          bool
 	DoNotFollowDeadBasenameLinks bool
+	CaseInsensitive              bool
 }
 
 // newLinkResolutionStrategy creates a new linkResolutionStrategy for the given set of LinkResolutionOptions.
@@ -36,6 +42,8 @@ func newLinkResolutionStrategy(options ...LinkResolutionOption) linkResolutionSt
 			s.DoNotFollowDeadBasenameLinks = true
 		case followAncestorLinks:
 			s.FollowAncestorLinks = true
+		case CaseInsensitive:
+			s.CaseInsensitive = true
 		}
 	}
 	return s
@@ -0,0 +1,299 @@
+package filetree
+
+import (
+	"io/fs"
+	"path"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"github.com/anchore/stereoscope/pkg/file"
+)
+
+// Predicate is a composable condition over file metadata, used to search for files (see PredicateSearcher.SearchByPredicate).
+// Predicates are created with the functions in this file (e.g. HasFileType, IsSetUID, OwnedByUser) and combined with
+// And, Or, and Not.
+type Predicate struct {
+	match func(file.Metadata) bool
+	// candidates returns a superset of all index entries that could match the predicate using the index buckets. This
+	// is nil when the predicate cannot be narrowed using the index (requiring a scan of all index entries).
+	candidates func(IndexReader) ([]IndexEntry, error)
+}
+
+// Matches indicates if the given file metadata satisfies the predicate.
+func (p Predicate) Matches(m file.Metadata) bool {
+	if p.match == nil {
+		return true
+	}
+	return p.match(m)
+}
+
+// MatchFunc creates a predicate from an arbitrary condition over file metadata (which cannot be narrowed using the
+// index).
+func MatchFunc(fn func(file.Metadata) bool) Predicate {
+	return Predicate{match: fn}
+}
+
+// And creates a predicate that matches when all the given predicates match.
+func And(predicates ...Predicate) Predicate {
+	p := Predicate{
+		match: func(m file.Metadata) bool {
+			for _, p := range predicates {
+				if !p.Matches(m) {
+					return false
+				}
+			}
+			return true
+		},
+	}
+	// any single narrowed predicate is a superset of the conjunction
+	for _, child := range predicates {
+		if child.candidates != nil {
+			p.candidates = child.candidates
+			break
+		}
+	}
+	return p
+}
+
+// Or creates a predicate that matches when any of the given predicates match.
+func Or(predicates ...Predicate) Predicate {
+	p := Predicate{
+		match: func(m file.Metadata) bool {
+			for _, p := range predicates {
+				if p.Matches(m) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+	// the union of candidates is only a superset of the disjunction when every predicate can be narrowed
+	for _, child := range predicates {
+		if child.candidates == nil {
+			return p
+		}
+	}
+	p.candidates = func(idx IndexReader) ([]IndexEntry, error) {
+		var entries []IndexEntry
+		for _, child := range predicates {
+			childEntries, err := child.candidates(idx)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, childEntries...)
+		}
+		return entries, nil
+	}
+	return p
+}
+
+// Not creates a predicate that matches when the given predicate does not match.
+func Not(predicate Predicate) Predicate {
+	return Predicate{
+		match: func(m file.Metadata) bool {
+			return !predicate.Matches(m)
+		},
+	}
+}
+
+// HasFileType matches files of any of the given types.
+func HasFileType(types ...file.Type) Predicate {
+	return Predicate{
+		match: func(m file.Metadata) bool {
+			for _, t := range types {
+				if m.Type == t {
+					return true
+				}
+			}
+			return false
+		},
+		candidates: func(idx IndexReader) ([]IndexEntry, error) {
+			return idx.GetByFileType(types...)
+		},
+	}
+}
+
+// HasMIMEType matches files with any of the given MIME types.
+func HasMIMEType(mimeTypes ...string) Predicate {
+	return Predicate{
+		match: func(m file.Metadata) bool {
+			for _, t := range mimeTypes {
+				if m.MIMEType == t {
+					return true
+				}
+			}
+			return false
+		},
+		candidates: func(idx IndexReader) ([]IndexEntry, error) {
+			return idx.GetByMIMEType(mimeTypes...)
+		},
+	}
+}
+
+// HasExtension matches files with any of the given extensions (e.g. ".txt" or ".tar.gz").
+func HasExtension(extensions ...string) Predicate {
+	return Predicate{
+		match: func(m file.Metadata) bool {
+			for _, fileExt := range fileExtensions(m.Path) {
+				for _, ext := range extensions {
+					if fileExt == ext {
+						return true
+					}
+				}
+			}
+			return false
+		},
+		candidates: func(idx IndexReader) ([]IndexEntry, error) {
+			return idx.GetByExtension(extensions...)
+		},
+	}
+}
+
+// HasBasename matches files with any of the given basenames.
+func HasBasename(basenames ...string) Predicate {
+	return Predicate{
+		match: func(m file.Metadata) bool {
+			base := path.Base(m.Path)
+			for _, b := range basenames {
+				if base == b {
+					return true
+				}
+			}
+			return false
+		},
+		candidates: func(idx IndexReader) ([]IndexEntry, error) {
+			return idx.GetByBasename(basenames...)
+		},
+	}
+}
+
+// HasXattr matches files with any of the given extended attributes set.
+func HasXattr(names ...string) Predicate {
+	return Predicate{
+		match: func(m file.Metadata) bool {
+			for _, name := range names {
+				if _, ok := m.Xattrs[name]; ok {
+					return true
+				}
+			}
+			return false
+		},
+		candidates: func(idx IndexReader) ([]IndexEntry, error) {
+			if xattrIdx, ok := idx.(XattrIndexReader); ok {
+				return xattrIdx.GetByXattr(names...)
+			}
+			// the index cannot be narrowed by xattr, so all entries must be considered
+			return idx.GetByFileType(file.AllTypes()...)
+		},
+	}
+}
+
+// HasCapabilities matches files with file capabilities set.
+func HasCapabilities() Predicate {
+	return HasXattr(file.CapabilitiesXattr)
+}
+
+// PathMatches matches files with a path matching the given glob pattern (e.g. "/usr/**/bin/*"). Note: this matches
+// the real path of the file only, not any paths reachable through links.
+func PathMatches(pattern string) Predicate {
+	return Predicate{
+		match: func(m file.Metadata) bool {
+			matched, err := doublestar.Match(pattern, m.Path)
+			return err == nil && matched
+		},
+	}
+}
+
+// IsSetUID matches files with the setuid bit set.
+func IsSetUID() Predicate {
+	return HasMode(fs.ModeSetuid)
+}
+
+// IsSetGID matches files with the setgid bit set.
+func IsSetGID() Predicate {
+	return HasMode(fs.ModeSetgid)
+}
+
+// IsSticky matches files with the sticky bit set.
+func IsSticky() Predicate {
+	return HasMode(fs.ModeSticky)
+}
+
+// IsWorldWritable matches files that are writable by all users.
+func IsWorldWritable() Predicate {
+	return HasMode(0o002)
+}
+
+// HasMode matches files with all the given mode bits set (e.g. fs.ModeSetuid or 0o111).
+func HasMode(mode fs.FileMode) Predicate {
+	return Predicate{
+		match: func(m file.Metadata) bool {
+			return m.FileInfo != nil && m.Mode()&mode == mode
+		},
+	}
+}
+
+// OwnedByUser matches files owned by any of the given user IDs.
+func OwnedByUser(uids ...int) Predicate {
+	return Predicate{
+		match: func(m file.Metadata) bool {
+			for _, uid := range uids {
+				if m.UserID == uid {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// OwnedByGroup matches files owned by any of the given group IDs.
+func OwnedByGroup(gids ...int) Predicate {
+	return Predicate{
+		match: func(m file.Metadata) bool {
+			for _, gid := range gids {
+				if m.GroupID == gid {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// SizeAtLeast matches files with a size of at least the given number of bytes.
+func SizeAtLeast(size int64) Predicate {
+	return Predicate{
+		match: func(m file.Metadata) bool {
+			return m.FileInfo != nil && m.Size() >= size
+		},
+	}
+}
+
+// SizeAtMost matches files with a size of at most the given number of bytes.
+func SizeAtMost(size int64) Predicate {
+	return Predicate{
+		match: func(m file.Metadata) bool {
+			return m.FileInfo != nil && m.Size() <= size
+		},
+	}
+}
+
+// ModifiedAfter matches files last modified after the given time.
+func ModifiedAfter(t time.Time) Predicate {
+	return Predicate{
+		match: func(m file.Metadata) bool {
+			return m.FileInfo != nil && m.ModTime().After(t)
+		},
+	}
+}
+
+// ModifiedBefore matches files last modified before the given time.
+func ModifiedBefore(t time.Time) Predicate {
+	return Predicate{
+		match: func(m file.Metadata) bool {
+			return m.FileInfo != nil && m.ModTime().Before(t)
+		},
+	}
+}
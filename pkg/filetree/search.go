@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/becheran/wildmatch-go"
 	"github.com/bmatcuk/doublestar/v4"
 
 	"github.com/anchore/stereoscope/internal/log"
@@ -21,14 +22,34 @@ type Searcher interface {
 	SearchByMIMEType(mimeTypes ...string) ([]file.Resolution, error)
 }
 
+// PredicateSearcher is a Searcher that can search by file metadata predicates.
+type PredicateSearcher interface {
+	Searcher
+	SearchByPredicate(predicate Predicate) ([]file.Resolution, error)
+}
+
+// LinkSearcher is a Searcher that can find all paths that resolve to a given file.
+type LinkSearcher interface {
+	Searcher
+	LinksTo(path string) ([]file.Resolution, error)
+}
+
 type searchContext struct {
 	tree  *FileTree   // this is the tree which all index search results are filtered against
 	index IndexReader // this index is relative to one or more trees, not just necessarily one
 
 	// the following enables correct link resolution when searching via the index
 	linkBackwardRefs map[node.ID]node.IDSet // {link-destination-node-id: str([link-node-id, ...])}
+
+	// foldCase indicates that the current search ignores case (set per search, see withOptions)
+	foldCase bool
 }
 
+var (
+	_ PredicateSearcher = (*searchContext)(nil)
+	_ LinkSearcher      = (*searchContext)(nil)
+)
+
 func NewSearchContext(tree Reader, index IndexReader) Searcher {
 	c := &searchContext{
 		tree:             tree.(*FileTree),
@@ -81,6 +102,12 @@ func (sc *searchContext) buildLinkResolutionIndex() error {
 	return nil
 }
 
+// withOptions returns a copy of the search context configured for a single search with the given options.
+func (sc searchContext) withOptions(options []LinkResolutionOption) searchContext {
+	sc.foldCase = sc.tree.caseInsensitive || newLinkResolutionStrategy(options...).CaseInsensitive
+	return sc
+}
+
 func (sc searchContext) SearchByPath(path string, options ...LinkResolutionOption) (*file.Resolution, error) {
 	// TODO: one day this could leverage indexes outside of the tree, but today this is not implemented
 	options = append(options, FollowBasenameLinks)
@@ -109,6 +136,132 @@ func (sc searchContext) SearchByMIMEType(mimeTypes ...string) ([]file.Resolution
 	return refs, nil
 }
 
+// SearchByPredicate returns all files in the tree with metadata matching the given predicate. Index buckets are used
+// to narrow the set of files considered where the predicate allows, otherwise all indexed files are considered.
+func (sc searchContext) SearchByPredicate(predicate Predicate) ([]file.Resolution, error) {
+	var candidates []IndexEntry
+	var err error
+	if predicate.candidates != nil {
+		candidates, err = predicate.candidates(sc.index)
+	} else {
+		log.Trace("predicate provided is an expensive search, consider combining with an indexed predicate")
+		candidates, err = sc.index.GetByFileType(file.AllTypes()...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch file references for predicate: %w", err)
+	}
+
+	seen := file.NewIDSet()
+	var matches []IndexEntry
+	for _, entry := range candidates {
+		id := entry.ID()
+		if seen.Contains(id) {
+			continue
+		}
+		seen.Add(id)
+		if predicate.Matches(entry.Metadata) {
+			matches = append(matches, entry)
+		}
+	}
+
+	// the index may describe files from several trees (e.g. all layers), so only consider the matching files that are
+	// in this tree, otherwise the metadata of a replaced file could match while the file in the tree does not.
+	nodes, err := sc.fileNodesInTree(matches)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []file.Resolution
+	for _, fn := range nodes {
+		ref, err := sc.firstMatchingReference("**/*", string(fn.RealPath))
+		if err != nil {
+			return nil, err
+		}
+		if ref != nil {
+			refs = append(refs, *ref)
+		}
+	}
+
+	sort.Sort(file.Resolutions(refs))
+
+	return refs, nil
+}
+
+// LinksTo returns all paths in the tree, other than the real path, that resolve to the file at the given path. This
+// includes symlinks and hardlinks to the file (direct and transitive), as well as paths reachable through links to any
+// parent directory (e.g. /bin/sh when /bin is a link to /usr/bin). Each resolution describes the link path as the
+// request path, resolving to the file reference.
+func (sc searchContext) LinksTo(path string) ([]file.Resolution, error) {
+	_, target, err := sc.tree.File(file.Path(path), FollowBasenameLinks)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve path=%q: %w", path, err)
+	}
+	if !target.HasReference() {
+		return nil, nil
+	}
+
+	type alias struct {
+		path file.Path
+		// links are only substituted once per alias, otherwise self-referencing links (e.g. /usr/bin/X11 -> /usr/bin)
+		// would yield an infinite set of paths
+		usedLinks node.IDSet
+	}
+
+	realPath := target.Reference.RealPath
+	observed := file.NewPathSet(realPath)
+	queue := []alias{{path: realPath, usedLinks: node.NewIDSet()}}
+
+	var results []file.Resolution
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		p := string(current.path)
+		for i := nextSegment(p, 1); i > 0; i = nextSegment(p, i+1) {
+			na, err := sc.tree.file(file.Path(p[:i])) // do not follow basename links, only ancestor links
+			if err != nil {
+				return nil, fmt.Errorf("unable to get ref for path=%q: %w", p[:i], err)
+			}
+			if !na.HasFileNode() {
+				continue
+			}
+
+			for _, linkID := range sc.linkBackwardRefs[na.FileNode.ID()].List() {
+				if current.usedLinks.Contains(linkID) {
+					continue
+				}
+				fn := sc.tree.tree.Node(linkID)
+				if fn == nil {
+					continue
+				}
+
+				aliasPath := file.Path(string(fn.(*filenode.FileNode).RealPath) + p[i:])
+				if observed.Contains(aliasPath) {
+					continue
+				}
+				observed.Add(aliasPath)
+
+				_, ref, err := sc.tree.File(aliasPath, FollowBasenameLinks)
+				if err != nil {
+					return nil, fmt.Errorf("unable to resolve link path=%q: %w", aliasPath, err)
+				}
+				if !ref.HasReference() || ref.Reference.ID() != target.Reference.ID() {
+					continue
+				}
+				results = append(results, *ref)
+
+				usedLinks := node.NewIDSet(linkID)
+				usedLinks.Merge(current.usedLinks)
+				queue = append(queue, alias{path: aliasPath, usedLinks: usedLinks})
+			}
+		}
+	}
+
+	sort.Sort(file.Resolutions(results))
+
+	return results, nil
+}
+
 // add case for status.d/* like things that hook up directly into filetree.ListPaths()
 
 func (sc searchContext) SearchByGlob(pattern string, options ...LinkResolutionOption) ([]file.Resolution, error) {
@@ -122,6 +275,8 @@ func (sc searchContext) SearchByGlob(pattern string, options ...LinkResolutionOp
 		return refs, nil
 	}
 
+	sc = sc.withOptions(options)
+
 	var allRefs []file.Resolution
 	for _, request := range parseGlob(pattern) {
 		refs, err := sc.searchByRequest(request, options...)
@@ -149,7 +304,7 @@ func (sc searchContext) searchByRequest(request searchRequest, options ...LinkRe
 		}
 		return []file.Resolution{*ref}, nil
 	case searchByBasename:
-		indexes, err := sc.index.GetByBasename(request.indexLookup)
+		indexes, err := sc.getByBasename(request.indexLookup)
 		if err != nil {
 			return nil, fmt.Errorf("unable to search by basename=%q: %w", request.indexLookup, err)
 		}
@@ -159,6 +314,10 @@ func (sc searchContext) searchByRequest(request searchRequest, options ...LinkRe
 		}
 		return resolutions, nil
 	case searchByBasenameGlob:
+		if sc.foldCase {
+			pattern := wildmatch.NewWildMatch(strings.ToLower(request.indexLookup))
+			return sc.searchByFoldedBasename(request, pattern.IsMatch)
+		}
 		indexes, err := sc.index.GetByBasenameGlob(request.indexLookup)
 		if err != nil {
 			return nil, fmt.Errorf("unable to search by basename-glob=%q: %w", request.indexLookup, err)
@@ -169,6 +328,12 @@ func (sc searchContext) searchByRequest(request searchRequest, options ...LinkRe
 		}
 		return resolutions, nil
 	case searchByExtension:
+		if sc.foldCase {
+			extension := strings.ToLower(request.indexLookup)
+			return sc.searchByFoldedBasename(request, func(basename string) bool {
+				return strings.HasSuffix(basename, extension)
+			})
+		}
 		indexes, err := sc.index.GetByExtension(request.indexLookup)
 		if err != nil {
 			return nil, fmt.Errorf("unable to search by extension=%q: %w", request.indexLookup, err)
@@ -184,15 +349,64 @@ func (sc searchContext) searchByRequest(request searchRequest, options ...LinkRe
 	case searchByGlob:
 		log.WithFields("glob", request.glob).Trace("glob provided is an expensive search, consider using a more specific indexed search")
 
-		options = append(options, FollowBasenameLinks)
-		return sc.tree.FilesByGlob(request.glob, options...)
+		return sc.searchByTreeGlob(request, options...)
 	}
 
 	return nil, fmt.Errorf("invalid search request: %+v", request.searchBasis)
 }
 
+func (sc searchContext) searchByTreeGlob(request searchRequest, options ...LinkResolutionOption) ([]file.Resolution, error) {
+	options = append(options, FollowBasenameLinks)
+	if sc.foldCase {
+		options = append(options, CaseInsensitive)
+	}
+	return sc.tree.FilesByGlob(request.glob, options...)
+}
+
+// searchByFoldedBasename returns the first matching reference for all indexed files with a lower case basename
+// accepted by the given function. Extensions and basename globs are only indexed as given, so all indexed files
+// are considered.
+func (sc searchContext) searchByFoldedBasename(request searchRequest, accept func(basename string) bool) ([]file.Resolution, error) {
+	log.WithFields("glob", request.glob).Trace("case-insensitive search is expensive, consider using a case-sensitive search")
+
+	entries, err := sc.index.GetByFileType(file.AllTypes()...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to search by %s=%q: %w", request.searchBasis, request.indexLookup, err)
+	}
+
+	var candidates []IndexEntry
+	for _, entry := range entries {
+		if accept(strings.ToLower(entry.RealPath.Basename())) {
+			candidates = append(candidates, entry)
+		}
+	}
+	return sc.firstMatchingReferences(request.glob, candidates)
+}
+
+func (sc searchContext) getByBasename(basename string) ([]IndexEntry, error) {
+	if !sc.foldCase {
+		return sc.index.GetByBasename(basename)
+	}
+	if foldIdx, ok := sc.index.(BasenameFoldIndexReader); ok {
+		return foldIdx.GetByBasenameFold(basename)
+	}
+
+	// the index cannot be searched ignoring case, so all entries must be considered
+	entries, err := sc.index.GetByFileType(file.AllTypes()...)
+	if err != nil {
+		return nil, err
+	}
+	var matches []IndexEntry
+	for _, entry := range entries {
+		if strings.EqualFold(entry.RealPath.Basename(), basename) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches, nil
+}
+
 func (sc searchContext) searchByParentBasename(request searchRequest) ([]file.Resolution, error) {
-	indexes, err := sc.index.GetByBasename(request.indexLookup)
+	indexes, err := sc.getByBasename(request.indexLookup)
 	if err != nil {
 		return nil, fmt.Errorf("unable to search by extension=%q: %w", request.indexLookup, err)
 	}
@@ -233,9 +447,15 @@ func (sc searchContext) firstMatchingReferences(glob string, entries []IndexEntr
 	return references, nil
 }
 
-func matchesGlob(ref file.Resolution, glob string) (bool, error) {
+func matchesGlob(ref file.Resolution, glob string, foldCase bool) (bool, error) {
+	if foldCase {
+		glob = strings.ToLower(glob)
+	}
 	allRefPaths := ref.AllRequestPaths()
 	for _, p := range allRefPaths {
+		if foldCase {
+			p = file.Path(strings.ToLower(string(p)))
+		}
 		matched, err := doublestar.Match(glob, string(p))
 		if err != nil {
 			return false, fmt.Errorf("unable to match glob pattern=%q to path=%q: %w", glob, p, err)
@@ -272,7 +492,7 @@ func (sc searchContext) firstPathToNode(observedPaths file.PathSet, glob string,
 		return nil, err
 	}
 	if ref != nil {
-		matches, err := matchesGlob(*ref, glob)
+		matches, err := matchesGlob(*ref, glob, sc.foldCase)
 		if err != nil {
 			return nil, err
 		}
@@ -2,10 +2,14 @@ package filetree
 
 import (
 	"fmt"
+	"io/fs"
+	"path"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/anchore/stereoscope/pkg/file"
@@ -327,6 +331,188 @@ func Test_searchContext_SearchByGlob(t *testing.T) {
 	}
 }
 
+func Test_searchContext_CaseInsensitive(t *testing.T) {
+	tree := New()
+	idx := NewIndex()
+	add := func(p file.Path, ty file.Type, ref *file.Reference, err error) {
+		t.Helper()
+		require.NoError(t, err)
+		idx.Add(*ref, file.Metadata{Path: string(p), Type: ty})
+	}
+	for _, p := range []file.Path{"/app/node_modules/Lodash/Package.json", "/app/lib/Foo.JAR", "/app/META-INF/MANIFEST.MF"} {
+		ref, err := tree.AddFile(p)
+		add(p, file.TypeRegular, ref, err)
+	}
+	ref, err := tree.AddDir("/app/META-INF")
+	add("/app/META-INF", file.TypeDirectory, ref, err)
+	ref, err = tree.AddSymLink("/link", "/app")
+	add("/link", file.TypeSymLink, ref, err)
+
+	sc := NewSearchContext(tree, idx)
+
+	tests := []struct {
+		glob string
+		want []file.Path
+	}{
+		{
+			glob: "/app/node_modules/lodash/package.json",
+			want: []file.Path{"/app/node_modules/Lodash/Package.json"},
+		},
+		{
+			glob: "**/package.json",
+			want: []file.Path{"/app/node_modules/Lodash/Package.json"},
+		},
+		{
+			glob: "**/*.jar",
+			want: []file.Path{"/app/lib/Foo.JAR"},
+		},
+		{
+			glob: "**/foo.*",
+			want: []file.Path{"/app/lib/Foo.JAR"},
+		},
+		{
+			glob: "**/meta-inf/*",
+			want: []file.Path{"/app/META-INF/MANIFEST.MF"},
+		},
+		{
+			glob: "/link/lib/foo.jar",
+			want: []file.Path{"/app/lib/Foo.JAR"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.glob, func(t *testing.T) {
+			results, err := sc.SearchByGlob(tt.glob)
+			require.NoError(t, err)
+			assert.Empty(t, results, "searches are case-sensitive by default")
+
+			results, err = sc.SearchByGlob(tt.glob, CaseInsensitive)
+			require.NoError(t, err)
+			var paths []file.Path
+			for _, r := range results {
+				paths = append(paths, r.RealPath)
+			}
+			assert.Equal(t, tt.want, paths)
+		})
+	}
+
+	resolution, err := sc.SearchByPath("/APP/lib/foo.jar", CaseInsensitive)
+	require.NoError(t, err)
+	require.NotNil(t, resolution)
+	assert.Equal(t, file.Path("/app/lib/Foo.JAR"), resolution.RealPath)
+
+	// the tree-level mode applies to all searches
+	tree.SetCaseInsensitive(true)
+	results, err := sc.SearchByGlob("**/*.jar")
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+}
+
+func Test_searchContext_LinksTo(t *testing.T) {
+	tree := New()
+	idx := NewIndex()
+	add := func(p file.Path, ty file.Type, ref *file.Reference, err error) {
+		t.Helper()
+		require.NoError(t, err)
+		idx.Add(*ref, file.Metadata{Path: string(p), Type: ty})
+	}
+
+	//  /usr/bin/python3.11            (the real file)
+	//  /usr/bin/python3 -> python3.11
+	//  /usr/bin/python -> python3     (transitive)
+	//  /usr/local/bin/py              (hardlink to /usr/bin/python3.11)
+	//  /bin -> /usr/bin               (ancestor link)
+	//  /usr/bin/X11 -> /usr/bin       (self-referencing ancestor link)
+	//  /usr/bin/other                 (unrelated file)
+	ref, err := tree.AddFile("/usr/bin/python3.11")
+	add("/usr/bin/python3.11", file.TypeRegular, ref, err)
+	ref, err = tree.AddFile("/usr/bin/other")
+	add("/usr/bin/other", file.TypeRegular, ref, err)
+	ref, err = tree.AddSymLink("/usr/bin/python3", "python3.11")
+	add("/usr/bin/python3", file.TypeSymLink, ref, err)
+	ref, err = tree.AddSymLink("/usr/bin/python", "python3")
+	add("/usr/bin/python", file.TypeSymLink, ref, err)
+	ref, err = tree.AddHardLink("/usr/local/bin/py", "/usr/bin/python3.11")
+	add("/usr/local/bin/py", file.TypeHardLink, ref, err)
+	ref, err = tree.AddSymLink("/bin", "/usr/bin")
+	add("/bin", file.TypeSymLink, ref, err)
+	ref, err = tree.AddSymLink("/usr/bin/X11", "/usr/bin")
+	add("/usr/bin/X11", file.TypeSymLink, ref, err)
+
+	sc := NewSearchContext(tree, idx).(LinkSearcher)
+
+	tests := []struct {
+		name string
+		path string
+		want []file.Path
+	}{
+		{
+			name: "all aliases of a file",
+			path: "/usr/bin/python3.11",
+			want: []file.Path{
+				"/bin/X11/python",
+				"/bin/X11/python3",
+				"/bin/X11/python3.11",
+				"/bin/python",
+				"/bin/python3",
+				"/bin/python3.11",
+				"/usr/bin/X11/python",
+				"/usr/bin/X11/python3",
+				"/usr/bin/X11/python3.11",
+				"/usr/bin/python",
+				"/usr/bin/python3",
+				"/usr/local/bin/py",
+			},
+		},
+		{
+			name: "given path is resolved to the file first",
+			path: "/bin/python",
+			want: []file.Path{
+				"/bin/X11/python",
+				"/bin/X11/python3",
+				"/bin/X11/python3.11",
+				"/bin/python",
+				"/bin/python3",
+				"/bin/python3.11",
+				"/usr/bin/X11/python",
+				"/usr/bin/X11/python3",
+				"/usr/bin/X11/python3.11",
+				"/usr/bin/python",
+				"/usr/bin/python3",
+				"/usr/local/bin/py",
+			},
+		},
+		{
+			name: "only ancestor links",
+			path: "/usr/bin/other",
+			want: []file.Path{
+				"/bin/X11/other",
+				"/bin/other",
+				"/usr/bin/X11/other",
+			},
+		},
+		{
+			name: "missing file",
+			path: "/usr/bin/missing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, target, err := tree.File(file.Path(tt.path), FollowBasenameLinks)
+			require.NoError(t, err)
+
+			results, err := sc.LinksTo(tt.path)
+			require.NoError(t, err)
+
+			var paths []file.Path
+			for _, r := range results {
+				paths = append(paths, r.RequestPath)
+				assert.Equal(t, target.Reference.ID(), r.Reference.ID())
+			}
+			assert.ElementsMatch(t, tt.want, paths)
+		})
+	}
+}
+
 func Test_searchContext_SearchByMIMEType(t *testing.T) {
 	type fields struct {
 		tree  *FileTree
@@ -490,3 +676,154 @@ func Test_nextSegment(t *testing.T) {
 		})
 	}
 }
+
+func Test_searchContext_SearchByPredicate(t *testing.T) {
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	idx := NewIndex()
+	tree := New()
+
+	add := func(tr *FileTree, p file.Path, uid int, mode fs.FileMode, size int64) {
+		ref, err := tr.AddFile(p)
+		require.NoError(t, err)
+		require.NotNil(t, ref)
+		idx.Add(*ref, file.Metadata{
+			FileInfo: file.ManualInfo{
+				NameValue:    path.Base(string(p)),
+				SizeValue:    size,
+				ModeValue:    mode,
+				ModTimeValue: modTime,
+			},
+			Path:    string(p),
+			Type:    file.TypeRegular,
+			UserID:  uid,
+			GroupID: uid,
+		})
+	}
+
+	add(tree, "/usr/bin/passwd", 0, fs.ModeSetuid|0o755, 100)
+	add(tree, "/usr/bin/wall", 0, fs.ModeSetgid|0o755, 200)
+	add(tree, "/var/tmp/scratch", 1000, 0o666, 300)
+	add(tree, "/etc/shadow", 42, 0o640, 400)
+
+	linkRef, err := tree.AddSymLink("/bin", "/usr/bin")
+	require.NoError(t, err)
+	idx.Add(*linkRef, file.Metadata{Path: "/bin", Type: file.TypeSymLink, LinkDestination: "/usr/bin"})
+
+	// a file in another tree sharing the same index (e.g. a lower layer) that has been replaced in the searched tree
+	add(New(), "/etc/shadow", 0, fs.ModeSetuid|0o777, 400)
+
+	sc := NewSearchContext(tree, idx).(PredicateSearcher)
+
+	tests := []struct {
+		name      string
+		predicate Predicate
+		want      []string
+	}{
+		{
+			name:      "setuid files",
+			predicate: IsSetUID(),
+			want:      []string{"/usr/bin/passwd"},
+		},
+		{
+			name:      "setuid or setgid files",
+			predicate: Or(IsSetUID(), IsSetGID()),
+			want:      []string{"/usr/bin/passwd", "/usr/bin/wall"},
+		},
+		{
+			name:      "world writable files",
+			predicate: IsWorldWritable(),
+			want:      []string{"/var/tmp/scratch"},
+		},
+		{
+			name:      "regular files not owned by root",
+			predicate: And(HasFileType(file.TypeRegular), Not(OwnedByUser(0))),
+			want:      []string{"/etc/shadow", "/var/tmp/scratch"},
+		},
+		{
+			name:      "size range",
+			predicate: And(SizeAtLeast(200), SizeAtMost(300)),
+			want:      []string{"/usr/bin/wall", "/var/tmp/scratch"},
+		},
+		{
+			name:      "modified time range",
+			predicate: And(ModifiedAfter(modTime.Add(-time.Hour)), ModifiedBefore(modTime.Add(time.Hour))),
+			want:      []string{"/etc/shadow", "/usr/bin/passwd", "/usr/bin/wall", "/var/tmp/scratch"},
+		},
+		{
+			name:      "modified after range",
+			predicate: ModifiedAfter(modTime),
+		},
+		{
+			name:      "indexed basename with metadata condition",
+			predicate: And(HasBasename("passwd", "shadow"), OwnedByUser(0)),
+			want:      []string{"/usr/bin/passwd"},
+		},
+		{
+			name:      "path glob",
+			predicate: And(HasFileType(file.TypeRegular), PathMatches("/usr/**")),
+			want:      []string{"/usr/bin/passwd", "/usr/bin/wall"},
+		},
+		{
+			name:      "custom match func",
+			predicate: MatchFunc(func(m file.Metadata) bool { return m.GroupID == 42 }),
+			want:      []string{"/etc/shadow"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sc.SearchByPredicate(tt.predicate)
+			require.NoError(t, err)
+
+			var paths []string
+			for _, r := range got {
+				paths = append(paths, string(r.RequestPath))
+			}
+			assert.Equal(t, tt.want, paths)
+		})
+	}
+}
+
+// indexReaderOnly hides all optional capabilities of an index (as with an external IndexReader implementation).
+type indexReaderOnly struct {
+	IndexReader
+}
+
+func Test_searchContext_SearchByPredicate_withoutXattrIndex(t *testing.T) {
+	idx := NewIndex()
+	tree := New()
+
+	add := func(p file.Path, xattrs map[string]string) {
+		ref, err := tree.AddFile(p)
+		require.NoError(t, err)
+		idx.Add(*ref, file.Metadata{Path: string(p), Type: file.TypeRegular, Xattrs: xattrs})
+	}
+
+	add("/usr/bin/ping", map[string]string{file.CapabilitiesXattr: "\x01\x00\x00\x02"})
+	add("/etc/passwd", nil)
+
+	sc := NewSearchContext(tree, indexReaderOnly{IndexReader: idx}).(PredicateSearcher)
+
+	got, err := sc.SearchByPredicate(HasCapabilities())
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, file.Path("/usr/bin/ping"), got[0].RequestPath)
+}
+
+func Test_searchContext_SearchByGlob_withoutBasenameFoldIndex(t *testing.T) {
+	idx := NewIndex()
+	tree := New()
+
+	for _, p := range []file.Path{"/app/README.md", "/app/readme.txt"} {
+		ref, err := tree.AddFile(p)
+		require.NoError(t, err)
+		idx.Add(*ref, file.Metadata{Path: string(p), Type: file.TypeRegular})
+	}
+
+	sc := NewSearchContext(tree, indexReaderOnly{IndexReader: idx})
+
+	got, err := sc.SearchByGlob("**/readme.md", CaseInsensitive)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, file.Path("/app/README.md"), got[0].RequestPath)
+}
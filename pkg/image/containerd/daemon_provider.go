@@ -3,8 +3,10 @@ package containerd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
+	"net/http"
 	"os"
 	"path"
 	"strings"
@@ -16,7 +18,9 @@ import (
 	"github.com/containerd/containerd/v2/core/images/archive"
 	"github.com/containerd/containerd/v2/core/remotes/docker"
 	"github.com/containerd/containerd/v2/core/remotes/docker/config"
+	remoteErrors "github.com/containerd/containerd/v2/core/remotes/errors"
 	"github.com/containerd/containerd/v2/pkg/namespaces"
+	"github.com/containerd/errdefs"
 	"github.com/containerd/platforms"
 	"github.com/google/go-containerregistry/pkg/name"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
@@ -34,19 +38,51 @@ import (
 
 const Daemon image.Source = image.ContainerdDaemonSource
 
+// DaemonProviderOption configures optional behavior of the containerd daemon provider.
+type DaemonProviderOption func(*daemonImageProvider)
+
+// WithPullPolicy sets when the image is pulled into containerd (image.PullIfMissing by default).
+func WithPullPolicy(policy image.PullPolicy) DaemonProviderOption {
+	return func(p *daemonImageProvider) {
+		p.pullPolicy = policy
+	}
+}
+
+// WithRemovePulledImage removes the image from containerd once it has been saved, but only when the image was not
+// present in containerd before the provider pulled it.
+func WithRemovePulledImage() DaemonProviderOption {
+	return func(p *daemonImageProvider) {
+		p.removePulledImage = true
+	}
+}
+
+// WithAdditionalMetadata applies the given metadata to the image before it is read (for example image.WithReadLimits),
+// overriding any provider defaults.
+func WithAdditionalMetadata(metadata ...image.AdditionalMetadata) DaemonProviderOption {
+	return func(p *daemonImageProvider) {
+		p.additionalMetadata = append(p.additionalMetadata, metadata...)
+	}
+}
+
 // NewDaemonProvider creates a new provider instance for a specific image that will later be cached to the given directory.
-func NewDaemonProvider(tmpDirGen *file.TempDirGenerator, registryOptions image.RegistryOptions, namespace string, imageStr string, platform *image.Platform) image.Provider {
+func NewDaemonProvider(tmpDirGen *file.TempDirGenerator, registryOptions image.RegistryOptions, namespace string, imageStr string, platform *image.Platform, options ...DaemonProviderOption) image.Provider {
 	if namespace == "" {
 		namespace = namespaces.Default
 	}
 
-	return &daemonImageProvider{
+	p := &daemonImageProvider{
 		imageStr:        imageStr,
 		tmpDirGen:       tmpDirGen,
 		platform:        platform,
 		namespace:       namespace,
 		registryOptions: registryOptions,
 	}
+	for _, option := range options {
+		if option != nil {
+			option(p)
+		}
+	}
+	return p
 }
 
 var mb = math.Pow(2, 20)
@@ -58,6 +94,10 @@ type daemonImageProvider struct {
 	platform        *image.Platform
 	namespace       string
 	registryOptions image.RegistryOptions
+
+	pullPolicy         image.PullPolicy
+	removePulledImage  bool
+	additionalMetadata []image.AdditionalMetadata
 }
 
 func (p *daemonImageProvider) Name() string {
@@ -74,7 +114,7 @@ func (p *daemonImageProvider) Provide(ctx context.Context) (*image.Image, error)
 	startTime := time.Now()
 	client, err := containerdClient.GetClient()
 	if err != nil {
-		return nil, fmt.Errorf("containerd not available: %w", err)
+		return nil, fmt.Errorf("containerd not available: %w: %w", image.ErrDaemonUnavailable, err)
 	}
 
 	defer func() {
@@ -85,7 +125,10 @@ func (p *daemonImageProvider) Provide(ctx context.Context) (*image.Image, error)
 
 	ctx = namespaces.WithNamespace(ctx, p.namespace)
 
-	resolvedImage, resolvedPlatform, err := p.pullImageIfMissing(ctx, client)
+	resolvedImage, resolvedPlatform, pulledNames, err := p.pullImageByPolicy(ctx, client)
+	if p.removePulledImage {
+		defer removeImages(ctx, client, pulledNames...)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -93,6 +136,14 @@ func (p *daemonImageProvider) Provide(ctx context.Context) (*image.Image, error)
 	log.WithFields("image", p.imageStr, "time", time.Since(startTime)).Info("containerd pulled image")
 	startTime = time.Now()
 
+	// note: the repo digest is not part of the exported archive, so it must be captured from the image store
+	var repoDigests []string
+	if digest, err := p.repoDigest(ctx, client); err != nil {
+		log.WithFields("image", p.imageStr, "error", err).Debug("unable to determine repo digest")
+	} else {
+		repoDigests = append(repoDigests, digest)
+	}
+
 	tarFileName, err := p.saveImage(ctx, client, resolvedImage)
 	if err != nil {
 		return nil, err
@@ -101,20 +152,46 @@ func (p *daemonImageProvider) Provide(ctx context.Context) (*image.Image, error)
 	log.WithFields("image", p.imageStr, "time", time.Since(startTime)).Info("containerd saved image")
 
 	// use the existing tarball provider to process what was pulled from the containerd daemon
-	return stereoscopeDocker.NewArchiveProvider(p.tmpDirGen, tarFileName, withMetadata(resolvedPlatform, p.imageStr)...).
+	img, err := stereoscopeDocker.NewArchiveProvider(p.tmpDirGen, tarFileName, append(withMetadata(resolvedPlatform, p.imageStr, repoDigests...), p.additionalMetadata...)...).
 		Provide(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := image.VerifyProvidedImage(p.imageStr, img); err != nil {
+		return nil, err
+	}
+	return img, nil
 }
 
-// pull a containerd image
-func (p *daemonImageProvider) pull(ctx context.Context, c *client.Client, resolvedImage string) (client.Image, error) {
-	var platformStr string
+// repoDigest returns the repo digest for the image within containerd (the digest of the manifest or index that the
+// image name refers to).
+func (p *daemonImageProvider) repoDigest(ctx context.Context, c *client.Client) (string, error) {
+	img, err := c.GetImage(ctx, p.imageStr)
+	if err != nil {
+		return "", err
+	}
+
+	ref, err := name.ParseReference(p.imageStr, prepareReferenceOptions(p.registryOptions)...)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse registry reference=%q: %w", p.imageStr, err)
+	}
+	return fmt.Sprintf("%s@%s", ref.Context().Name(), img.Target().Digest), nil
+}
+
+// pull a containerd image from the given source
+func (p *daemonImageProvider) pull(ctx context.Context, c *client.Client, source image.PullSource) (client.Image, error) {
+	resolvedImage := source.Reference
+
+	var platformStr, osVersion string
 	if p.platform != nil {
 		platformStr = p.platform.String()
+		osVersion = p.platform.OSVersion
 	}
 
 	// note: if not platform is provided then containerd will default to linux/amd64 automatically. We don't override
 	// this behavior here and intentionally show that the value is blank in the log.
-	log.WithFields("image", resolvedImage, "platform", platformStr).Debug("pulling containerd")
+	log.WithFields("image", resolvedImage, "platform", platformStr, "osVersion", osVersion).Debug("pulling containerd")
 
 	ongoing := newJobs(resolvedImage)
 
@@ -133,12 +210,18 @@ func (p *daemonImageProvider) pull(ctx context.Context, c *client.Client, resolv
 		return nil, nil
 	})
 
-	ref, err := name.ParseReference(p.imageStr, prepareReferenceOptions(p.registryOptions)...)
+	registryOptions := p.registryOptions
+	if source.Insecure {
+		registryOptions.InsecureUseHTTP = true
+		registryOptions.InsecureSkipTLSVerify = true
+	}
+
+	ref, err := name.ParseReference(resolvedImage, prepareReferenceOptions(registryOptions)...)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse registry reference=%q: %+v", p.imageStr, err)
+		return nil, fmt.Errorf("unable to parse registry reference=%q: %+v", resolvedImage, err)
 	}
 
-	options, err := p.pullOptions(ctx, ref)
+	options, err := p.pullOptions(ctx, registryOptions, ref)
 	if err != nil {
 		return nil, fmt.Errorf("unable to prepare pull options: %w", err)
 	}
@@ -147,32 +230,63 @@ func (p *daemonImageProvider) pull(ctx context.Context, c *client.Client, resolv
 	// note: this will return an image object with the platform correctly set (if it exists)
 	resp, err := c.Pull(ctx, resolvedImage, options...)
 	if err != nil {
-		return nil, fmt.Errorf("pull failed: %w", err)
+		return nil, fmt.Errorf("pull failed: %w", classifyContainerdError(err))
+	}
+
+	if source.Reference != source.Canonical {
+		// the image was pulled from a mirror (or a rewritten location), make it available under the canonical name
+		// as well so that it is found locally on subsequent requests
+		if err := tagImage(ctx, c, resp.Target(), source.Canonical); err != nil {
+			return nil, err
+		}
 	}
 
 	return resp, nil
 }
 
-func (p *daemonImageProvider) pullOptions(ctx context.Context, ref name.Reference) ([]client.RemoteOpt, error) {
+// tagImage names the given image target within the containerd image store, replacing any existing image with the name.
+func tagImage(ctx context.Context, c *client.Client, target ocispec.Descriptor, name string) error {
+	img := images.Image{Name: name, Target: target}
+	if _, err := c.ImageService().Create(ctx, img); err != nil {
+		if !errdefs.IsAlreadyExists(err) {
+			return fmt.Errorf("unable to tag image=%q: %w", name, err)
+		}
+		if _, err := c.ImageService().Update(ctx, img, "target"); err != nil {
+			return fmt.Errorf("unable to tag image=%q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (p *daemonImageProvider) pullOptions(ctx context.Context, registryOptions image.RegistryOptions, ref name.Reference) ([]client.RemoteOpt, error) {
 	options := []client.RemoteOpt{
 		client.WithPlatform(p.platform.String()),
 	}
 
+	if p.platform != nil && p.platform.OSVersion != "" {
+		// the OS version cannot be expressed within the platform string, so an explicit matcher is required
+		platformObj, err := ociPlatform(p.platform)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse platform: %w", err)
+		}
+		options = append(options, client.WithPlatformMatcher(platforms.Only(platformObj)))
+	}
+
 	dockerOptions := docker.ResolverOptions{
 		Tracker: docker.NewInMemoryTracker(),
 	}
 
-	if p.registryOptions.Keychain != nil {
+	if registryOptions.Keychain != nil {
 		log.Warn("keychain registry option provided but is not supported for containerd daemon image provider")
 	}
 
 	var hostOptions config.HostOptions
 
-	if len(p.registryOptions.Credentials) > 0 {
+	if len(registryOptions.Credentials) > 0 {
 		hostOptions.Credentials = func(host string) (string, string, error) {
 			// TODO: how should a bearer token be handled here?
 
-			auth := p.registryOptions.Authenticator(host)
+			auth := registryOptions.Authenticator(host)
 			if auth != nil {
 				cfg, err := auth.Authorization()
 				if err != nil {
@@ -186,7 +300,7 @@ func (p *daemonImageProvider) pullOptions(ctx context.Context, ref name.Referenc
 		}
 	}
 
-	switch p.registryOptions.InsecureUseHTTP {
+	switch registryOptions.InsecureUseHTTP {
 	case true:
 		hostOptions.DefaultScheme = "http"
 	default:
@@ -195,7 +309,7 @@ func (p *daemonImageProvider) pullOptions(ctx context.Context, ref name.Referenc
 
 	registryName := ref.Context().RegistryStr()
 
-	tlsConfig, err := p.registryOptions.TLSConfig(registryName)
+	tlsConfig, err := registryOptions.TLSConfig(registryName)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get TLS config for registry=%q: %w", registryName, err)
 	}
@@ -261,7 +375,7 @@ func (p *daemonImageProvider) resolveImage(ctx context.Context, client *client.C
 			return "", nil, fmt.Errorf("unable to unmarshal manifest list: %w", err)
 		}
 
-		platformObj, err := platforms.Parse(p.platform.String())
+		platformObj, err := ociPlatform(p.platform)
 		if err != nil {
 			return "", nil, fmt.Errorf("unable to parse platform: %w", err)
 		}
@@ -276,7 +390,7 @@ func (p *daemonImageProvider) resolveImage(ctx context.Context, client *client.C
 		}
 
 		// no manifest found for the platform we want
-		return imageStr, nil, fmt.Errorf("no manifest found in manifest list for platform %q", p.platform.String())
+		return imageStr, nil, fmt.Errorf("no manifest found in manifest list for platform %q", platforms.FormatAll(platformObj))
 	}
 
 	return "", nil, fmt.Errorf("unexpected mediaType for image: %q", desc.MediaType)
@@ -324,34 +438,109 @@ func (p *daemonImageProvider) fetchPlatformFromConfig(ctx context.Context, clien
 	return &cfg, nil
 }
 
-func (p *daemonImageProvider) pullImageIfMissing(ctx context.Context, client *client.Client) (string, *platforms.Platform, error) {
-	p.imageStr = ensureRegistryHostPrefix(p.imageStr)
+// pullImageByPolicy pulls the image into containerd as needed according to the pull policy, returning the resolved
+// image and platform along with the image names that did not exist in containerd before pulling.
+func (p *daemonImageProvider) pullImageByPolicy(ctx context.Context, client *client.Client) (string, *platforms.Platform, []string, error) {
+	sources, err := p.registryOptions.PullSources(p.imageStr)
+	if err != nil {
+		return "", nil, nil, err
+	}
 
-	// try to get the image first before pulling
+	// try to get the image first before pulling (by the name of the first candidate, which is the only candidate
+	// unless unqualified search registries have been configured)
+	p.imageStr = sources[0].Canonical
 	resolvedImage, resolvedPlatform, err := p.resolveImage(ctx, client, p.imageStr)
+	if err != nil && errdefs.IsUnavailable(err) {
+		return "", nil, nil, fmt.Errorf("containerd not available: %w", classifyContainerdError(err))
+	}
+	missing := err != nil
+
+	var pulledNames []string
+	switch {
+	case missing && p.pullPolicy == image.PullNever:
+		return "", nil, nil, fmt.Errorf("%w: image=%q is not present in containerd and the pull policy is %q", image.ErrImageNotFound, p.imageStr, image.PullNever)
+	case missing || p.pullPolicy == image.PullAlways:
+		if p.registryOptions.Offline {
+			return "", nil, nil, fmt.Errorf("%w: unable to pull image=%q into containerd", image.ErrOffline, p.imageStr)
+		}
 
-	imageStr := resolvedImage
-	if imageStr == "" {
-		imageStr = p.imageStr
-	}
-
-	if err != nil {
-		_, err := p.pull(ctx, client, imageStr)
+		source, err := p.pullFromSources(ctx, client, sources)
 		if err != nil {
-			return "", nil, err
+			return "", nil, nil, err
 		}
+		p.imageStr = source.Canonical
 
-		resolvedImage, resolvedPlatform, err = p.resolveImage(ctx, client, imageStr)
+		if missing {
+			pulledNames = append(pulledNames, source.Canonical)
+			if source.Reference != source.Canonical {
+				pulledNames = append(pulledNames, source.Reference)
+			}
+		}
+
+		resolvedImage, resolvedPlatform, err = p.resolveImage(ctx, client, p.imageStr)
 		if err != nil {
-			return "", nil, fmt.Errorf("unable to resolve image after pull: %w", err)
+			return "", nil, pulledNames, fmt.Errorf("unable to resolve image after pull: %w", err)
 		}
 	}
 
 	if err := validatePlatform(p.platform, resolvedPlatform); err != nil {
-		return "", nil, fmt.Errorf("platform validation failed: %w", err)
+		return "", nil, pulledNames, fmt.Errorf("platform validation failed: %w", err)
 	}
 
-	return resolvedImage, resolvedPlatform, nil
+	return resolvedImage, resolvedPlatform, pulledNames, nil
+}
+
+// pullFromSources pulls the image from the first source that succeeds (mirrors are ordered before the registry).
+func (p *daemonImageProvider) pullFromSources(ctx context.Context, client *client.Client, sources []image.PullSource) (image.PullSource, error) {
+	var errs []error
+	for _, source := range sources {
+		if _, err := p.pull(ctx, client, source); err != nil {
+			log.WithFields("image", source.Reference, "mirror", source.Mirror, "error", err).Debug("unable to pull image from source")
+			errs = append(errs, err)
+			continue
+		}
+		return source, nil
+	}
+	return image.PullSource{}, errors.Join(errs...)
+}
+
+// removeImages removes images that were pulled by the provider from containerd (failures are only logged).
+func removeImages(ctx context.Context, c *client.Client, names ...string) {
+	for _, name := range names {
+		if err := c.ImageService().Delete(ctx, name); err != nil && !errdefs.IsNotFound(err) {
+			log.WithFields("image", name, "error", err).Warn("unable to remove pulled image from containerd")
+			continue
+		}
+		log.WithFields("image", name).Debug("removed pulled image from containerd")
+	}
+}
+
+// classifyContainerdError wraps the given containerd error with the matching stereoscope error (e.g. image.ErrImageNotFound).
+// Errors that cannot be classified are returned as-is.
+func classifyContainerdError(err error) error {
+	var statusErr remoteErrors.ErrUnexpectedStatus
+	switch {
+	case err == nil:
+		return nil
+	case errdefs.IsUnavailable(err):
+		return fmt.Errorf("%w: %w", image.ErrDaemonUnavailable, err)
+	case errdefs.IsNotFound(err):
+		return fmt.Errorf("%w: %w", image.ErrImageNotFound, err)
+	case errdefs.IsUnauthorized(err), errdefs.IsPermissionDenied(err):
+		return fmt.Errorf("%w: %w", image.ErrUnauthorized, err)
+	case errdefs.IsResourceExhausted(err):
+		return fmt.Errorf("%w: %w", image.ErrRateLimited, err)
+	case errors.As(err, &statusErr):
+		switch statusErr.StatusCode {
+		case http.StatusNotFound:
+			return fmt.Errorf("%w: %w", image.ErrImageNotFound, err)
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return fmt.Errorf("%w: %w", image.ErrUnauthorized, err)
+		case http.StatusTooManyRequests:
+			return fmt.Errorf("%w: %w", image.ErrRateLimited, err)
+		}
+	}
+	return err
 }
 
 func validatePlatform(expected *image.Platform, given *platforms.Platform) error {
@@ -449,12 +638,11 @@ func (p *daemonImageProvider) saveImage(ctx context.Context, client *client.Clie
 func exportPlatformComparer(platform *image.Platform) (platforms.MatchComparer, error) {
 	// it is important to only export a single architecture. Default to linux/amd64. Without specifying a specific
 	// architecture then the export may include multiple architectures (if the tag points to a manifest list)
-	platformStr := "linux/amd64"
-	if platform != nil {
-		platformStr = platform.String()
+	if platform == nil {
+		platform = &image.Platform{OS: "linux", Architecture: "amd64"}
 	}
 
-	platformObj, err := platforms.Parse(platformStr)
+	platformObj, err := ociPlatform(platform)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse platform: %w", err)
 	}
@@ -503,7 +691,7 @@ func prepareReferenceOptions(registryOptions image.RegistryOptions) []name.Optio
 	return options
 }
 
-func withMetadata(platform *platforms.Platform, ref string) (metadata []image.AdditionalMetadata) {
+func withMetadata(platform *platforms.Platform, ref string, repoDigests ...string) (metadata []image.AdditionalMetadata) {
 	if platform != nil {
 		metadata = append(metadata,
 			image.WithArchitecture(platform.Architecture, platform.Variant),
@@ -515,24 +703,20 @@ func withMetadata(platform *platforms.Platform, ref string) (metadata []image.Ad
 		// remove digest from ref
 		metadata = append(metadata, image.WithTags(strings.Split(ref, "@")[0]))
 	}
-	return metadata
-}
 
-// if imageName doesn't have an identifiable hostname prefix set,
-// add docker hub by default
-func ensureRegistryHostPrefix(imageName string) string {
-	parts := strings.Split(imageName, "/")
-	if len(parts) == 1 {
-		return fmt.Sprintf("docker.io/library/%s", imageName)
+	if len(repoDigests) > 0 {
+		metadata = append(metadata, image.WithRepoDigests(repoDigests...))
 	}
-	if isRegistryHostname(parts[0]) {
-		return imageName
-	}
-	return fmt.Sprintf("docker.io/%s", imageName)
+	return metadata
 }
 
-// isRegistryHostname returns true if the string passed in can be interpreted
-// as a container registry hostname
-func isRegistryHostname(s string) bool {
-	return s == "localhost" || strings.Contains(s, ".") || strings.Contains(s, ":")
+// ociPlatform converts the stereoscope platform to an OCI platform spec. The OS version is carried separately from
+// the platform string, since containerd does not accept platform specifiers that include an OS version.
+func ociPlatform(platform *image.Platform) (ocispec.Platform, error) {
+	platformObj, err := platforms.Parse(platform.String())
+	if err != nil {
+		return ocispec.Platform{}, err
+	}
+	platformObj.OSVersion = platform.OSVersion
+	return platformObj, nil
 }
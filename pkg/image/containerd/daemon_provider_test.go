@@ -1,71 +1,21 @@
 package containerd
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"testing"
 
+	remoteErrors "github.com/containerd/containerd/v2/core/remotes/errors"
+	"github.com/containerd/errdefs"
 	"github.com/containerd/platforms"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/anchore/stereoscope/pkg/image"
 )
 
-func Test_ensureRegistryHostPrefix(t *testing.T) {
-	tests := []struct {
-		image string
-		want  string
-	}{
-		{
-			image: "alpine:sometag",
-			want:  "docker.io/library/alpine:sometag",
-		},
-		{
-			image: "alpine:latest",
-			want:  "docker.io/library/alpine:latest",
-		},
-		{
-			image: "alpine",
-			want:  "docker.io/library/alpine",
-		},
-		{
-			image: "registry.place.io/thing:version",
-			want:  "registry.place.io/thing:version",
-		},
-		{
-			image: "127.0.0.1/thing:version",
-			want:  "127.0.0.1/thing:version",
-		},
-		{
-			image: "127.0.0.1:1234/thing:version",
-			want:  "127.0.0.1:1234/thing:version",
-		},
-		{
-			image: "localhost/thing:version",
-			want:  "localhost/thing:version",
-		},
-		{
-			image: "localhost:1234/thing:version",
-			want:  "localhost:1234/thing:version",
-		},
-		{
-			image: "alpine@sha256:95cf004f559831017cdf4628aaf1bb30133677be8702a8c5f2994629f637a209",
-			want:  "docker.io/library/alpine@sha256:95cf004f559831017cdf4628aaf1bb30133677be8702a8c5f2994629f637a209",
-		},
-		{
-			image: "alpine:sometag@sha256:95cf004f559831017cdf4628aaf1bb30133677be8702a8c5f2994629f637a209",
-			want:  "docker.io/library/alpine:sometag@sha256:95cf004f559831017cdf4628aaf1bb30133677be8702a8c5f2994629f637a209",
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.image, func(t *testing.T) {
-			got := ensureRegistryHostPrefix(tt.image)
-			require.NotNil(t, got)
-			assert.Equal(t, tt.want, got)
-		})
-	}
-}
-
 func Test_exportPlatformComparer(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -89,6 +39,20 @@ func Test_exportPlatformComparer(t *testing.T) {
 			want:    platforms.OnlyStrict(platforms.MustParse("darwin/arm64")),
 			wantErr: assert.NoError,
 		},
+		{
+			name: "honor provided OS version",
+			platform: func() *image.Platform {
+				p, err := image.NewPlatform("windows/amd64:10.0.17763.5576")
+				require.NoError(t, err)
+				return p
+			}(),
+			want: platforms.OnlyStrict(ocispec.Platform{
+				OS:           "windows",
+				Architecture: "amd64",
+				OSVersion:    "10.0.17763.5576",
+			}),
+			wantErr: assert.NoError,
+		},
 		{
 			// note: platforms.Parse() will still allow for invalid platform values, but not malformed inputs (too many "/")
 			name: "bad platform errors",
@@ -176,3 +140,45 @@ func TestValidatePlatform(t *testing.T) {
 		})
 	}
 }
+
+func Test_classifyContainerdError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{
+			name:    "not found",
+			err:     fmt.Errorf("failed to resolve reference: %w", errdefs.ErrNotFound),
+			wantErr: image.ErrImageNotFound,
+		},
+		{
+			name:    "unavailable",
+			err:     errdefs.ErrUnavailable,
+			wantErr: image.ErrDaemonUnavailable,
+		},
+		{
+			name:    "unauthorized status",
+			err:     fmt.Errorf("failed to authorize: %w", remoteErrors.ErrUnexpectedStatus{StatusCode: http.StatusUnauthorized}),
+			wantErr: image.ErrUnauthorized,
+		},
+		{
+			name:    "rate limited status",
+			err:     remoteErrors.ErrUnexpectedStatus{StatusCode: http.StatusTooManyRequests},
+			wantErr: image.ErrRateLimited,
+		},
+		{
+			name: "unclassified",
+			err:  errors.New("boom"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyContainerdError(tt.err)
+			require.ErrorContains(t, got, tt.err.Error())
+			if tt.wantErr != nil {
+				require.ErrorIs(t, got, tt.wantErr)
+			}
+		})
+	}
+}
@@ -25,3 +25,17 @@ func fetchReaderByPath(ft filetree.Reader, fileCatalog FileCatalogReader, path f
 	}
 	return reader, nil
 }
+
+// fetchSeekableReaderByPath is a common helper function for resolving a random-access reader for the file contents
+// of a path from the file catalog relative to the given tree.
+func fetchSeekableReaderByPath(ft filetree.Reader, fileCatalog FileCatalogReader, path file.Path) (file.SeekableReader, error) {
+	exists, refVia, err := ft.File(path, filetree.FollowBasenameLinks)
+	if err != nil {
+		return nil, err
+	}
+	if !exists && refVia == nil || refVia.Reference == nil {
+		return nil, fmt.Errorf("could not find file path in Tree: %s", path)
+	}
+
+	return openSeekable(fileCatalog, *refVia.Reference)
+}
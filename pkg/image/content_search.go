@@ -0,0 +1,252 @@
+package image
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"runtime"
+	"sort"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/anchore/stereoscope/internal/log"
+	"github.com/anchore/stereoscope/pkg/file"
+	"github.com/anchore/stereoscope/pkg/filetree"
+)
+
+const (
+	// binarySniffSize is the number of bytes inspected at the start of a file to determine if the file is binary.
+	binarySniffSize = 8000
+
+	// maxContentLineSize is the maximum number of bytes of a single line that are held in memory while searching.
+	// Longer lines are searched in segments of this size.
+	maxContentLineSize = 1024 * 1024
+)
+
+// ContentQuery describes a search for content within the files of the squashed image filesystem.
+type ContentQuery struct {
+	// Pattern is a regular expression to search for (mutually exclusive with Literal). Patterns are matched against
+	// a single line at a time (lines longer than 1 MiB are matched in 1 MiB segments, so matches spanning two
+	// segments are not found).
+	Pattern string
+	// Literal is an exact string to search for (mutually exclusive with Pattern)
+	Literal string
+	// Globs restricts the search to files with paths matching at least one of the given glob patterns (all files when empty)
+	Globs []string
+	// MIMETypes restricts the search to files with at least one of the given MIME types (all files when empty)
+	MIMETypes []string
+	// MaxFileSize skips files larger than the given number of bytes (no limit when 0)
+	MaxFileSize int64
+	// IncludeBinary searches files that appear to be binary (these are skipped by default)
+	IncludeBinary bool
+	// Concurrency is the maximum number of files searched at the same time (defaults to the number of CPUs)
+	Concurrency int
+}
+
+// ContentMatch is a single match of a ContentQuery within a file.
+type ContentMatch struct {
+	// Path is the path the file was found at within the squashed filesystem
+	Path file.Path
+	// Reference is the file containing the match
+	Reference file.Reference
+	// Layer is the layer that provides the file containing the match
+	Layer *Layer
+	// Offset is the byte offset of the start of the match within the file
+	Offset int64
+	// Length is the number of bytes matched
+	Length int
+	// Line is the line number of the match within the file (starting at 1)
+	Line int64
+	// Text is the matched text
+	Text string
+}
+
+func (q ContentQuery) regexp() (*regexp.Regexp, error) {
+	switch {
+	case q.Pattern != "" && q.Literal != "":
+		return nil, fmt.Errorf("content query pattern and literal are mutually exclusive")
+	case q.Pattern != "":
+		return regexp.Compile(q.Pattern)
+	case q.Literal != "":
+		return regexp.Compile(regexp.QuoteMeta(q.Literal))
+	}
+	return nil, fmt.Errorf("content query requires a pattern or literal")
+}
+
+// SearchContents searches the contents of all regular files in the squashed image filesystem that satisfy the given
+// query, returning all matches ordered by path and offset.
+func (i *Image) SearchContents(ctx context.Context, query ContentQuery) ([]ContentMatch, error) {
+	pattern, err := query.regexp()
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := i.contentSearchCandidates(query)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := query.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	results := make([][]ContentMatch, len(candidates))
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for idx, candidate := range candidates {
+		g.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			matches, err := i.searchFileContents(ctx, candidate, pattern, query.IncludeBinary)
+			if err != nil {
+				return fmt.Errorf("unable to search contents of %q: %w", candidate.RequestPath, err)
+			}
+			results[idx] = matches
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	var matches []ContentMatch
+	for _, r := range results {
+		matches = append(matches, r...)
+	}
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].Path != matches[b].Path {
+			return matches[a].Path < matches[b].Path
+		}
+		return matches[a].Offset < matches[b].Offset
+	})
+	return matches, nil
+}
+
+// contentSearchCandidates returns all regular files in the squashed filesystem satisfying the path, MIME type, and
+// size filters of the query (each file is only returned once, even if reachable via several paths).
+func (i *Image) contentSearchCandidates(query ContentQuery) ([]file.Resolution, error) {
+	var resolutions []file.Resolution
+	switch {
+	case len(query.Globs) > 0:
+		for _, glob := range query.Globs {
+			refs, err := i.SquashedSearchContext.SearchByGlob(glob)
+			if err != nil {
+				return nil, err
+			}
+			resolutions = append(resolutions, refs...)
+		}
+	case len(query.MIMETypes) > 0:
+		refs, err := i.SquashedSearchContext.SearchByMIMEType(query.MIMETypes...)
+		if err != nil {
+			return nil, err
+		}
+		resolutions = refs
+	default:
+		searcher, ok := i.SquashedSearchContext.(filetree.PredicateSearcher)
+		if !ok {
+			return nil, fmt.Errorf("image search context does not support predicate searches")
+		}
+		refs, err := searcher.SearchByPredicate(filetree.HasFileType(file.TypeRegular))
+		if err != nil {
+			return nil, err
+		}
+		resolutions = refs
+	}
+
+	mimeTypes := make(map[string]struct{}, len(query.MIMETypes))
+	for _, mt := range query.MIMETypes {
+		mimeTypes[mt] = struct{}{}
+	}
+
+	seen := file.NewIDSet()
+	var candidates []file.Resolution
+	for _, r := range resolutions {
+		if !r.HasReference() || seen.Contains(r.Reference.ID()) {
+			continue
+		}
+		entry, err := i.FileCatalog.Get(*r.Reference)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get metadata for %q: %w", r.RequestPath, err)
+		}
+		if entry.Type != file.TypeRegular {
+			continue
+		}
+		if len(mimeTypes) > 0 {
+			if _, ok := mimeTypes[entry.MIMEType]; !ok {
+				continue
+			}
+		}
+		if query.MaxFileSize > 0 && entry.FileInfo != nil && entry.Size() > query.MaxFileSize {
+			log.WithFields("path", r.RequestPath, "size", entry.Size()).Trace("skipping content search of large file")
+			continue
+		}
+		seen.Add(r.Reference.ID())
+		candidates = append(candidates, r)
+	}
+	return candidates, nil
+}
+
+// searchFileContents streams the contents of the given file, returning all matches of the pattern.
+func (i *Image) searchFileContents(ctx context.Context, r file.Resolution, pattern *regexp.Regexp, includeBinary bool) ([]ContentMatch, error) {
+	rc, err := i.FileCatalog.Open(*r.Reference)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	reader := bufio.NewReaderSize(rc, maxContentLineSize)
+
+	if !includeBinary {
+		// note: Peek returns an error when the file is smaller than the requested size, which is not a concern here
+		head, _ := reader.Peek(binarySniffSize)
+		if bytes.IndexByte(head, 0) >= 0 {
+			return nil, nil
+		}
+	}
+
+	layer := i.FileCatalog.Layer(*r.Reference)
+
+	var matches []ContentMatch
+	var offset int64
+	var lineNumber int64
+	lineStart := true
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// note: the returned slice is only valid until the next read, and is bounded by the reader buffer size
+		line, err := reader.ReadSlice('\n')
+		if len(line) > 0 {
+			if lineStart {
+				lineNumber++
+			}
+			for _, loc := range pattern.FindAllIndex(line, -1) {
+				matches = append(matches, ContentMatch{
+					Path:      r.RequestPath,
+					Reference: *r.Reference,
+					Layer:     layer,
+					Offset:    offset + int64(loc[0]),
+					Length:    loc[1] - loc[0],
+					Line:      lineNumber,
+					Text:      string(line[loc[0]:loc[1]]),
+				})
+			}
+			offset += int64(len(line))
+		}
+		// a full buffer means the line continues in the next segment
+		lineStart = !errors.Is(err, bufio.ErrBufferFull)
+		if errors.Is(err, io.EOF) {
+			return matches, nil
+		}
+		if err != nil && lineStart {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package image
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anchore/stereoscope/pkg/file"
+)
+
+func TestImage_SearchContents(t *testing.T) {
+	img, err := readTestImage(t, []v1.Layer{
+		tarLayer(t, map[string]string{
+			"etc/app/config.yaml": "version: 1.2.3\nkey: AKIA1234\n",
+			"etc/os-release":      "NAME=test\nVERSION=1.0.0\n",
+			"usr/bin/app":         "\x7fELF\x00\x00version 9.9.9",
+			"var/log/big.log":     "version 5.5.5\n" + string(make([]byte, 100)),
+		}),
+		tarLayer(t, map[string]string{
+			// replaces the file from the lower layer
+			"etc/os-release": "NAME=test\nVERSION=2.0.0\n",
+		}),
+	})
+	require.NoError(t, err)
+
+	type match struct {
+		path   file.Path
+		offset int64
+		line   int64
+		text   string
+		layer  uint
+	}
+
+	tests := []struct {
+		name    string
+		query   ContentQuery
+		want    []match
+		wantErr require.ErrorAssertionFunc
+	}{
+		{
+			name:  "regex across all text files",
+			query: ContentQuery{Pattern: `\d+\.\d+\.\d+`},
+			want: []match{
+				{path: "/etc/app/config.yaml", offset: 9, line: 1, text: "1.2.3", layer: 0},
+				{path: "/etc/os-release", offset: 18, line: 2, text: "2.0.0", layer: 1},
+			},
+		},
+		{
+			name:  "include binary files",
+			query: ContentQuery{Pattern: `\d+\.\d+\.\d+`, IncludeBinary: true},
+			want: []match{
+				{path: "/etc/app/config.yaml", offset: 9, line: 1, text: "1.2.3", layer: 0},
+				{path: "/etc/os-release", offset: 18, line: 2, text: "2.0.0", layer: 1},
+				{path: "/usr/bin/app", offset: 14, line: 1, text: "9.9.9", layer: 0},
+				{path: "/var/log/big.log", offset: 8, line: 1, text: "5.5.5", layer: 0},
+			},
+		},
+		{
+			name:  "max file size",
+			query: ContentQuery{Pattern: `\d+\.\d+\.\d+`, IncludeBinary: true, MaxFileSize: 30},
+			want: []match{
+				{path: "/etc/app/config.yaml", offset: 9, line: 1, text: "1.2.3", layer: 0},
+				{path: "/etc/os-release", offset: 18, line: 2, text: "2.0.0", layer: 1},
+				{path: "/usr/bin/app", offset: 14, line: 1, text: "9.9.9", layer: 0},
+			},
+		},
+		{
+			name:  "literal with glob",
+			query: ContentQuery{Literal: "AKIA", Globs: []string{"/etc/**/*.yaml"}},
+			want: []match{
+				{path: "/etc/app/config.yaml", offset: 20, line: 2, text: "AKIA", layer: 0},
+			},
+		},
+		{
+			name:  "glob excludes other files",
+			query: ContentQuery{Literal: "version", Globs: []string{"/usr/**"}, IncludeBinary: true},
+			want: []match{
+				{path: "/usr/bin/app", offset: 6, line: 1, text: "version", layer: 0},
+			},
+		},
+		{
+			name:  "MIME type filter",
+			query: ContentQuery{Literal: "NAME", MIMETypes: []string{"text/plain"}, Concurrency: 1},
+			want: []match{
+				{path: "/etc/os-release", offset: 0, line: 1, text: "NAME", layer: 1},
+			},
+		},
+		{
+			name:    "missing pattern",
+			query:   ContentQuery{},
+			wantErr: require.Error,
+		},
+		{
+			name:    "pattern and literal",
+			query:   ContentQuery{Pattern: "a", Literal: "a"},
+			wantErr: require.Error,
+		},
+		{
+			name:    "invalid pattern",
+			query:   ContentQuery{Pattern: "("},
+			wantErr: require.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr == nil {
+				tt.wantErr = require.NoError
+			}
+			matches, err := img.SearchContents(context.Background(), tt.query)
+			tt.wantErr(t, err)
+			if err != nil {
+				return
+			}
+
+			var got []match
+			for _, m := range matches {
+				require.NotNil(t, m.Layer)
+				assert.Equal(t, len(m.Text), m.Length)
+				got = append(got, match{
+					path:   m.Path,
+					offset: m.Offset,
+					line:   m.Line,
+					text:   m.Text,
+					layer:  m.Layer.Metadata.Index,
+				})
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestImage_SearchContents_Cancelled(t *testing.T) {
+	img, err := readTestImage(t, []v1.Layer{
+		tarLayer(t, map[string]string{"a.txt": "a"}),
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = img.SearchContents(ctx, ContentQuery{Literal: "a"})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestImage_SearchContents_LongLine(t *testing.T) {
+	// a single line spanning several segments, with a match in the last segment and on the following line
+	long := strings.Repeat("x", 2*maxContentLineSize+10) + "needle"
+	img, err := readTestImage(t, []v1.Layer{
+		tarLayer(t, map[string]string{"long.txt": long + "\nneedle\n"}),
+	})
+	require.NoError(t, err)
+
+	matches, err := img.SearchContents(context.Background(), ContentQuery{Literal: "needle"})
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+
+	assert.Equal(t, int64(len(long)-len("needle")), matches[0].Offset)
+	assert.Equal(t, int64(1), matches[0].Line)
+	assert.Equal(t, int64(len(long)+1), matches[1].Offset)
+	assert.Equal(t, int64(2), matches[1].Line)
+}
@@ -0,0 +1,106 @@
+package image
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// DigestMismatchError is returned when the image obtained for a digest-pinned reference has none of the requested
+// digest within its manifest digest or repo digests.
+type DigestMismatchError struct {
+	// Reference is the digest-pinned image reference requested by the user
+	Reference string
+	// Expected is the digest pinned within the reference
+	Expected string
+	// Actual contains all digests known for the image that was obtained (empty if none are known)
+	Actual []string
+}
+
+func (e *DigestMismatchError) Error() string {
+	if len(e.Actual) == 0 {
+		return fmt.Sprintf("%s: reference=%q expected=%s but no digest is known for the image", ErrDigestMismatch, e.Reference, e.Expected)
+	}
+	return fmt.Sprintf("%s: reference=%q expected=%s found=%s", ErrDigestMismatch, e.Reference, e.Expected, strings.Join(e.Actual, ","))
+}
+
+func (e *DigestMismatchError) Unwrap() error {
+	return ErrDigestMismatch
+}
+
+// PinnedDigest returns the digest that the given image reference is pinned to (e.g. "sha256:..." for
+// "alpine@sha256:..."). False is returned when the reference is not pinned to a digest.
+func PinnedDigest(reference string) (string, bool) {
+	if !strings.Contains(reference, "@") {
+		return "", false
+	}
+	ref, err := name.NewDigest(reference)
+	if err != nil {
+		return "", false
+	}
+	return ref.DigestStr(), true
+}
+
+// RequirePinnedDigest returns ErrReferenceNotPinned when the given image reference is not pinned to a digest.
+func RequirePinnedDigest(reference string) error {
+	if _, ok := PinnedDigest(reference); !ok {
+		return fmt.Errorf("%w: reference=%q", ErrReferenceNotPinned, reference)
+	}
+	return nil
+}
+
+// VerifyDigest confirms that the image metadata describes the digest the given reference is pinned to, comparing
+// against the manifest digest (as recorded and recomputed from the raw manifest) and the digests of all repo digests
+// (which hold the index digest for multi-platform images). References that are not pinned are not verified.
+func VerifyDigest(reference string, metadata Metadata) error {
+	expected, ok := PinnedDigest(reference)
+	if !ok {
+		return nil
+	}
+
+	actual := metadata.Digests()
+	if slices.Contains(actual, expected) {
+		return nil
+	}
+	return &DigestMismatchError{
+		Reference: reference,
+		Expected:  expected,
+		Actual:    actual,
+	}
+}
+
+// VerifyProvidedImage confirms that an image obtained by a provider has the digest the given reference is pinned to
+// (see VerifyDigest), cleaning up the image when it does not. Providers that only learn the image digest once the
+// image is read (daemon providers) call this before returning the image.
+func VerifyProvidedImage(reference string, img *Image) error {
+	if err := VerifyDigest(reference, img.Metadata); err != nil {
+		return errors.Join(err, img.Cleanup())
+	}
+	return nil
+}
+
+// Digests returns all unique digests known to identify the image: the manifest digest, the digest of the raw
+// manifest and the digest portion of each repo digest.
+func (m Metadata) Digests() []string {
+	var digests []string
+	add := func(digest string) {
+		if digest != "" && !slices.Contains(digests, digest) {
+			digests = append(digests, digest)
+		}
+	}
+
+	add(m.ManifestDigest)
+	if len(m.RawManifest) > 0 {
+		add(fmt.Sprintf("sha256:%x", sha256.Sum256(m.RawManifest)))
+	}
+	for _, repoDigest := range m.RepoDigests {
+		if _, digest, found := strings.Cut(repoDigest, "@"); found {
+			add(digest)
+		}
+	}
+	return digests
+}
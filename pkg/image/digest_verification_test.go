@@ -0,0 +1,136 @@
+package image
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anchore/stereoscope/pkg/file"
+)
+
+const (
+	indexDigest    = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	manifestDigest = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	otherDigest    = "sha256:3333333333333333333333333333333333333333333333333333333333333333"
+)
+
+func TestPinnedDigest(t *testing.T) {
+	tests := []struct {
+		reference string
+		want      string
+		wantOK    bool
+	}{
+		{reference: "alpine@" + indexDigest, want: indexDigest, wantOK: true},
+		{reference: "docker.io/library/alpine:3.20@" + indexDigest, want: indexDigest, wantOK: true},
+		{reference: "alpine:latest"},
+		{reference: "alpine"},
+		{reference: "/some/path/image.tar"},
+		{reference: "alpine@sha256:invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reference, func(t *testing.T) {
+			got, ok := PinnedDigest(tt.reference)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRequirePinnedDigest(t *testing.T) {
+	require.NoError(t, RequirePinnedDigest("alpine@"+indexDigest))
+	require.ErrorIs(t, RequirePinnedDigest("alpine:latest"), ErrReferenceNotPinned)
+}
+
+func TestVerifyDigest(t *testing.T) {
+	rawManifest := []byte(`{"schemaVersion":2}`)
+	rawManifestDigest := fmt.Sprintf("sha256:%x", sha256.Sum256(rawManifest))
+
+	tests := []struct {
+		name       string
+		reference  string
+		metadata   Metadata
+		wantErr    require.ErrorAssertionFunc
+		wantActual []string
+	}{
+		{
+			name:      "not pinned",
+			reference: "alpine:latest",
+			metadata:  Metadata{ManifestDigest: otherDigest},
+		},
+		{
+			name:      "manifest digest matches",
+			reference: "alpine@" + manifestDigest,
+			metadata:  Metadata{ManifestDigest: manifestDigest},
+		},
+		{
+			name:      "recomputed manifest digest matches",
+			reference: "alpine@" + rawManifestDigest,
+			metadata:  Metadata{ManifestDigest: otherDigest, RawManifest: rawManifest},
+		},
+		{
+			name:      "repo digest matches index digest",
+			reference: "alpine@" + indexDigest,
+			metadata: Metadata{
+				ManifestDigest: manifestDigest,
+				RepoDigests:    []string{"index.docker.io/library/alpine@" + indexDigest},
+			},
+		},
+		{
+			name:      "mismatch",
+			reference: "alpine@" + indexDigest,
+			metadata: Metadata{
+				ManifestDigest: manifestDigest,
+				RepoDigests:    []string{"index.docker.io/library/alpine@" + otherDigest},
+			},
+			wantErr:    require.Error,
+			wantActual: []string{manifestDigest, otherDigest},
+		},
+		{
+			name:      "no digests known",
+			reference: "alpine@" + indexDigest,
+			wantErr:   require.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr == nil {
+				tt.wantErr = require.NoError
+			}
+			err := VerifyDigest(tt.reference, tt.metadata)
+			tt.wantErr(t, err)
+			if err == nil {
+				return
+			}
+
+			require.ErrorIs(t, err, ErrDigestMismatch)
+			var mismatch *DigestMismatchError
+			require.ErrorAs(t, err, &mismatch)
+			assert.Equal(t, tt.reference, mismatch.Reference)
+			assert.Equal(t, indexDigest, mismatch.Expected)
+			assert.Equal(t, tt.wantActual, mismatch.Actual)
+		})
+	}
+}
+
+func TestVerifyProvidedImage(t *testing.T) {
+	tmpDirGen := file.NewTempDirGenerator("stereoscope-digest-test")
+	dir, err := tmpDirGen.NewDirectory()
+	require.NoError(t, err)
+
+	img := &Image{
+		Metadata:  Metadata{ManifestDigest: manifestDigest},
+		tmpDirGen: tmpDirGen,
+	}
+
+	require.NoError(t, VerifyProvidedImage("alpine@"+manifestDigest, img))
+	require.NoError(t, VerifyProvidedImage("alpine:latest", img))
+	assert.DirExists(t, dir)
+
+	// images that do not match are cleaned up
+	err = VerifyProvidedImage("alpine@"+otherDigest, img)
+	require.ErrorIs(t, err, ErrDigestMismatch)
+	assert.NoDirExists(t, dir)
+}
@@ -33,22 +33,60 @@ import (
 
 const Daemon image.Source = image.DockerDaemonSource
 
+// DaemonProviderOption configures optional behavior of a daemon provider.
+type DaemonProviderOption func(*daemonImageProvider)
+
+// WithOffline prevents the daemon from pulling images (which requires the daemon to contact a registry). Images that
+// are missing from the daemon (or do not match the requested platform) fail with image.ErrOffline instead.
+func WithOffline() DaemonProviderOption {
+	return func(p *daemonImageProvider) {
+		p.offline = true
+	}
+}
+
+// WithPullPolicy sets when the image is pulled into the daemon (image.PullIfMissing by default).
+func WithPullPolicy(policy image.PullPolicy) DaemonProviderOption {
+	return func(p *daemonImageProvider) {
+		p.pullPolicy = policy
+	}
+}
+
+// WithRemovePulledImage removes the image from the daemon once it has been saved, but only when the image was not
+// present in the daemon before the provider pulled it.
+func WithRemovePulledImage() DaemonProviderOption {
+	return func(p *daemonImageProvider) {
+		p.removePulledImage = true
+	}
+}
+
+// WithAdditionalMetadata applies the given metadata to the image before it is read (for example image.WithReadLimits),
+// overriding any provider defaults.
+func WithAdditionalMetadata(metadata ...image.AdditionalMetadata) DaemonProviderOption {
+	return func(p *daemonImageProvider) {
+		p.additionalMetadata = append(p.additionalMetadata, metadata...)
+	}
+}
+
 // NewDaemonProvider creates a new provider instance for a specific image that will later be cached to the given directory
-func NewDaemonProvider(tmpDirGen *file.TempDirGenerator, imageStr string, platform *image.Platform) image.Provider {
+func NewDaemonProvider(tmpDirGen *file.TempDirGenerator, imageStr string, platform *image.Platform, options ...DaemonProviderOption) image.Provider {
 	return NewAPIClientProvider(Daemon, tmpDirGen, imageStr, platform, func() (client.APIClient, error) {
 		return docker.GetClient()
-	})
+	}, options...)
 }
 
 // NewAPIClientProvider creates a new provider for the provided Docker client.APIClient
-func NewAPIClientProvider(name string, tmpDirGen *file.TempDirGenerator, imageStr string, platform *image.Platform, newClient apiClientCreator) image.Provider {
-	return &daemonImageProvider{
+func NewAPIClientProvider(name string, tmpDirGen *file.TempDirGenerator, imageStr string, platform *image.Platform, newClient apiClientCreator, options ...DaemonProviderOption) image.Provider {
+	p := &daemonImageProvider{
 		name:         name,
 		tmpDirGen:    tmpDirGen,
 		newAPIClient: newClient,
 		imageStr:     imageStr,
 		platform:     platform,
 	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
 }
 
 type apiClientCreator func() (client.APIClient, error)
@@ -60,6 +98,11 @@ type daemonImageProvider struct {
 	newAPIClient apiClientCreator
 	imageStr     string
 	platform     *image.Platform
+	offline      bool
+
+	pullPolicy         image.PullPolicy
+	removePulledImage  bool
+	additionalMetadata []image.AdditionalMetadata
 }
 
 func (p *daemonImageProvider) Name() string {
@@ -75,6 +118,7 @@ func (p *daemonImageProvider) ociPlatform() *ocispec.Platform {
 		OS:           p.platform.OS,
 		Architecture: p.platform.Architecture,
 		Variant:      p.platform.Variant,
+		OSVersion:    p.platform.OSVersion,
 	}
 }
 
@@ -157,6 +201,10 @@ func (p *daemonImageProvider) trackSaveProgress(ctx context.Context, apiClient c
 
 // pull a docker image
 func (p *daemonImageProvider) pull(ctx context.Context, client client.APIClient, imageRef string) error {
+	if p.offline {
+		return fmt.Errorf("%w: unable to pull %s image=%q", image.ErrOffline, p.name, imageRef)
+	}
+
 	log.Debugf("pulling %s image=%q", p.name, imageRef)
 
 	status := newPullStatus()
@@ -178,7 +226,7 @@ func (p *daemonImageProvider) pull(ctx context.Context, client client.APIClient,
 
 	resp, err := client.ImagePull(ctx, imageRef, options)
 	if err != nil {
-		return fmt.Errorf("pull failed: %w", err)
+		return fmt.Errorf("pull failed: %w", classifyDaemonError(err))
 	}
 	defer resp.Close()
 
@@ -211,7 +259,7 @@ func handlePullEvent(status emitter, event *pullEvent) error {
 				Err: errors.New(event.Error),
 			}
 		}
-		return errors.New(event.Error)
+		return classifyPullEventError(event.Error)
 	}
 
 	// check for the last two events indicating the pull is complete
@@ -224,6 +272,40 @@ func handlePullEvent(status emitter, event *pullEvent) error {
 	return nil
 }
 
+// classifyDaemonError wraps the given daemon API error with the matching stereoscope error (e.g. image.ErrImageNotFound).
+// Errors that cannot be classified are returned as-is.
+func classifyDaemonError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case client.IsErrConnectionFailed(err), errdefs.IsUnavailable(err):
+		return fmt.Errorf("%w: %w", image.ErrDaemonUnavailable, err)
+	case errdefs.IsNotFound(err):
+		return fmt.Errorf("%w: %w", image.ErrImageNotFound, err)
+	case errdefs.IsUnauthorized(err), errdefs.IsPermissionDenied(err):
+		return fmt.Errorf("%w: %w", image.ErrUnauthorized, err)
+	case errdefs.IsResourceExhausted(err):
+		return fmt.Errorf("%w: %w", image.ErrRateLimited, err)
+	}
+	return err
+}
+
+// classifyPullEventError creates an error for the error message from a pull event stream, wrapped with the matching
+// stereoscope error when the (unstructured) message can be classified.
+func classifyPullEventError(msg string) error {
+	err := errors.New(msg)
+	lower := strings.ToLower(msg)
+	switch {
+	case strings.Contains(lower, "toomanyrequests"), strings.Contains(lower, "rate limit"):
+		return fmt.Errorf("%w: %w", image.ErrRateLimited, err)
+	case strings.Contains(lower, "unauthorized"), strings.Contains(lower, "denied"):
+		return fmt.Errorf("%w: %w", image.ErrUnauthorized, err)
+	case strings.Contains(lower, "manifest unknown"), strings.Contains(lower, "not found"):
+		return fmt.Errorf("%w: %w", image.ErrImageNotFound, err)
+	}
+	return err
+}
+
 func (p *daemonImageProvider) pullOptions(imageRef string) (client.ImagePullOptions, error) {
 	options := client.ImagePullOptions{}
 	if p.platform != nil {
@@ -306,7 +388,7 @@ func (p *daemonImageProvider) Provide(ctx context.Context) (*image.Image, error)
 	startTime := time.Now()
 	apiClient, err := p.newAPIClient()
 	if err != nil {
-		return nil, fmt.Errorf("%s not available: %w", p.name, err)
+		return nil, fmt.Errorf("%s not available: %w: %w", p.name, image.ErrDaemonUnavailable, err)
 	}
 
 	defer func() {
@@ -319,16 +401,23 @@ func (p *daemonImageProvider) Provide(ctx context.Context) (*image.Image, error)
 	defer cancel()
 
 	pong, err := apiClient.Ping(c2, client.PingOptions{})
-	if err != nil || pong.APIVersion == "" {
-		return nil, fmt.Errorf("unable to get %s API response: %w", p.name, err)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get %s API response: %w: %w", p.name, image.ErrDaemonUnavailable, err)
+	}
+	if pong.APIVersion == "" {
+		return nil, fmt.Errorf("unable to get %s API response: %w: no API version reported", p.name, image.ErrDaemonUnavailable)
 	}
 
-	log.WithFields("image", p.imageStr).Info("docker pulling image")
-	imageRef, err := p.pullImageIfMissing(ctx, apiClient)
+	log.WithFields("image", p.imageStr, "policy", p.pullPolicy).Info("docker pulling image")
+	imageRef, pulled, err := p.pullImageByPolicy(ctx, apiClient)
 	if err != nil {
 		return nil, err
 	}
 
+	if pulled && p.removePulledImage {
+		defer p.removeImage(ctx, apiClient, imageRef)
+	}
+
 	log.WithFields("image", imageRef, "time", time.Since(startTime)).Info("docker pulled image")
 	startTime = time.Now()
 
@@ -355,8 +444,16 @@ func (p *daemonImageProvider) Provide(ctx context.Context) (*image.Image, error)
 	log.WithFields("image", imageRef, "time", time.Since(startTime), "path", tarFileName).Info("docker saved image")
 
 	// use the existing tarball provider to process what was pulled from the docker daemon
-	return NewArchiveProvider(p.tmpDirGen, tarFileName, withInspectMetadata(inspectResult)...).
+	img, err := NewArchiveProvider(p.tmpDirGen, tarFileName, append(withInspectMetadata(inspectResult), p.additionalMetadata...)...).
 		Provide(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := image.VerifyProvidedImage(p.imageStr, img); err != nil {
+		return nil, err
+	}
+	return img, nil
 }
 
 func (p *daemonImageProvider) saveImage(ctx context.Context, apiClient client.APIClient, imageRef string) (string, error) {
@@ -421,10 +518,12 @@ func (p *daemonImageProvider) saveImage(ctx context.Context, apiClient client.AP
 	return tempTarFile.Name(), nil
 }
 
-func (p *daemonImageProvider) pullImageIfMissing(ctx context.Context, apiClient client.APIClient) (imageRef string, err error) {
+// pullImageByPolicy pulls the image into the daemon as needed according to the pull policy, returning the image
+// reference to use and whether the image was absent from the daemon before pulling.
+func (p *daemonImageProvider) pullImageByPolicy(ctx context.Context, apiClient client.APIClient) (imageRef string, pulled bool, err error) {
 	imageRef, originalImageRef, err := image.ParseReference(p.imageStr)
 	if err != nil {
-		return "", err
+		return "", false, err
 	}
 
 	// check if the image exists locally (use platform-aware inspect so Docker 29+ resolves
@@ -436,24 +535,53 @@ func (p *daemonImageProvider) pullImageIfMissing(ctx context.Context, apiClient
 			imageRef = strings.TrimSuffix(imageRef, ":latest")
 		}
 	}
-	if err != nil {
-		if errdefs.IsNotFound(err) {
-			if err = p.pull(ctx, apiClient, imageRef); err != nil {
-				return imageRef, err
-			}
-		} else {
-			return imageRef, fmt.Errorf("unable to inspect existing image: %w", err)
+
+	missing := false
+	switch {
+	case err != nil && !errdefs.IsNotFound(err):
+		return imageRef, false, fmt.Errorf("unable to inspect existing image: %w", classifyDaemonError(err))
+	case err != nil:
+		missing = true
+	}
+
+	switch p.pullPolicy {
+	case image.PullNever:
+		if missing {
+			return imageRef, false, fmt.Errorf("%w: image=%q is not present in %s and the pull policy is %q", image.ErrImageNotFound, imageRef, p.name, image.PullNever)
 		}
-	} else {
-		// looks like the image exists, but if the platform doesn't match what the user specified, we may need to
-		// pull the image again with the correct platform specifier, which will override the local tag.
-		if err = p.validatePlatform(inspectResult); err != nil {
-			if err = p.pull(ctx, apiClient, imageRef); err != nil {
-				return imageRef, err
-			}
+		// the platform is validated by the caller
+		return imageRef, false, nil
+	case image.PullAlways:
+		if err := p.pull(ctx, apiClient, imageRef); err != nil {
+			return imageRef, false, err
+		}
+		return imageRef, missing, nil
+	}
+
+	if missing {
+		if err := p.pull(ctx, apiClient, imageRef); err != nil {
+			return imageRef, false, err
 		}
+		return imageRef, true, nil
 	}
-	return imageRef, nil
+
+	// looks like the image exists, but if the platform doesn't match what the user specified, we may need to
+	// pull the image again with the correct platform specifier, which will override the local tag.
+	if err := p.validatePlatform(inspectResult); err != nil {
+		if err := p.pull(ctx, apiClient, imageRef); err != nil {
+			return imageRef, false, err
+		}
+	}
+	return imageRef, false, nil
+}
+
+// removeImage removes an image that was pulled by the provider from the daemon (failures are only logged).
+func (p *daemonImageProvider) removeImage(ctx context.Context, apiClient client.APIClient, imageRef string) {
+	if _, err := apiClient.ImageRemove(ctx, imageRef, client.ImageRemoveOptions{}); err != nil {
+		log.WithFields("image", imageRef, "error", err).Warnf("unable to remove pulled image from %s", p.name)
+		return
+	}
+	log.WithFields("image", imageRef).Debugf("removed pulled image from %s", p.name)
 }
 
 func (p *daemonImageProvider) validatePlatform(i client.ImageInspectResult) error {
@@ -463,11 +591,17 @@ func (p *daemonImageProvider) validatePlatform(i client.ImageInspectResult) erro
 	}
 
 	if i.Os != p.platform.OS {
-		return fmt.Errorf("image has unexpected OS %q, which differs from the user specified PS %q", i.Os, p.platform.OS)
+		return &image.ErrPlatformMismatch{
+			ExpectedPlatform: p.platform.String(),
+			Err:              fmt.Errorf("image has unexpected OS %q, which differs from the user specified PS %q", i.Os, p.platform.OS),
+		}
 	}
 
 	if i.Architecture != p.platform.Architecture {
-		return fmt.Errorf("image has unexpected architecture %q, which differs from the user specified architecture %q", i.Architecture, p.platform.Architecture)
+		return &image.ErrPlatformMismatch{
+			ExpectedPlatform: p.platform.String(),
+			Err:              fmt.Errorf("image has unexpected architecture %q, which differs from the user specified architecture %q", i.Architecture, p.platform.Architecture),
+		}
 	}
 
 	// note: there is no architecture variant captured in inspect responses
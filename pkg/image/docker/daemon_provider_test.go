@@ -1,11 +1,17 @@
 package docker
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"testing"
 
+	"github.com/containerd/errdefs"
 	configTypes "github.com/docker/cli/cli/config/types"
+	"github.com/moby/moby/client"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -115,6 +121,26 @@ func TestHandlePullEventWithMockEmitter(t *testing.T) {
 				require.NotErrorAs(t, err, &pErr)
 			},
 		},
+		{
+			name: "rate limit error in event",
+			event: &pullEvent{
+				Error: "toomanyrequests: You have reached your pull rate limit.",
+			},
+			expectOnEvent: false,
+			assertFunc: func(t require.TestingT, err error, args ...any) {
+				require.ErrorIs(t, err, image.ErrRateLimited)
+			},
+		},
+		{
+			name: "not found error in event",
+			event: &pullEvent{
+				Error: "manifest for anchore/test_images:missing not found: manifest unknown: manifest unknown",
+			},
+			expectOnEvent: false,
+			assertFunc: func(t require.TestingT, err error, args ...any) {
+				require.ErrorIs(t, err, image.ErrImageNotFound)
+			},
+		},
 		{
 			name: "platform error in event",
 			event: &pullEvent{
@@ -168,3 +194,130 @@ func TestHandlePullEventWithMockEmitter(t *testing.T) {
 		})
 	}
 }
+
+func Test_classifyDaemonError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{
+			name:    "not found",
+			err:     fmt.Errorf("wrapped: %w", errdefs.ErrNotFound),
+			wantErr: image.ErrImageNotFound,
+		},
+		{
+			name:    "unauthorized",
+			err:     errdefs.ErrUnauthenticated,
+			wantErr: image.ErrUnauthorized,
+		},
+		{
+			name:    "unavailable",
+			err:     errdefs.ErrUnavailable,
+			wantErr: image.ErrDaemonUnavailable,
+		},
+		{
+			name: "unclassified",
+			err:  errors.New("boom"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyDaemonError(tt.err)
+			require.ErrorIs(t, got, tt.err)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_daemonImageProvider_pull_Offline(t *testing.T) {
+	p := NewAPIClientProvider(Daemon, nil, "alpine:latest", nil, nil, WithOffline()).(*daemonImageProvider)
+
+	// note: the client is never used when offline
+	err := p.pull(context.Background(), nil, "docker.io/library/alpine:latest")
+	require.ErrorIs(t, err, image.ErrOffline)
+}
+
+// fakeAPIClient is a minimal daemon API client tracking pulls and removals of a single image
+type fakeAPIClient struct {
+	client.APIClient
+	present bool
+	pulls   int
+	removed []string
+}
+
+func (c *fakeAPIClient) ImageInspect(context.Context, string, ...client.ImageInspectOption) (client.ImageInspectResult, error) {
+	if !c.present {
+		return client.ImageInspectResult{}, errdefs.ErrNotFound
+	}
+	return client.ImageInspectResult{}, nil
+}
+
+func (c *fakeAPIClient) ImagePull(context.Context, string, client.ImagePullOptions) (client.ImagePullResponse, error) {
+	c.pulls++
+	c.present = true
+	return fakePullResponse{}, nil
+}
+
+func (c *fakeAPIClient) ImageRemove(_ context.Context, imageRef string, _ client.ImageRemoveOptions) (client.ImageRemoveResult, error) {
+	c.removed = append(c.removed, imageRef)
+	c.present = false
+	return client.ImageRemoveResult{}, nil
+}
+
+type fakePullResponse struct {
+	client.ImagePullResponse
+}
+
+func (fakePullResponse) Read([]byte) (int, error) { return 0, io.EOF }
+
+func (fakePullResponse) Close() error { return nil }
+
+func Test_daemonImageProvider_pullImageByPolicy(t *testing.T) {
+	// avoid picking up any docker config (and credential helpers) from the host
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	tests := []struct {
+		name       string
+		policy     image.PullPolicy
+		present    bool
+		wantPulls  int
+		wantPulled bool
+		wantErr    error
+	}{
+		{name: "default policy pulls missing image", policy: "", present: false, wantPulls: 1, wantPulled: true},
+		{name: "default policy uses present image", policy: "", present: true, wantPulls: 0},
+		{name: "if-missing pulls missing image", policy: image.PullIfMissing, present: false, wantPulls: 1, wantPulled: true},
+		{name: "if-missing uses present image", policy: image.PullIfMissing, present: true, wantPulls: 0},
+		{name: "always pulls present image", policy: image.PullAlways, present: true, wantPulls: 1, wantPulled: false},
+		{name: "always pulls missing image", policy: image.PullAlways, present: false, wantPulls: 1, wantPulled: true},
+		{name: "never uses present image", policy: image.PullNever, present: true, wantPulls: 0},
+		{name: "never fails on missing image", policy: image.PullNever, present: false, wantPulls: 0, wantErr: image.ErrImageNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiClient := &fakeAPIClient{present: tt.present}
+			p := NewAPIClientProvider(Daemon, nil, "alpine:latest", nil, nil, WithPullPolicy(tt.policy)).(*daemonImageProvider)
+
+			_, pulled, err := p.pullImageByPolicy(context.Background(), apiClient)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantPulls, apiClient.pulls)
+			assert.Equal(t, tt.wantPulled, pulled)
+		})
+	}
+}
+
+func Test_daemonImageProvider_removeImage(t *testing.T) {
+	apiClient := &fakeAPIClient{present: true}
+	p := NewAPIClientProvider(Daemon, nil, "alpine:latest", nil, nil, WithRemovePulledImage()).(*daemonImageProvider)
+	require.True(t, p.removePulledImage)
+
+	p.removeImage(context.Background(), apiClient, "alpine:latest")
+	assert.Equal(t, []string{"alpine:latest"}, apiClient.removed)
+}
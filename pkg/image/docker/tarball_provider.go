@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"time"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -26,7 +27,7 @@ func NewArchiveProvider(tmpDirGen *file.TempDirGenerator, path string, additiona
 	}
 }
 
-var ErrMultipleManifests = fmt.Errorf("cannot process multiple docker manifests")
+var ErrMultipleManifests = fmt.Errorf("cannot process multiple docker manifests: %w", image.ErrMultipleImages)
 
 // tarballImageProvider is a image.Provider for a docker image (V2) for an existing tar on disk (the output from a "docker image save ..." command).
 type tarballImageProvider struct {
@@ -40,7 +41,11 @@ func (p *tarballImageProvider) Name() string {
 }
 
 // Provide an image object that represents the docker image tar at the configured location on disk.
-func (p *tarballImageProvider) Provide(_ context.Context) (*image.Image, error) {
+func (p *tarballImageProvider) Provide(ctx context.Context) (*image.Image, error) {
+	if fi, err := os.Stat(p.path); err != nil || fi.IsDir() {
+		return nil, fmt.Errorf("%w: %q is not a file", image.ErrProviderNotApplicable, p.path)
+	}
+
 	startTime := time.Now()
 
 	img, err := tarball.ImageFromPath(p.path, nil)
@@ -58,7 +63,10 @@ func (p *tarballImageProvider) Provide(_ context.Context) (*image.Image, error)
 	var rawOCIManifest []byte
 	var rawConfig []byte
 	var ociManifest *v1.Manifest
-	var metadata []image.AdditionalMetadata
+	metadata := []image.AdditionalMetadata{
+		// note: the layer digests are computed from the archive content, so only the diff IDs can be verified
+		image.WithLayerVerification(image.LayerVerification{DiffIDs: true}),
+	}
 
 	theManifest, err := extractManifest(p.path)
 	if err != nil {
@@ -98,7 +106,7 @@ func (p *tarballImageProvider) Provide(_ context.Context) (*image.Image, error)
 	}
 
 	out := image.New(img, p.tmpDirGen, contentTempDir, metadata...)
-	err = out.Read()
+	err = out.ReadWithContext(ctx)
 	if err != nil {
 		cleanErr := out.Cleanup()
 		return nil, errors.Join(err, cleanErr)
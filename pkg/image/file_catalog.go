@@ -15,6 +15,15 @@ type FileCatalogReader interface {
 	filetree.IndexReader
 }
 
+var _ SeekableFileCatalogReader = (*FileCatalog)(nil)
+
+// SeekableFileCatalogReader is a FileCatalogReader that can also provide random-access readers for file contents.
+type SeekableFileCatalogReader interface {
+	FileCatalogReader
+	OpenSeekable(file.Reference) (file.SeekableReader, error)
+	OpenReaderAt(file.Reference) (file.ReaderAtCloser, int64, error)
+}
+
 // FileCatalog represents all file metadata and source tracing for all files contained within the image layer
 // blobs (i.e. everything except for the image index/manifest/metadata files).
 type FileCatalog struct {
@@ -22,6 +31,26 @@ type FileCatalog struct {
 	filetree.Index
 	layerByID  map[file.ID]*Layer
 	openerByID map[file.ID]file.Opener
+	spool      *spoolDir
+}
+
+// spoolDir lazily creates the directory that file contents are copied to when random access is needed (see
+// FileCatalog.OpenSeekable). The directory is removed when the owning TempDirGenerator is cleaned up.
+type spoolDir struct {
+	once      sync.Once
+	tmpDirGen *file.TempDirGenerator
+	path      string
+	err       error
+}
+
+func (s *spoolDir) get() (string, error) {
+	if s == nil || s.tmpDirGen == nil {
+		return "", nil
+	}
+	s.once.Do(func() {
+		s.path, s.err = s.tmpDirGen.NewDirectory("spool")
+	})
+	return s.path, s.err
 }
 
 // NewFileCatalog returns an empty FileCatalog.
@@ -34,6 +63,14 @@ func NewFileCatalog() *FileCatalog {
 	}
 }
 
+// SpoolTo copies file contents that need random access (but are not backed by a random-access source) to a
+// directory created by the given generator, so that the copies are removed when the generator is cleaned up.
+func (c *FileCatalog) SpoolTo(tmpDirGen *file.TempDirGenerator) {
+	c.Lock()
+	defer c.Unlock()
+	c.spool = &spoolDir{tmpDirGen: tmpDirGen}
+}
+
 // Add creates a new FileCatalogEntry for the given file reference and metadata, cataloged by the ID of the
 // file reference (overwriting any existing entries without warning).
 func (c *FileCatalog) Add(f file.Reference, m file.Metadata, l *Layer, opener file.Opener) {
@@ -60,6 +97,26 @@ func (c *FileCatalog) addImageReferences(id file.ID, l *Layer, opener file.Opene
 	}
 }
 
+// GetByBasenameFold fetches all entries for files with a basename matching one of the given basenames, ignoring case
+// (see filetree.BasenameFoldIndexReader).
+func (c *FileCatalog) GetByBasenameFold(basenames ...string) ([]filetree.IndexEntry, error) {
+	idx, ok := c.Index.(filetree.BasenameFoldIndexReader)
+	if !ok {
+		return nil, fmt.Errorf("file index does not support case-insensitive lookups by basename")
+	}
+	return idx.GetByBasenameFold(basenames...)
+}
+
+// GetByXattr fetches all entries for files that have at least one of the given extended attributes set (see
+// filetree.XattrIndexReader).
+func (c *FileCatalog) GetByXattr(names ...string) ([]filetree.IndexEntry, error) {
+	idx, ok := c.Index.(filetree.XattrIndexReader)
+	if !ok {
+		return nil, fmt.Errorf("file index does not support lookups by xattr")
+	}
+	return idx.GetByXattr(names...)
+}
+
 func (c *FileCatalog) Layer(f file.Reference) *Layer {
 	c.RLock()
 	defer c.RUnlock()
@@ -84,3 +141,49 @@ func (c *FileCatalog) Open(f file.Reference) (io.ReadCloser, error) {
 
 	return opener()
 }
+
+// OpenSeekable returns a random-access reader for the contents of the given file reference. Tar-backed file contents
+// are read directly from the (uncompressed) layer tar, while other file contents (e.g. from squashfs-backed layers)
+// are first copied to a temporary file (see SpoolTo), which is removed when the reader is closed.
+func (c *FileCatalog) OpenSeekable(f file.Reference) (file.SeekableReader, error) {
+	c.RLock()
+	spool := c.spool
+	c.RUnlock()
+
+	tempDir, err := spool.get()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create spool directory: %w", err)
+	}
+
+	reader, err := c.Open(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var size int64 = -1
+	if entry, err := c.Get(f); err == nil && entry.FileInfo != nil {
+		size = entry.Size()
+	}
+
+	return file.NewSeekableReader(reader, size, tempDir)
+}
+
+// OpenReaderAt returns an io.ReaderAt for the contents of the given file reference along with the size of the
+// contents (as needed by archive/zip and debug/elf).
+func (c *FileCatalog) OpenReaderAt(f file.Reference) (file.ReaderAtCloser, int64, error) {
+	reader, err := c.OpenSeekable(f)
+	if err != nil {
+		return nil, 0, err
+	}
+	return reader, reader.Size(), nil
+}
+
+// openSeekable returns a random-access reader for the contents of the given file reference, provided the catalog
+// supports random access (see SeekableFileCatalogReader).
+func openSeekable(catalog FileCatalogReader, f file.Reference) (file.SeekableReader, error) {
+	c, ok := catalog.(SeekableFileCatalogReader)
+	if !ok {
+		return nil, fmt.Errorf("file catalog does not support random access reads")
+	}
+	return c.OpenSeekable(f)
+}
@@ -154,6 +154,35 @@ func TestFileCatalog_Open(t *testing.T) {
 	}
 }
 
+func TestFileCatalog_OpenSeekable_SpoolTo(t *testing.T) {
+	ref := file.NewFileReference("/a.txt")
+	opener := func() (io.ReadCloser, error) {
+		// a stream-only reader, which must be spooled for random access
+		return io.NopCloser(strings.NewReader("contents")), nil
+	}
+
+	tmpDirGen := file.NewTempDirGenerator("stereoscope-spool-test")
+	catalog := NewFileCatalog()
+	catalog.SpoolTo(tmpDirGen)
+	catalog.Add(*ref, file.Metadata{Path: "/a.txt"}, nil, opener)
+
+	reader, err := catalog.OpenSeekable(*ref)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		// note: the spooled file is already removed by the generator cleanup, so only the handle is released here
+		_ = reader.Close()
+	})
+	assert.Equal(t, int64(len("contents")), reader.Size())
+
+	spooled, ok := reader.(interface{ Name() string })
+	require.True(t, ok)
+	assert.True(t, fileExists(t, spooled.Name()))
+
+	// spooled contents are removed on cleanup, even when the reader is never closed
+	require.NoError(t, tmpDirGen.Cleanup())
+	assert.False(t, fileExists(t, spooled.Name()))
+}
+
 func Test_fileExtensions(t *testing.T) {
 	tests := []struct {
 		name string
@@ -0,0 +1,375 @@
+package image
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/anchore/stereoscope/pkg/file"
+	"github.com/anchore/stereoscope/pkg/filetree"
+)
+
+var _ fs.ReadDirFS = (*TreeFS)(nil)
+var _ fs.StatFS = (*TreeFS)(nil)
+var _ fs.ReadLinkFS = (*TreeFS)(nil)
+var _ fs.ReadDirFile = (*treeFSFile)(nil)
+var _ io.Seeker = (*treeFSFile)(nil)
+
+var errIsDirectory = errors.New("is a directory")
+
+// TreeFS is a read-only fs.FS over a file tree, where file contents are fetched from a file catalog. This allows for
+// standard library tooling (e.g. fs.WalkDir, fs.Glob, template.ParseFS, and http.FS) to be used against an image
+// squash or a single layer.
+//
+// Names follow the fs.FS conventions: they are unrooted and slash-separated (e.g. "etc/os-release"), with "." being
+// the root of the tree. Symlinks are followed by Open and Stat (relative to the tree), and hardlinks are presented
+// as the file they link to.
+type TreeFS struct {
+	tree    filetree.Reader
+	catalog FileCatalogReader
+}
+
+// NewTreeFS creates a fs.FS for the given tree, fetching file metadata and contents from the given catalog.
+func NewTreeFS(tree filetree.Reader, catalog FileCatalogReader) *TreeFS {
+	return &TreeFS{
+		tree:    tree,
+		catalog: catalog,
+	}
+}
+
+// SquashedFS returns a fs.FS over the image squash tree.
+func (i *Image) SquashedFS() *TreeFS {
+	return NewTreeFS(i.SquashedTree(), i.FileCatalog)
+}
+
+// FS returns a fs.FS over the layer "diff tree" (only the files provided by this layer).
+func (l *Layer) FS() *TreeFS {
+	return NewTreeFS(l.Tree, l.fileCatalog)
+}
+
+// SquashedFS returns a fs.FS over the layer squash tree (the files from this layer and all lower layers).
+func (l *Layer) SquashedFS() *TreeFS {
+	return NewTreeFS(l.SquashedTree, l.fileCatalog)
+}
+
+// Open opens the named file. Regular file contents are not fetched from the catalog until the first read.
+func (f *TreeFS) Open(name string) (fs.File, error) {
+	ref, info, err := f.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return &treeFSFile{
+		fsys: f,
+		name: name,
+		ref:  ref,
+		info: info,
+	}, nil
+}
+
+// Stat returns a FileInfo describing the named file, following symlinks.
+func (f *TreeFS) Stat(name string) (fs.FileInfo, error) {
+	_, info, err := f.resolve("stat", name, true)
+	return info, err
+}
+
+// Lstat returns a FileInfo describing the named file. If the file is a symlink, the returned FileInfo describes the
+// symlink.
+func (f *TreeFS) Lstat(name string) (fs.FileInfo, error) {
+	_, info, err := f.resolve("lstat", name, false)
+	return info, err
+}
+
+// ReadLink returns the destination of the named symlink.
+func (f *TreeFS) ReadLink(name string) (string, error) {
+	ref, info, err := f.resolve("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	entry, err := f.catalog.Get(*ref)
+	if err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return entry.LinkDestination, nil
+}
+
+// ReadDir reads the named directory, returning all its entries sorted by filename. Entries describe the files within
+// the directory without following symlinks.
+func (f *TreeFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	_, info, err := f.resolve("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	children, err := f.tree.ListPaths(treePath(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, child := range children {
+		childInfo, err := f.Lstat(path.Join(name, child.Basename()))
+		if err != nil {
+			// the child may be a dead link or otherwise unresolvable from this tree
+			continue
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(childInfo))
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return entries, nil
+}
+
+// resolve finds the file reference and file info for the given fs.FS name, optionally following basename symlinks.
+// Hardlinks are always followed.
+func (f *TreeFS) resolve(op, name string, followLinks bool) (*file.Reference, fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	p := treePath(name)
+	var options []filetree.LinkResolutionOption
+	if followLinks {
+		options = append(options, filetree.FollowBasenameLinks)
+	}
+
+	exists, resolution, err := f.tree.File(p, options...)
+	if err != nil {
+		return nil, nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if !exists || resolution == nil {
+		return nil, nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if !resolution.HasReference() {
+		// directories implied by the paths of other entries (and the root) have no reference or metadata of their own
+		return nil, newTreeFSFileInfo(path.Base(name), file.Metadata{Type: file.TypeDirectory}), nil
+	}
+
+	entry, err := f.catalog.Get(*resolution.Reference)
+	if err != nil {
+		return nil, nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+
+	if !followLinks && entry.Type == file.TypeHardLink {
+		return f.resolve(op, name, true)
+	}
+
+	return resolution.Reference, newTreeFSFileInfo(path.Base(name), entry.Metadata), nil
+}
+
+// treePath converts an fs.FS name into an absolute path within a file tree.
+func treePath(name string) file.Path {
+	if name == "." {
+		return "/"
+	}
+	return file.Path("/" + name)
+}
+
+// treeFSFileInfo describes a file within a TreeFS, named by the path it was requested at (which may differ from the
+// real path when links were followed).
+type treeFSFileInfo struct {
+	name     string
+	metadata file.Metadata
+}
+
+func newTreeFSFileInfo(name string, metadata file.Metadata) *treeFSFileInfo {
+	return &treeFSFileInfo{
+		name:     name,
+		metadata: metadata,
+	}
+}
+
+func (i *treeFSFileInfo) Name() string {
+	return i.name
+}
+
+func (i *treeFSFileInfo) Size() int64 {
+	if i.metadata.FileInfo == nil || i.metadata.Type != file.TypeRegular {
+		return 0
+	}
+	return i.metadata.Size()
+}
+
+// Mode returns the permission bits of the file along with the mode type bits derived from the file type.
+func (i *treeFSFileInfo) Mode() fs.FileMode {
+	var perm fs.FileMode = 0o755
+	if i.metadata.FileInfo != nil {
+		perm = i.metadata.Mode() & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+	}
+	switch i.metadata.Type {
+	case file.TypeDirectory:
+		return perm | fs.ModeDir
+	case file.TypeSymLink:
+		return perm | fs.ModeSymlink
+	case file.TypeCharacterDevice:
+		return perm | fs.ModeDevice | fs.ModeCharDevice
+	case file.TypeBlockDevice:
+		return perm | fs.ModeDevice
+	case file.TypeFIFO:
+		return perm | fs.ModeNamedPipe
+	case file.TypeSocket:
+		return perm | fs.ModeSocket
+	case file.TypeIrregular:
+		return perm | fs.ModeIrregular
+	default:
+		return perm
+	}
+}
+
+func (i *treeFSFileInfo) ModTime() time.Time {
+	if i.metadata.FileInfo == nil {
+		return time.Time{}
+	}
+	return i.metadata.ModTime()
+}
+
+func (i *treeFSFileInfo) IsDir() bool {
+	return i.metadata.Type == file.TypeDirectory
+}
+
+// Sys returns the file.Metadata describing the file.
+func (i *treeFSFileInfo) Sys() any {
+	return i.metadata
+}
+
+// treeFSFile is an open file within a TreeFS. Contents are read lazily from the file catalog; seeking is supported by
+// discarding content (or re-opening the file when seeking backwards).
+type treeFSFile struct {
+	fsys   *TreeFS
+	name   string
+	ref    *file.Reference
+	info   fs.FileInfo
+	closed bool
+
+	// regular file state
+	reader       io.ReadCloser
+	readerOffset int64
+	offset       int64
+
+	// directory state
+	entries       []fs.DirEntry
+	entriesLoaded bool
+}
+
+func (f *treeFSFile) Stat() (fs.FileInfo, error) {
+	if f.closed {
+		return nil, &fs.PathError{Op: "stat", Path: f.name, Err: fs.ErrClosed}
+	}
+	return f.info, nil
+}
+
+func (f *treeFSFile) Read(b []byte) (int, error) {
+	if f.closed {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrClosed}
+	}
+	if f.info.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: errIsDirectory}
+	}
+	if !f.info.Mode().IsRegular() {
+		// devices, pipes, and sockets have no contents within an image
+		return 0, io.EOF
+	}
+	if err := f.seekReader(); err != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: err}
+	}
+	n, err := f.reader.Read(b)
+	f.offset += int64(n)
+	f.readerOffset += int64(n)
+	return n, err
+}
+
+func (f *treeFSFile) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrClosed}
+	}
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = f.offset + offset
+	case io.SeekEnd:
+		abs = f.info.Size() + offset
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if abs < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	f.offset = abs
+	return abs, nil
+}
+
+// seekReader ensures the underlying content reader is open and positioned at the current file offset.
+func (f *treeFSFile) seekReader() error {
+	if f.reader != nil && f.readerOffset > f.offset {
+		if err := f.reader.Close(); err != nil {
+			return err
+		}
+		f.reader = nil
+	}
+	if f.reader == nil {
+		reader, err := f.fsys.catalog.Open(*f.ref)
+		if err != nil {
+			return err
+		}
+		f.reader = reader
+		f.readerOffset = 0
+	}
+	if f.readerOffset < f.offset {
+		n, err := io.CopyN(io.Discard, f.reader, f.offset-f.readerOffset)
+		f.readerOffset += n
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *treeFSFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if f.closed {
+		return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: fs.ErrClosed}
+	}
+	if !f.entriesLoaded {
+		entries, err := f.fsys.ReadDir(f.name)
+		if err != nil {
+			return nil, err
+		}
+		f.entries = entries
+		f.entriesLoaded = true
+	}
+
+	if n <= 0 {
+		entries := f.entries
+		f.entries = nil
+		return entries, nil
+	}
+	if len(f.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(f.entries))
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+	return entries, nil
+}
+
+func (f *treeFSFile) Close() error {
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+	if f.reader != nil {
+		return f.reader.Close()
+	}
+	return nil
+}
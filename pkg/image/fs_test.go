@@ -0,0 +1,204 @@
+package image
+
+import (
+	"archive/tar"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fsTestImage(t *testing.T) *Image {
+	t.Helper()
+	img, err := readTestImage(t, []v1.Layer{
+		tarLayerFromHeaders(t, []tar.Header{
+			{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "etc/os-release", Typeflag: tar.TypeReg, Mode: 0644},
+			{Name: "etc/passwd", Typeflag: tar.TypeReg, Mode: 0600},
+			{Name: "usr/bin/app", Typeflag: tar.TypeReg, Mode: 0755},
+			{Name: "usr/bin/app-hardlink", Typeflag: tar.TypeLink, Linkname: "usr/bin/app"},
+		}, map[string]string{
+			"etc/os-release": "NAME=test\nVERSION=1.0.0\n",
+			"etc/passwd":     "root:x:0:0:root:/root:/bin/sh\n",
+			"usr/bin/app":    "#!/bin/sh\necho app\n",
+		}),
+		tarLayerFromHeaders(t, []tar.Header{
+			{Name: "etc/os-release", Typeflag: tar.TypeReg, Mode: 0644},
+			{Name: "usr/bin/app-link", Typeflag: tar.TypeSymlink, Linkname: "app"},
+			{Name: "usr/lib/os-release", Typeflag: tar.TypeSymlink, Linkname: "/etc/os-release"},
+		}, map[string]string{
+			"etc/os-release": "NAME=test\nVERSION=2.0.0\n",
+		}),
+	})
+	require.NoError(t, err)
+	return img
+}
+
+func TestTreeFS(t *testing.T) {
+	img := fsTestImage(t)
+
+	t.Run("image squash", func(t *testing.T) {
+		require.NoError(t, fstest.TestFS(img.SquashedFS(),
+			"etc/os-release",
+			"etc/passwd",
+			"usr/bin/app",
+			"usr/bin/app-hardlink",
+			"usr/bin/app-link",
+			"usr/lib/os-release",
+		))
+	})
+
+	t.Run("layer diff tree", func(t *testing.T) {
+		require.NoError(t, fstest.TestFS(img.Layers[0].FS(),
+			"etc/os-release",
+			"etc/passwd",
+			"usr/bin/app",
+			"usr/bin/app-hardlink",
+		))
+	})
+
+	t.Run("layer squash tree", func(t *testing.T) {
+		require.NoError(t, fstest.TestFS(img.Layers[1].SquashedFS(),
+			"etc/os-release",
+			"etc/passwd",
+			"usr/bin/app",
+			"usr/lib/os-release",
+		))
+	})
+}
+
+func TestTreeFS_Contents(t *testing.T) {
+	img := fsTestImage(t)
+	fsys := img.SquashedFS()
+
+	tests := []struct {
+		name     string
+		path     string
+		fsys     fs.FS
+		want     string
+		wantErr  error
+		wantMode fs.FileMode
+	}{
+		{
+			name:     "regular file from upper layer",
+			path:     "etc/os-release",
+			fsys:     fsys,
+			want:     "NAME=test\nVERSION=2.0.0\n",
+			wantMode: 0644,
+		},
+		{
+			name:     "regular file from lower layer",
+			path:     "etc/os-release",
+			fsys:     img.Layers[0].FS(),
+			want:     "NAME=test\nVERSION=1.0.0\n",
+			wantMode: 0644,
+		},
+		{
+			name:     "absolute symlink",
+			path:     "usr/lib/os-release",
+			fsys:     fsys,
+			want:     "NAME=test\nVERSION=2.0.0\n",
+			wantMode: 0644,
+		},
+		{
+			name:     "relative symlink",
+			path:     "usr/bin/app-link",
+			fsys:     fsys,
+			want:     "#!/bin/sh\necho app\n",
+			wantMode: 0755,
+		},
+		{
+			name:     "hardlink",
+			path:     "usr/bin/app-hardlink",
+			fsys:     fsys,
+			want:     "#!/bin/sh\necho app\n",
+			wantMode: 0755,
+		},
+		{
+			name:    "missing file",
+			path:    "etc/shadow",
+			fsys:    fsys,
+			wantErr: fs.ErrNotExist,
+		},
+		{
+			name:    "dead link within a single layer",
+			path:    "usr/bin/app-link",
+			fsys:    img.Layers[1].FS(),
+			wantErr: fs.ErrNotExist,
+		},
+		{
+			name:    "invalid path",
+			path:    "/etc/os-release",
+			fsys:    fsys,
+			wantErr: fs.ErrInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fs.ReadFile(tt.fsys, tt.path)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+
+			info, err := fs.Stat(tt.fsys, tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantMode, info.Mode())
+			assert.Equal(t, int64(len(tt.want)), info.Size())
+		})
+	}
+}
+
+func TestTreeFS_Links(t *testing.T) {
+	fsys := fsTestImage(t).SquashedFS()
+
+	dest, err := fs.ReadLink(fsys, "usr/lib/os-release")
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/os-release", dest)
+
+	info, err := fs.Lstat(fsys, "usr/bin/app-link")
+	require.NoError(t, err)
+	assert.Equal(t, fs.ModeSymlink, info.Mode().Type())
+
+	// hardlinks are presented as the file they link to
+	info, err = fs.Lstat(fsys, "usr/bin/app-hardlink")
+	require.NoError(t, err)
+	assert.True(t, info.Mode().IsRegular())
+
+	_, err = fs.ReadLink(fsys, "usr/bin/app")
+	require.ErrorIs(t, err, fs.ErrInvalid)
+}
+
+func TestTreeFS_Seek(t *testing.T) {
+	fsys := fsTestImage(t).SquashedFS()
+
+	f, err := fsys.Open("etc/os-release")
+	require.NoError(t, err)
+	defer f.Close()
+
+	seeker := f.(io.ReadSeeker)
+
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(seeker, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "NAME", string(buf))
+
+	_, err = seeker.Seek(-6, io.SeekEnd)
+	require.NoError(t, err)
+	rest, err := io.ReadAll(seeker)
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0\n", string(rest))
+
+	// seeking backwards re-reads from the start of the file
+	_, err = seeker.Seek(5, io.SeekStart)
+	require.NoError(t, err)
+	_, err = io.ReadFull(seeker, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "test", string(buf))
+}
@@ -1,6 +1,7 @@
 package image
 
 import (
+	"context"
 	"crypto/sha256"
 	"errors"
 	"fmt"
@@ -39,8 +40,17 @@ type Image struct {
 	FileCatalog FileCatalogReader
 
 	SquashedSearchContext filetree.Searcher
+	// SkippedByReadLimits describes all content that was not read because it exceeded the configured read limits
+	// (only populated when partial reads are allowed, see WithReadLimits)
+	SkippedByReadLimits []ErrReadLimitExceeded
 
 	overrideMetadata []AdditionalMetadata
+	// readLimits tracks resource usage against the limits configured with WithReadLimits (nil when unbounded)
+	readLimits *readBudget
+	// derivedFileIDs indicates that file references are assigned deterministic IDs (see WithDerivedFileIDs)
+	derivedFileIDs bool
+	// layerVerification describes which layer digests are verified while reading (see WithLayerVerification)
+	layerVerification LayerVerification
 }
 
 type AdditionalMetadata func(*Image) error
@@ -112,6 +122,9 @@ func WithPlatform(platform string) AdditionalMetadata {
 		image.Metadata.Architecture = p.Architecture
 		image.Metadata.Variant = p.Variant
 		image.Metadata.OS = p.OS
+		if p.OSVersion != "" {
+			image.Metadata.OSVersion = p.OSVersion
+		}
 		return nil
 	}
 }
@@ -143,6 +156,17 @@ func WithOS(o string) AdditionalMetadata {
 	}
 }
 
+// WithDerivedFileIDs assigns all file references IDs derived from the layer digest, the real path, and the sequence
+// of the entry within the layer (see file.NewDerivedID) instead of sequential IDs. The same file in the same layer
+// then always has the same file.ID, regardless of the process or read order. This only takes effect when given to New
+// or to a provider (see stereoscope.WithDerivedFileIDs when using stereoscope.GetImage).
+func WithDerivedFileIDs() AdditionalMetadata {
+	return func(image *Image) error {
+		image.derivedFileIDs = true
+		return nil
+	}
+}
+
 // NewImage provides a new (unread) image object.
 //
 // Deprecated: use New() instead
@@ -197,6 +221,12 @@ func (i *Image) applyOverrideMetadata() error {
 // Read parses information from the underlying image tar into this struct. This includes image metadata, layer
 // metadata, layer file trees, and layer squash trees (which implies the image squash tree).
 func (i *Image) Read() error {
+	return i.ReadWithContext(context.Background())
+}
+
+// ReadWithContext is the same as Read, but reading stops with the context error as soon as the given context is done;
+// the caller is expected to call Cleanup() to remove any partially cached layer content.
+func (i *Image) ReadWithContext(ctx context.Context) error {
 	var layers = make([]*Layer, 0)
 	var err error
 	i.Metadata, err = readImageMetadata(i.image)
@@ -209,6 +239,10 @@ func (i *Image) Read() error {
 		return err
 	}
 
+	if defaults := getDefaultReadLimits(); i.readLimits == nil && defaults != (ReadLimits{}) {
+		i.readLimits = &readBudget{limits: defaults}
+	}
+
 	startTime := time.Now()
 	lapTime := startTime
 
@@ -228,14 +262,25 @@ func (i *Image) Read() error {
 	readProg := i.trackReadProgress(i.Metadata)
 
 	fileCatalog := NewFileCatalog()
+	if i.tmpDirGen != nil {
+		fileCatalog.SpoolTo(i.tmpDirGen)
+	}
 
 	for idx, v1Layer := range v1Layers {
 		layer := NewLayer(v1Layer)
-		err := layer.Read(fileCatalog, idx, i.contentCacheDir)
+		layer.readLimits = i.readLimits
+		layer.derivedFileIDs = i.derivedFileIDs
+		layer.verification = i.layerVerification
+		layer.os = i.Metadata.OS
+		if layer.os == "" {
+			layer.os = i.Metadata.Config.OS
+		}
+		err := layer.ReadWithContext(ctx, fileCatalog, idx, i.contentCacheDir)
 		if err != nil {
 			return err
 		}
 		i.Metadata.Size += layer.Metadata.Size
+		i.SkippedByReadLimits = append(i.SkippedByReadLimits, layer.SkippedByReadLimits...)
 		layers = append(layers, layer)
 
 		readProg.Increment()
@@ -247,7 +292,7 @@ func (i *Image) Read() error {
 	lapTime = time.Now()
 
 	// in order to resolve symlinks all squashed trees must be available
-	err = i.squash(readProg)
+	err = i.squash(ctx, readProg)
 
 	log.WithFields("digest", i.Metadata.ID, "time", time.Since(lapTime)).Trace("completed image squash")
 	lapTime = time.Now()
@@ -263,10 +308,14 @@ func (i *Image) Read() error {
 
 // squash generates a squash tree for each layer in the image. For instance, layer 2 squash =
 // squash(layer 0, layer 1, layer 2), layer 3 squash = squash(layer 0, layer 1, layer 2, layer 3), and so on.
-func (i *Image) squash(prog *progress.Manual) error {
+func (i *Image) squash(ctx context.Context, prog *progress.Manual) error {
 	var lastSquashTree filetree.ReadWriter
 
 	for idx, layer := range i.Layers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		if idx == 0 {
 			lastSquashTree = layer.Tree.(filetree.ReadWriter)
 			layer.SquashedTree = layer.Tree
@@ -344,6 +393,28 @@ func (i *Image) OpenReference(ref file.Reference) (io.ReadCloser, error) {
 	return i.FileCatalog.Open(ref)
 }
 
+// OpenSeekable fetches a random-access reader for the file contents of a single file reference, regardless of the
+// source layer. If the file reference does not exist an error is returned.
+func (i *Image) OpenSeekable(ref file.Reference) (file.SeekableReader, error) {
+	return openSeekable(i.FileCatalog, ref)
+}
+
+// OpenReaderAt fetches an io.ReaderAt for the file contents of a single file reference, regardless of the source
+// layer, along with the size of the contents. If the file reference does not exist an error is returned.
+func (i *Image) OpenReaderAt(ref file.Reference) (file.ReaderAtCloser, int64, error) {
+	reader, err := openSeekable(i.FileCatalog, ref)
+	if err != nil {
+		return nil, 0, err
+	}
+	return reader, reader.Size(), nil
+}
+
+// OpenSeekablePathFromSquash fetches a random-access reader for the file contents of a single path, relative to the
+// image squash tree. If the path does not exist an error is returned.
+func (i *Image) OpenSeekablePathFromSquash(path file.Path) (file.SeekableReader, error) {
+	return fetchSeekableReaderByPath(i.SquashedTree(), i.FileCatalog, path)
+}
+
 // FileContentsByRef fetches file contents for a single file reference, regardless of the source layer.
 // If the path does not exist an error is returned.
 //
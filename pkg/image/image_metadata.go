@@ -23,6 +23,8 @@ type Metadata struct {
 	Architecture   string
 	Variant        string
 	OS             string
+	// OSVersion is the operating system version the image was built for (only populated for Windows images)
+	OSVersion string
 }
 
 // readImageMetadata extracts the most pertinent information from the underlying image tar.
@@ -52,5 +54,6 @@ func readImageMetadata(img v1.Image) (Metadata, error) {
 		Config:    *config,
 		MediaType: mediaType,
 		RawConfig: rawConfig,
+		OSVersion: config.OSVersion,
 	}, nil
 }
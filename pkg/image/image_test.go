@@ -1,14 +1,23 @@
 package image
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
 	"crypto/sha256"
 	"fmt"
+	"io"
 	"os"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anchore/stereoscope/pkg/file"
 )
 
 func TestImageAdditionalMetadata(t *testing.T) {
@@ -135,3 +144,107 @@ func TestImage_SquashedTree(t *testing.T) {
 		}
 	})
 }
+
+func TestImage_OpenReaderAt(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("META-INF/MANIFEST.MF")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("Manifest-Version: 1.0\n"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	img, err := readTestImage(t, []v1.Layer{
+		tarLayer(t, map[string]string{"app/lib.jar": buf.String()}),
+	})
+	require.NoError(t, err)
+
+	_, ref, err := img.SquashedTree().File("/app/lib.jar")
+	require.NoError(t, err)
+	require.NotNil(t, ref)
+
+	readerAt, size, err := img.OpenReaderAt(*ref.Reference)
+	require.NoError(t, err)
+	defer readerAt.Close()
+	assert.Equal(t, int64(buf.Len()), size)
+
+	zr, err := zip.NewReader(readerAt, size)
+	require.NoError(t, err)
+	require.Len(t, zr.File, 1)
+
+	rc, err := zr.File[0].Open()
+	require.NoError(t, err)
+	defer rc.Close()
+	contents, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "Manifest-Version: 1.0\n", string(contents))
+
+	for _, open := range []func(file.Path) (file.SeekableReader, error){
+		img.OpenSeekablePathFromSquash,
+		img.Layers[0].OpenSeekablePath,
+		img.Layers[0].OpenSeekablePathFromSquash,
+	} {
+		r, err := open("/app/lib.jar")
+		require.NoError(t, err)
+		assert.Equal(t, size, r.Size())
+		require.NoError(t, r.Close())
+	}
+
+	_, err = img.OpenSeekablePathFromSquash("/missing")
+	require.Error(t, err)
+}
+
+func TestImage_DerivedFileIDs(t *testing.T) {
+	layers := func() []v1.Layer {
+		return []v1.Layer{
+			tarLayer(t, map[string]string{"etc/os-release": "ID=test"}),
+			// note: the entry order must be stable for the layer digest (and entry sequences) to be stable
+			tarLayerFromHeaders(t, []tar.Header{
+				{Name: "etc/os-release", Typeflag: tar.TypeReg, Mode: 0644},
+				{Name: "app/main", Typeflag: tar.TypeReg, Mode: 0755},
+			}, map[string]string{"etc/os-release": "ID=other", "app/main": "binary"}),
+		}
+	}
+
+	ids := func(img *Image) map[file.Path][]file.ID {
+		out := make(map[file.Path][]file.ID)
+		for _, l := range img.Layers {
+			for _, ref := range l.Tree.AllFiles(file.AllTypes()...) {
+				out[ref.RealPath] = append(out[ref.RealPath], ref.ID())
+			}
+		}
+		return out
+	}
+
+	first, err := readTestImage(t, layers(), WithDerivedFileIDs())
+	require.NoError(t, err)
+	second, err := readTestImage(t, layers(), WithDerivedFileIDs())
+	require.NoError(t, err)
+
+	firstIDs := ids(first)
+	assert.Equal(t, firstIDs, ids(second), "the same files in the same layers must have the same IDs")
+
+	require.Len(t, firstIDs["/etc/os-release"], 2)
+	assert.NotEqual(t, firstIDs["/etc/os-release"][0], firstIDs["/etc/os-release"][1], "the same path in different layers must have different IDs")
+	for _, refIDs := range firstIDs {
+		for _, id := range refIDs {
+			assert.True(t, id.IsDerived())
+		}
+	}
+
+	// the catalog is keyed by the derived IDs
+	_, ref, err := first.SquashedTree().File("/app/main")
+	require.NoError(t, err)
+	entry, err := first.FileCatalog.Get(*ref.Reference)
+	require.NoError(t, err)
+	assert.Equal(t, "/app/main", entry.Path)
+
+	// sequential IDs are used by default
+	sequential, err := readTestImage(t, layers())
+	require.NoError(t, err)
+	for _, refIDs := range ids(sequential) {
+		for _, id := range refIDs {
+			assert.False(t, id.IsDerived())
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package image
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -49,9 +50,11 @@ var singularityLayerMediaTypes = strset.New(
 	string(SingularitySquashFSLayer),
 )
 
-// isSupportedLayerMediaType returns true if the given media type is supported for layer processing.
+// isSupportedLayerMediaType returns true if the given media type is supported for layer processing (either a built-in
+// media type or one with a registered LayerReader).
 func isSupportedLayerMediaType(mt types.MediaType) bool {
-	return standardLayerMediaTypes.Has(string(mt)) || singularityLayerMediaTypes.Has(string(mt))
+	_, ok := layerReaders.get(mt)
+	return ok
 }
 
 // validateLayerMediaTypes checks all layers have supported media types before processing.
@@ -67,7 +70,7 @@ func validateLayerMediaTypes(layers []v1.Layer) error {
 		}
 	}
 	if len(unsupported) > 0 {
-		return fmt.Errorf("unsupported layer media type(s): %s", strings.Join(unsupported, ", "))
+		return fmt.Errorf("%w: unsupported layer media type(s): %s", ErrUnsupportedMediaType, strings.Join(unsupported, ", "))
 	}
 	return nil
 }
@@ -89,6 +92,23 @@ type Layer struct {
 	fileCatalog           *FileCatalog
 	SquashedSearchContext filetree.Searcher
 	SearchContext         filetree.Searcher
+	// SkippedByReadLimits describes all content in this layer that was not read because it exceeded the configured
+	// read limits (only populated when partial reads are allowed)
+	SkippedByReadLimits []ErrReadLimitExceeded
+	// Anomalies describes malformed or suspicious content found while reading the layer, in the order found
+	Anomalies []LayerAnomaly
+	// Windows indicates that the layer uses the Windows layer layout (content is stored under `Files/`), in which case
+	// the layer trees are case-insensitive.
+	Windows bool
+	// readLimits tracks resource usage against the read limits shared by all layers of the image (nil when unbounded)
+	readLimits *readBudget
+	// os is the operating system of the image the layer belongs to (empty when unknown)
+	os string
+	// derivedFileIDs indicates that file references are assigned IDs derived from the layer digest, path, and entry
+	// sequence instead of sequential IDs (see WithDerivedFileIDs)
+	derivedFileIDs bool
+	// verification describes which layer digests are verified while caching the layer content
+	verification LayerVerification
 }
 
 // NewLayer provides a new, unread layer object.
@@ -98,21 +118,47 @@ func NewLayer(layer v1.Layer) *Layer {
 	}
 }
 
-func (l *Layer) uncompressedCache(uncompressedLayersCacheDir string) (string, error) {
+func (l *Layer) uncompressedCache(ctx context.Context, uncompressedLayersCacheDir string) (string, error) {
 	if uncompressedLayersCacheDir == "" {
 		return "", fmt.Errorf("no cache directory given")
 	}
 
 	path := path.Join(uncompressedLayersCacheDir, l.Metadata.Digest)
 
-	if _, err := os.Stat(path); !os.IsNotExist(err) {
+	var compressedSize int64
+	if l.readLimits != nil {
+		// the compressed size is only used to bound the compression ratio, so it is not required to be known
+		if size, err := l.layer.Size(); err == nil {
+			compressedSize = size
+		}
+	}
+
+	fi, err := os.Stat(path)
+	if err == nil && l.verification.DiffIDs {
+		if verifyErr := l.verifyCachedContent(path); verifyErr != nil {
+			// the cache is keyed by diff ID, so content that does not match can be replaced from the layer blob
+			log.WithFields("path", path, "error", verifyErr).Warn("discarding corrupt layer cache")
+			if rmErr := os.Remove(path); rmErr != nil {
+				return "", fmt.Errorf("unable to remove corrupt layer cache=%q : %w", path, rmErr)
+			}
+			err = os.ErrNotExist
+		}
+	}
+
+	if !os.IsNotExist(err) {
+		if err == nil {
+			if limitErr := l.readLimits.checkLayerBytes(l, fi.Size(), compressedSize); limitErr != nil {
+				return "", limitErr
+			}
+			l.readLimits.consume(fi.Size())
+		}
 		return path, nil
 	}
 
 	log.WithFields("index", l.Metadata.Index, "path", path).Trace("start uncompressed layer cache")
 	startTime := time.Now()
 
-	rawReader, err := l.layer.Uncompressed()
+	rawReader, verifyBlob, err := l.verifiedUncompressed()
 	if err != nil {
 		return "", err
 	}
@@ -124,97 +170,158 @@ func (l *Layer) uncompressedCache(uncompressedLayersCacheDir string) (string, er
 	}
 	defer fh.Close()
 
-	if _, err := io.Copy(fh, rawReader); err != nil {
+	var reader io.Reader = &contextReader{ctx: ctx, reader: rawReader}
+	if l.readLimits != nil {
+		reader = &limitedLayerReader{budget: l.readLimits, layer: l, reader: reader, compressedSize: compressedSize}
+	}
+
+	var writer io.Writer = fh
+	var diffID *digester
+	if l.verification.DiffIDs {
+		if diffID, err = l.diffIDDigester(); err != nil {
+			return "", err
+		}
+		writer = io.MultiWriter(fh, diffID.hash)
+	}
+
+	n, err := io.Copy(writer, reader)
+	if err == nil {
+		err = l.verifyCacheWrite(verifyBlob, diffID)
+	}
+	if err == nil && diffID != nil {
+		err = recordVerifiedCache(path)
+	}
+	if err != nil {
+		// don't leave a partial (or unverified) layer behind, otherwise it would be considered a valid cache entry on
+		// the next read
+		if rmErr := os.Remove(path); rmErr != nil {
+			log.WithFields("path", path, "error", rmErr).Debug("unable to remove partial layer cache")
+		}
+		var mismatchErr *ErrLayerDigestMismatch
+		if errors.As(err, &mismatchErr) {
+			return "", err
+		}
 		return "", fmt.Errorf("unable to populate layer cache dir=%q : %w", path, err)
 	}
+	l.readLimits.consume(n)
 	log.WithFields("index", l.Metadata.Index, "path", path, "time", time.Since(startTime)).Trace("completed uncompressed layer cache")
 
 	return path, nil
 }
 
+// verifyCacheWrite verifies the layer blob digest and the diff ID of all content written to the layer cache (when
+// enabled, see LayerVerification).
+func (l *Layer) verifyCacheWrite(verifyBlob func() error, diffID *digester) error {
+	if err := verifyBlob(); err != nil {
+		return err
+	}
+	if diffID == nil {
+		return nil
+	}
+	return l.checkDigest(LayerDiffID, diffID.expected, diffID.actual())
+}
+
+// contextReader is an io.Reader that stops reading once the given context is done.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
 // Read parses information from the underlying layer tar into this struct. This includes layer metadata, the layer
 // file tree, and the layer squash tree.
 func (l *Layer) Read(catalog *FileCatalog, idx int, uncompressedLayersCacheDir string) error {
+	return l.ReadWithContext(context.Background(), catalog, idx, uncompressedLayersCacheDir)
+}
+
+// ReadWithContext is the same as Read, but reading stops with the context error as soon as the given context is done.
+func (l *Layer) ReadWithContext(ctx context.Context, catalog *FileCatalog, idx int, uncompressedLayersCacheDir string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	mediaType, err := l.layer.MediaType()
 	if err != nil {
 		return err
 	}
-	tree := filetree.New()
-	l.Tree = tree
-	l.fileCatalog = catalog
 
-	var readErr error
-	switch {
-	case standardLayerMediaTypes.Has(string(mediaType)):
-		readErr = l.readStandardImageLayer(idx, uncompressedLayersCacheDir, tree)
-	case singularityLayerMediaTypes.Has(string(mediaType)):
-		readErr = l.readSingularityImageLayer(idx, uncompressedLayersCacheDir, tree)
-	default:
-		return fmt.Errorf("unknown layer media type: %+v", mediaType)
+	reader, ok := layerReaders.get(mediaType)
+	if !ok {
+		return fmt.Errorf("%w: unknown layer media type: %+v", ErrUnsupportedMediaType, mediaType)
 	}
-	if readErr != nil {
-		return readErr
-	}
-
-	startTime := time.Now()
-	l.SearchContext = filetree.NewSearchContext(l.Tree, l.fileCatalog.Index)
-	log.WithFields("index", idx, "time", time.Since(startTime)).Trace("completed layer search context")
 
-	return nil
-}
+	tree := filetree.New()
+	l.Tree = tree
+	l.fileCatalog = catalog
 
-func (l *Layer) readStandardImageLayer(idx int, uncompressedLayersCacheDir string, tree *filetree.FileTree) error {
-	var err error
 	l.Metadata, err = newLayerMetadata(l.layer, idx)
-	monitor := trackReadProgress(l.Metadata)
 	if err != nil {
 		return err
 	}
 
-	log.WithFields("index", l.Metadata.Index, "digest", l.Metadata.Digest, "mediaType", l.Metadata.MediaType).Trace("reading uncompressed image layer")
+	log.WithFields("index", l.Metadata.Index, "digest", l.Metadata.Digest, "mediaType", l.Metadata.MediaType).Trace("reading image layer")
 
-	tarFilePath, err := l.uncompressedCache(uncompressedLayersCacheDir)
-	if err != nil {
-		return err
-	}
+	monitor := trackReadProgress(l.Metadata)
 
 	startTime := time.Now()
-	l.indexedContent, err = file.NewTarIndex(
-		tarFilePath,
-		layerTarIndexer(tree, l.fileCatalog, &l.Metadata.Size, l, monitor),
-	)
-	if err != nil {
-		return fmt.Errorf("failed to read layer=%q tar : %w", l.Metadata.Digest, err)
+	contentPath, err := l.uncompressedCache(ctx, uncompressedLayersCacheDir)
+	var limitErr *ErrReadLimitExceeded
+	switch {
+	case errors.As(err, &limitErr) && l.readLimits.limits.PartialRead:
+		// the layer content is too large to read, so the layer is treated as empty
+		l.skip(limitErr)
+	case err != nil:
+		return err
+	default:
+		err := reader(ctx, l, contentPath, tree, catalog, monitor)
+		// references are only created while indexing, so don't keep the factory around
+		tree.SetReferenceFactory(nil)
+		if err != nil {
+			return err
+		}
+		if l.Windows {
+			tree.SetCaseInsensitive(true)
+			for _, collision := range tree.CaseFoldCollisions() {
+				log.WithFields("index", l.Metadata.Index, "paths", collision.Paths).Debug("layer paths collide under case-insensitive lookup")
+			}
+		}
+		log.WithFields("index", l.Metadata.Index, "digest", l.Metadata.Digest, "mediaType", l.Metadata.MediaType, "time", time.Since(startTime)).Trace("completed indexing image layer")
 	}
-	log.WithFields("index", l.Metadata.Index, "digest", l.Metadata.Digest, "mediaType", l.Metadata.MediaType, "time", time.Since(startTime)).Trace("completed indexing image layer")
 
 	monitor.SetCompleted()
+
+	startTime = time.Now()
+	l.SearchContext = filetree.NewSearchContext(l.Tree, l.fileCatalog.Index)
+	log.WithFields("index", idx, "time", time.Since(startTime)).Trace("completed layer search context")
+
 	return nil
 }
 
-func (l *Layer) readSingularityImageLayer(idx int, uncompressedLayersCacheDir string, tree *filetree.FileTree) error {
+// readTarLayer is the LayerReader for all tar-based layer media types.
+func readTarLayer(ctx context.Context, l *Layer, contentPath string, tree filetree.Writer, catalog *FileCatalog, monitor *progress.Manual) error {
 	var err error
-	l.Metadata, err = newLayerMetadata(l.layer, idx)
-	if err != nil {
-		return err
-	}
-
-	log.Debugf("layer metadata: index=%+v digest=%+v mediaType=%+v",
-		l.Metadata.Index,
-		l.Metadata.Digest,
-		l.Metadata.MediaType)
-
-	monitor := trackReadProgress(l.Metadata)
-	sqfsFilePath, err := l.uncompressedCache(uncompressedLayersCacheDir)
+	l.indexedContent, err = file.NewTarIndexWithContext(
+		ctx,
+		contentPath,
+		layerTarIndexer(tree, catalog, &l.Metadata.Size, l, monitor),
+	)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to read layer=%q tar : %w", l.Metadata.Digest, err)
 	}
+	return nil
+}
 
-	if err := file.WalkSquashFS(sqfsFilePath, squashfsVisitor(tree, l.fileCatalog, &l.Metadata.Size, l, monitor)); err != nil {
+// readSquashFSLayer is the LayerReader for all SquashFS-based layer media types.
+func readSquashFSLayer(ctx context.Context, l *Layer, contentPath string, tree filetree.Writer, catalog *FileCatalog, monitor *progress.Manual) error {
+	if err := file.WalkSquashFS(contentPath, squashfsVisitor(ctx, tree, catalog, &l.Metadata.Size, l, monitor)); err != nil {
 		return fmt.Errorf("failed to walk layer=%q: %w", l.Metadata.Digest, err)
 	}
-
-	monitor.SetCompleted()
 	return nil
 }
 
@@ -230,6 +337,19 @@ func (l *Layer) OpenPathFromSquash(path file.Path) (io.ReadCloser, error) {
 	return fetchReaderByPath(l.SquashedTree, l.fileCatalog, path)
 }
 
+// OpenSeekablePath returns a random-access reader for the file contents of the given path from the underlying layer
+// blob, relative to the layers "diff tree". An error is returned if there is no file at the given path and layer.
+func (l *Layer) OpenSeekablePath(path file.Path) (file.SeekableReader, error) {
+	return fetchSeekableReaderByPath(l.Tree, l.fileCatalog, path)
+}
+
+// OpenSeekablePathFromSquash returns a random-access reader for the file contents of the given path from the
+// underlying layer blob, relative to the layers squashed file tree. An error is returned if there is no file at the
+// given path and layer.
+func (l *Layer) OpenSeekablePathFromSquash(path file.Path) (file.SeekableReader, error) {
+	return fetchSeekableReaderByPath(l.SquashedTree, l.fileCatalog, path)
+}
+
 // FileContents reads the file contents for the given path from the underlying layer blob, relative to the layers "diff tree".
 // An error is returned if there is no file at the given path and layer or the read operation cannot continue.
 //
@@ -282,10 +402,34 @@ func (l *Layer) FilesByMIMETypeFromSquash(mimeTypes ...string) ([]file.Reference
 
 func layerTarIndexer(ft filetree.Writer, fileCatalog *FileCatalog, size *int64, layerRef *Layer, monitor *progress.Manual) file.TarIndexVisitor {
 	builder := filetree.NewBuilder(ft, fileCatalog.Index)
+	anomalies := newTarAnomalyDetector()
+	var windows *windowsLayerDetector
+	var sequence int64
+	if layerRef != nil {
+		windows = newWindowsLayerDetector(layerRef.os)
+		layerRef.useDerivedFileIDs(ft, &sequence)
+	}
 
 	return func(index file.TarIndexEntry) error {
 		var err error
 		var entry = index.ToTarFileEntry()
+		sequence = entry.Sequence
+
+		if layerRef != nil {
+			layerRef.Anomalies = append(layerRef.Anomalies, anomalies.observe(entry.Sequence, entry.Header)...)
+
+			if layerRef.Windows = windows.observe(entry.Header.Name); layerRef.Windows && !normalizeWindowsHeader(&entry.Header) {
+				return nil
+			}
+
+			admit, err := layerRef.AdmitFile(entry.Header.Name)
+			if errors.Is(err, fs.SkipAll) {
+				return file.ErrTarStopIteration
+			}
+			if err != nil || !admit {
+				return err
+			}
+		}
 
 		var contents = index.Open()
 		defer func() {
@@ -324,6 +468,23 @@ func layerTarIndexer(ft filetree.Writer, fileCatalog *FileCatalog, size *int64,
 	}
 }
 
+// useDerivedFileIDs configures the given tree to assign file reference IDs derived from the layer digest, the path,
+// and the current entry sequence (when enabled for the layer).
+func (l *Layer) useDerivedFileIDs(ft filetree.Writer, sequence *int64) {
+	if !l.derivedFileIDs {
+		return
+	}
+	t, ok := ft.(*filetree.FileTree)
+	if !ok {
+		log.WithFields("index", l.Metadata.Index).Debug("unable to assign derived file IDs for layer tree")
+		return
+	}
+	digest := l.Metadata.Digest
+	t.SetReferenceFactory(func(realPath file.Path) *file.Reference {
+		return file.NewFileReferenceWithID(realPath, file.NewDerivedID(digest, realPath, *sequence))
+	})
+}
+
 // squashfsReader implements an io.ReadCloser that reads a file from within a SquashFS filesystem.
 type squashfsReader struct {
 	fs.File
@@ -363,10 +524,27 @@ func (f *squashfsReader) Close() error {
 	return f.backingFile.Close()
 }
 
-func squashfsVisitor(ft filetree.Writer, fileCatalog *FileCatalog, size *int64, layerRef *Layer, monitor *progress.Manual) file.SquashFSVisitor {
+func squashfsVisitor(ctx context.Context, ft filetree.Writer, fileCatalog *FileCatalog, size *int64, layerRef *Layer, monitor *progress.Manual) file.SquashFSVisitor {
 	builder := filetree.NewBuilder(ft, fileCatalog.Index)
 
+	// squashfs entries have no sequence, so the (deterministic) walk order is used instead
+	sequence := int64(-1)
+	if layerRef != nil {
+		layerRef.useDerivedFileIDs(ft, &sequence)
+	}
+
 	return func(fsys fs.FS, sqfsPath, path string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		sequence++
+
+		if layerRef != nil {
+			if admit, err := layerRef.AdmitFile(path); err != nil || !admit {
+				return err
+			}
+		}
+
 		ff, err := fsys.Open(path)
 		if err != nil {
 			return err
@@ -378,7 +556,7 @@ func squashfsVisitor(ft filetree.Writer, fileCatalog *FileCatalog, size *int64,
 			return errors.New("unexpected file type from squashfs")
 		}
 
-		metadata, err := file.NewMetadataFromSquashFSFile(path, f)
+		metadata, err := file.NewMetadataFromSquashFS(fsys, path, f)
 		if err != nil {
 			return err
 		}
@@ -395,7 +573,9 @@ func squashfsVisitor(ft filetree.Writer, fileCatalog *FileCatalog, size *int64,
 			return newSquashfsFileReader(sqfsPath, path)
 		})
 
-		monitor.Increment()
+		if monitor != nil {
+			monitor.Increment()
+		}
 		return nil
 	}
 }
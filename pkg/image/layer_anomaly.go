@@ -0,0 +1,167 @@
+package image
+
+import (
+	"archive/tar"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/anchore/stereoscope/internal/log"
+)
+
+// LayerAnomalyKind describes a class of malformed or suspicious layer content.
+type LayerAnomalyKind string
+
+const (
+	// DuplicatePathAnomaly is an entry for a path that already has an entry of the same type in the layer (the last
+	// entry takes precedence).
+	DuplicatePathAnomaly LayerAnomalyKind = "duplicate-path"
+	// ConflictingTypeAnomaly is an entry for a path that already has an entry of a different type in the layer (e.g.
+	// a regular file replacing a directory).
+	ConflictingTypeAnomaly LayerAnomalyKind = "conflicting-type"
+	// PathTraversalAnomaly is an entry name or link target with ".." components that resolve above the root.
+	PathTraversalAnomaly LayerAnomalyKind = "path-traversal"
+	// NonCanonicalNameAnomaly is an entry name or hardlink target that is not in canonical form (e.g. is absolute or
+	// has empty, "." or ".." components).
+	NonCanonicalNameAnomaly LayerAnomalyKind = "non-canonical-name"
+	// MissingHardlinkTargetAnomaly is a hardlink to a path that does not appear earlier in the layer.
+	MissingHardlinkTargetAnomaly LayerAnomalyKind = "missing-hardlink-target"
+)
+
+// LayerAnomaly describes malformed or suspicious content found while reading a layer. Anomalies do not prevent the
+// layer from being read, as the content is normalized when added to the layer file tree, however, they may indicate an
+// attempt to hide content from (or confuse) tooling that inspects the layer.
+type LayerAnomaly struct {
+	// Kind is the class of anomaly found
+	Kind LayerAnomalyKind
+	// Sequence is the position of the offending entry within the layer tar (starting at 0)
+	Sequence int64
+	// Name is the entry name exactly as it appears in the layer tar
+	Name string
+	// Description is a human-readable explanation of the anomaly
+	Description string
+}
+
+func (a LayerAnomaly) String() string {
+	return fmt.Sprintf("%s (sequence=%d name=%q): %s", a.Kind, a.Sequence, a.Name, a.Description)
+}
+
+type tarAnomalyEntry struct {
+	sequence int64
+	typeflag byte
+}
+
+// tarAnomalyDetector finds anomalies across all entries of a single layer tar, which must be observed in order.
+type tarAnomalyDetector struct {
+	seen map[string]tarAnomalyEntry
+}
+
+func newTarAnomalyDetector() *tarAnomalyDetector {
+	return &tarAnomalyDetector{
+		seen: make(map[string]tarAnomalyEntry),
+	}
+}
+
+// observe returns all anomalies for the given tar entry relative to all entries previously observed.
+func (d *tarAnomalyDetector) observe(sequence int64, hdr tar.Header) []LayerAnomaly {
+	var anomalies []LayerAnomaly
+	add := func(kind LayerAnomalyKind, format string, args ...any) {
+		anomalies = append(anomalies, LayerAnomaly{
+			Kind:        kind,
+			Sequence:    sequence,
+			Name:        hdr.Name,
+			Description: fmt.Sprintf(format, args...),
+		})
+	}
+
+	name, escapes := canonicalTarPath(hdr.Name)
+	switch {
+	case escapes:
+		add(PathTraversalAnomaly, "entry name resolves above the root")
+	case !isCanonicalTarName(hdr.Name):
+		add(NonCanonicalNameAnomaly, "entry name normalizes to %q", name)
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeLink:
+		target, targetEscapes := canonicalTarPath(hdr.Linkname)
+		switch {
+		case targetEscapes:
+			add(PathTraversalAnomaly, "hardlink target %q resolves above the root", hdr.Linkname)
+		case !isCanonicalTarName(hdr.Linkname):
+			add(NonCanonicalNameAnomaly, "hardlink target %q normalizes to %q", hdr.Linkname, target)
+		}
+		if _, ok := d.seen[target]; !ok {
+			add(MissingHardlinkTargetAnomaly, "hardlink target %q does not appear earlier in the layer", hdr.Linkname)
+		}
+	case tar.TypeSymlink:
+		// relative symlinks are resolved from the directory containing the link, where ".." components are expected,
+		// however, resolving above the root is not (note: path.Join would clean away the components that escape).
+		target := hdr.Linkname
+		if !path.IsAbs(target) {
+			target = path.Dir(name) + "/" + target
+		}
+		if _, targetEscapes := canonicalTarPath(target); targetEscapes {
+			add(PathTraversalAnomaly, "symlink target %q resolves above the root", hdr.Linkname)
+		}
+	}
+
+	if previous, ok := d.seen[name]; ok {
+		if normalizedTypeflag(previous.typeflag) != normalizedTypeflag(hdr.Typeflag) {
+			add(ConflictingTypeAnomaly, "replaces entry of a different type at sequence %d", previous.sequence)
+		} else {
+			add(DuplicatePathAnomaly, "replaces entry at sequence %d", previous.sequence)
+		}
+	}
+	d.seen[name] = tarAnomalyEntry{sequence: sequence, typeflag: hdr.Typeflag}
+
+	for _, a := range anomalies {
+		log.WithFields("kind", a.Kind, "sequence", a.Sequence, "name", a.Name, "description", a.Description).Debug("found layer anomaly")
+	}
+
+	return anomalies
+}
+
+// canonicalTarPath returns the absolute, cleaned form of the given tar path and whether any ".." component would
+// resolve above the root (which path.Clean silently discards).
+func canonicalTarPath(p string) (string, bool) {
+	var depth int
+	var escapes bool
+	for _, segment := range strings.Split(p, "/") {
+		switch segment {
+		case "", ".":
+		case "..":
+			depth--
+			if depth < 0 {
+				escapes = true
+				depth = 0
+			}
+		default:
+			depth++
+		}
+	}
+	return path.Clean("/" + p), escapes
+}
+
+// isCanonicalTarName indicates if the given tar name is relative with no empty, "." or ".." components. A leading "./"
+// and a trailing "/" (for directories) are commonly written by tar implementations and are considered canonical.
+func isCanonicalTarName(name string) bool {
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(name, "./"), "/")
+	if trimmed == "" || trimmed == "." {
+		return name == "./" || name == "."
+	}
+	for _, segment := range strings.Split(trimmed, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizedTypeflag accounts for the legacy regular file typeflag being equivalent to the current one.
+func normalizedTypeflag(typeflag byte) byte {
+	if typeflag == tar.TypeRegA { //nolint:staticcheck // legacy tar writers may still use this
+		return tar.TypeReg
+	}
+	return typeflag
+}
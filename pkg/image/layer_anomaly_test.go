@@ -0,0 +1,113 @@
+package image
+
+import (
+	"archive/tar"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTarAnomalyDetector(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []tar.Header
+		want    []LayerAnomaly
+	}{
+		{
+			name: "no anomalies",
+			headers: []tar.Header{
+				{Name: "./", Typeflag: tar.TypeDir},
+				{Name: "etc/", Typeflag: tar.TypeDir},
+				{Name: "etc/passwd", Typeflag: tar.TypeReg},
+				{Name: "etc/passwd-link", Typeflag: tar.TypeLink, Linkname: "etc/passwd"},
+				{Name: "usr/lib/libc.so", Typeflag: tar.TypeSymlink, Linkname: "../../lib/libc.so"},
+				{Name: "usr/bin/sh", Typeflag: tar.TypeSymlink, Linkname: "/bin/busybox"},
+			},
+		},
+		{
+			name: "duplicate path",
+			headers: []tar.Header{
+				{Name: "etc/passwd", Typeflag: tar.TypeReg},
+				{Name: "etc/passwd", Typeflag: tar.TypeRegA},
+			},
+			want: []LayerAnomaly{
+				{Kind: DuplicatePathAnomaly, Sequence: 1, Name: "etc/passwd", Description: "replaces entry at sequence 0"},
+			},
+		},
+		{
+			name: "conflicting type",
+			headers: []tar.Header{
+				{Name: "etc/", Typeflag: tar.TypeDir},
+				{Name: "etc", Typeflag: tar.TypeReg},
+			},
+			want: []LayerAnomaly{
+				{Kind: ConflictingTypeAnomaly, Sequence: 1, Name: "etc", Description: "replaces entry of a different type at sequence 0"},
+			},
+		},
+		{
+			name: "path traversal",
+			headers: []tar.Header{
+				{Name: "../../etc/passwd", Typeflag: tar.TypeReg},
+				{Name: "usr/lib/libc.so", Typeflag: tar.TypeSymlink, Linkname: "../../../etc/shadow"},
+			},
+			want: []LayerAnomaly{
+				{Kind: PathTraversalAnomaly, Sequence: 0, Name: "../../etc/passwd", Description: "entry name resolves above the root"},
+				{Kind: PathTraversalAnomaly, Sequence: 1, Name: "usr/lib/libc.so", Description: `symlink target "../../../etc/shadow" resolves above the root`},
+			},
+		},
+		{
+			name: "non-canonical names",
+			headers: []tar.Header{
+				{Name: "/etc/passwd", Typeflag: tar.TypeReg},
+				{Name: "usr//bin/./sh", Typeflag: tar.TypeReg},
+				{Name: "usr/lib/../bin/ls", Typeflag: tar.TypeReg},
+			},
+			want: []LayerAnomaly{
+				{Kind: NonCanonicalNameAnomaly, Sequence: 0, Name: "/etc/passwd", Description: `entry name normalizes to "/etc/passwd"`},
+				{Kind: NonCanonicalNameAnomaly, Sequence: 1, Name: "usr//bin/./sh", Description: `entry name normalizes to "/usr/bin/sh"`},
+				{Kind: NonCanonicalNameAnomaly, Sequence: 2, Name: "usr/lib/../bin/ls", Description: `entry name normalizes to "/usr/bin/ls"`},
+			},
+		},
+		{
+			name: "hardlinks",
+			headers: []tar.Header{
+				{Name: "a-link", Typeflag: tar.TypeLink, Linkname: "a"},
+				{Name: "a", Typeflag: tar.TypeReg},
+				{Name: "b-link", Typeflag: tar.TypeLink, Linkname: "/a"},
+				{Name: "c-link", Typeflag: tar.TypeLink, Linkname: "../../a"},
+			},
+			want: []LayerAnomaly{
+				{Kind: MissingHardlinkTargetAnomaly, Sequence: 0, Name: "a-link", Description: `hardlink target "a" does not appear earlier in the layer`},
+				{Kind: NonCanonicalNameAnomaly, Sequence: 2, Name: "b-link", Description: `hardlink target "/a" normalizes to "/a"`},
+				{Kind: PathTraversalAnomaly, Sequence: 3, Name: "c-link", Description: `hardlink target "../../a" resolves above the root`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector := newTarAnomalyDetector()
+			var got []LayerAnomaly
+			for idx, hdr := range tt.headers {
+				got = append(got, detector.observe(int64(idx), hdr)...)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLayerAnomalies(t *testing.T) {
+	layer := tarLayerFromHeaders(t, []tar.Header{
+		{Name: "etc/passwd", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "etc/passwd", Typeflag: tar.TypeReg, Mode: 0644},
+	}, nil)
+
+	img, err := readTestImage(t, []v1.Layer{layer})
+	require.NoError(t, err)
+	require.Len(t, img.Layers, 1)
+
+	assert.Equal(t, []LayerAnomaly{
+		{Kind: DuplicatePathAnomaly, Sequence: 1, Name: "etc/passwd", Description: "replaces entry at sequence 0"},
+	}, img.Layers[0].Anomalies)
+}
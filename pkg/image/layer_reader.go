@@ -0,0 +1,82 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/wagoodman/go-progress"
+
+	"github.com/anchore/stereoscope/internal/log"
+	"github.com/anchore/stereoscope/pkg/filetree"
+)
+
+// LayerReader populates the given file tree and file catalog from the uncompressed layer content cached at
+// contentPath. Implementations are expected to add every file to both the tree and the catalog index (see
+// filetree.NewBuilder), associate each new file reference with the given layer and a content opener in the catalog
+// (see FileCatalog.AssociateLayer and FileCatalog.AssociateOpener), add the size of each file to layer.Metadata.Size, and
+// increment the monitor once per file processed. Each file should be checked against the configured read limits with
+// Layer.AdmitFile before being added. Readers should stop and return the context error once the given context
+// is done.
+type LayerReader func(ctx context.Context, layer *Layer, contentPath string, tree filetree.Writer, catalog *FileCatalog, monitor *progress.Manual) error
+
+// layerReaderRegistry is the set of LayerReaders keyed by the layer media type they are able to process.
+type layerReaderRegistry struct {
+	lock    sync.RWMutex
+	readers map[types.MediaType]LayerReader
+}
+
+var layerReaders = newLayerReaderRegistry()
+
+func newLayerReaderRegistry() *layerReaderRegistry {
+	r := &layerReaderRegistry{
+		readers: make(map[types.MediaType]LayerReader),
+	}
+	for _, mt := range standardLayerMediaTypes.List() {
+		r.readers[types.MediaType(mt)] = readTarLayer
+	}
+	for _, mt := range singularityLayerMediaTypes.List() {
+		r.readers[types.MediaType(mt)] = readSquashFSLayer
+	}
+	return r
+}
+
+// RegisterLayerReader associates the given LayerReader with one or more layer media types. Registering a reader for a
+// media type that is already supported (including built-in media types) replaces the existing reader.
+func RegisterLayerReader(reader LayerReader, mediaTypes ...types.MediaType) error {
+	return layerReaders.register(reader, mediaTypes...)
+}
+
+func (r *layerReaderRegistry) register(reader LayerReader, mediaTypes ...types.MediaType) error {
+	if reader == nil {
+		return fmt.Errorf("no layer reader given")
+	}
+	if len(mediaTypes) == 0 {
+		return fmt.Errorf("no media types given for layer reader")
+	}
+	for _, mt := range mediaTypes {
+		if mt == "" {
+			return fmt.Errorf("empty media type given for layer reader")
+		}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, mt := range mediaTypes {
+		if _, ok := r.readers[mt]; ok {
+			log.WithFields("mediaType", mt).Debug("replacing existing layer reader")
+		}
+		r.readers[mt] = reader
+	}
+	return nil
+}
+
+func (r *layerReaderRegistry) get(mediaType types.MediaType) (LayerReader, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	reader, ok := r.readers[mediaType]
+	return reader, ok
+}
@@ -0,0 +1,91 @@
+package image
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	v1Types "github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wagoodman/go-progress"
+
+	"github.com/anchore/stereoscope/pkg/file"
+	"github.com/anchore/stereoscope/pkg/filetree"
+)
+
+func TestRegisterLayerReader_Validation(t *testing.T) {
+	registry := newLayerReaderRegistry()
+	noop := func(context.Context, *Layer, string, filetree.Writer, *FileCatalog, *progress.Manual) error {
+		return nil
+	}
+
+	require.ErrorContains(t, registry.register(nil, "application/vnd.example"), "no layer reader given")
+	require.ErrorContains(t, registry.register(noop), "no media types given")
+	require.ErrorContains(t, registry.register(noop, ""), "empty media type")
+
+	// the registry must not be partially updated when any of the media types are invalid
+	require.ErrorContains(t, registry.register(noop, "application/vnd.example", ""), "empty media type")
+	_, ok := registry.get("application/vnd.example")
+	assert.False(t, ok)
+}
+
+func TestRegisterLayerReader(t *testing.T) {
+	const customMediaType v1Types.MediaType = "application/vnd.example.layer.v1.custom"
+
+	original := layerReaders
+	t.Cleanup(func() {
+		layerReaders = original
+	})
+	layerReaders = newLayerReaderRegistry()
+
+	require.False(t, isSupportedLayerMediaType(customMediaType))
+
+	var gotContentPath string
+	reader := func(_ context.Context, l *Layer, contentPath string, tree filetree.Writer, catalog *FileCatalog, monitor *progress.Manual) error {
+		gotContentPath = contentPath
+		builder := filetree.NewBuilder(tree, catalog.Index)
+		metadata := file.Metadata{
+			FileInfo: file.ManualInfo{
+				NameValue: "custom.txt",
+				SizeValue: 8,
+			},
+			Path: "/custom.txt",
+			Type: file.TypeRegular,
+		}
+		ref, err := builder.Add(metadata)
+		if err != nil {
+			return err
+		}
+		catalog.AssociateLayer(*ref, l)
+		catalog.AssociateOpener(*ref, func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("contents")), nil
+		})
+		l.Metadata.Size += metadata.Size()
+		monitor.Increment()
+		return nil
+	}
+
+	require.NoError(t, RegisterLayerReader(reader, customMediaType))
+	require.True(t, isSupportedLayerMediaType(customMediaType))
+	require.NoError(t, validateLayerMediaTypes([]v1.Layer{fakeLayer(customMediaType, nil)}))
+
+	catalog := NewFileCatalog()
+	layer := NewLayer(fakeLayer(customMediaType, nil))
+	require.NoError(t, layer.Read(catalog, 0, t.TempDir()))
+
+	assert.NotEmpty(t, gotContentPath)
+	assert.Equal(t, int64(8), layer.Metadata.Size)
+	assert.True(t, layer.Tree.HasPath("/custom.txt"))
+
+	reader2, err := layer.OpenPath("/custom.txt")
+	require.NoError(t, err)
+	contents, err := io.ReadAll(reader2)
+	require.NoError(t, err)
+	assert.Equal(t, "contents", string(contents))
+	refs := layer.Tree.AllFiles()
+	require.Len(t, refs, 1)
+	assert.Equal(t, layer, catalog.Layer(refs[0]))
+}
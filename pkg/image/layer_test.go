@@ -1,8 +1,10 @@
 package image
 
 import (
+	"context"
 	"errors"
 	"io"
+	"os"
 	"strings"
 	"testing"
 
@@ -98,11 +100,47 @@ func TestUncompressedCacheClosesReader(t *testing.T) {
 	layer := Layer{layer: spy}
 	layer.Metadata.Digest = digest.String()
 
-	_, err = layer.uncompressedCache(t.TempDir())
+	_, err = layer.uncompressedCache(context.Background(), t.TempDir())
 	require.NoError(t, err)
 	require.True(t, spy.closed, "expected uncompressedCache to close the layer reader to release the pull-limiter token")
 }
 
+func TestUncompressedCacheCancelled(t *testing.T) {
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	layers, err := img.Layers()
+	require.NoError(t, err)
+	require.Len(t, layers, 1)
+
+	digest, err := layers[0].Digest()
+	require.NoError(t, err)
+
+	layer := Layer{layer: layers[0]}
+	layer.Metadata.Digest = digest.String()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cacheDir := t.TempDir()
+	_, err = layer.uncompressedCache(ctx, cacheDir)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// a partial cache entry must not be left behind, otherwise it would be used on the next read
+	entries, err := os.ReadDir(cacheDir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestReadCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	layer := Layer{layer: fakeLayer(v1Types.OCILayer, nil)}
+	err := layer.ReadWithContext(ctx, NewFileCatalog(), 0, t.TempDir())
+	require.ErrorIs(t, err, context.Canceled)
+}
+
 func TestRead(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -0,0 +1,242 @@
+package image
+
+import (
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+)
+
+const (
+	// LayerDiffID identifies the digest of the uncompressed layer content (as listed in the image config)
+	LayerDiffID LayerDigestKind = "diff-id"
+
+	// LayerBlobDigest identifies the digest of the compressed layer blob (as listed in the image manifest)
+	LayerBlobDigest LayerDigestKind = "digest"
+)
+
+// LayerDigestKind describes which digest of a layer failed verification.
+type LayerDigestKind string
+
+// LayerVerification describes which layer digests are verified while caching layer content. All content is hashed
+// as it is written to the layer cache, so verification does not require an additional read of the layer content.
+type LayerVerification struct {
+	// DiffIDs verifies that the uncompressed content of each layer hashes to the diff ID within the image config.
+	// Existing layer cache entries that were not written (or already verified) by this process are verified as well,
+	// which requires an additional read of the cached content.
+	DiffIDs bool
+	// Digests verifies that the compressed content of each layer hashes to the layer digest within the image
+	// manifest. This should only be enabled when the layer digests are not computed from the content itself.
+	Digests bool
+}
+
+// WithLayerVerification verifies the content of each layer while caching it. This only takes effect when given to New
+// or to a provider (which apply it before reading the image, after any provider defaults); additional metadata given
+// to stereoscope.GetImage is applied after the image is read, so use stereoscope.WithLayerVerification there instead.
+func WithLayerVerification(verification LayerVerification) AdditionalMetadata {
+	return func(image *Image) error {
+		image.layerVerification = verification
+		return nil
+	}
+}
+
+// ErrLayerDigestMismatch is returned when the content of a layer does not hash to the digest expected by the image
+// config (diff ID) or image manifest (layer digest), indicating tampered or corrupted content.
+type ErrLayerDigestMismatch struct {
+	// LayerIndex is the index of the offending layer within the image
+	LayerIndex uint
+	// Kind is the kind of digest that failed verification
+	Kind LayerDigestKind
+	// Expected is the digest expected by the image config or manifest
+	Expected string
+	// Actual is the digest of the layer content
+	Actual string
+}
+
+func (e *ErrLayerDigestMismatch) Error() string {
+	return fmt.Sprintf("layer %d %s mismatch: expected=%s actual=%s", e.LayerIndex, e.Kind, e.Expected, e.Actual)
+}
+
+// verifiedCaches records the layer cache files verified by this process (keyed by path), allowing verification of
+// existing cache entries to be skipped when the file has not changed since.
+var verifiedCaches sync.Map
+
+// cacheStamp identifies the state of a layer cache file when it was verified.
+type cacheStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+func newCacheStamp(path string) (cacheStamp, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return cacheStamp{}, err
+	}
+	return cacheStamp{size: fi.Size(), modTime: fi.ModTime()}, nil
+}
+
+// recordVerifiedCache records that the layer cache file at the given path hashes to the layer diff ID.
+func recordVerifiedCache(path string) error {
+	stamp, err := newCacheStamp(path)
+	if err != nil {
+		return err
+	}
+	verifiedCaches.Store(path, stamp)
+	return nil
+}
+
+// isVerifiedCache indicates that the layer cache file at the given path is unchanged since it was verified.
+func isVerifiedCache(path string) bool {
+	recorded, ok := verifiedCaches.Load(path)
+	if !ok {
+		return false
+	}
+	stamp, err := newCacheStamp(path)
+	return err == nil && stamp == recorded.(cacheStamp)
+}
+
+// digester hashes content with the algorithm of the expected digest.
+type digester struct {
+	expected v1.Hash
+	hash     hash.Hash
+}
+
+// diffIDDigester returns a digester for the layer diff ID, using the algorithm of the diff ID.
+func (l *Layer) diffIDDigester() (*digester, error) {
+	expected, err := v1.NewHash(l.Metadata.Digest)
+	if err != nil {
+		return nil, err
+	}
+	h, err := v1.Hasher(expected.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return &digester{expected: expected, hash: h}, nil
+}
+
+func (d *digester) actual() v1.Hash {
+	return v1.Hash{Algorithm: d.expected.Algorithm, Hex: fmt.Sprintf("%x", d.hash.Sum(nil))}
+}
+
+// digestingReader hashes all content read through it.
+type digestingReader struct {
+	io.ReadCloser
+	hash hash.Hash
+}
+
+func newDigestingReader(reader io.ReadCloser, algorithm string) (*digestingReader, error) {
+	h, err := v1.Hasher(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return &digestingReader{ReadCloser: reader, hash: h}, nil
+}
+
+func (r *digestingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	return n, err
+}
+
+// verify hashes any unread content and compares the digest of all content against the expected digest.
+func (r *digestingReader) verify(l *Layer, kind LayerDigestKind, expected v1.Hash) error {
+	// the decompressor may stop reading before the end of the stream (e.g. trailing padding), which must still be
+	// accounted for
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return fmt.Errorf("unable to read layer=%q: %w", l.Metadata.Digest, err)
+	}
+	return l.checkDigest(kind, expected, v1.Hash{Algorithm: expected.Algorithm, Hex: fmt.Sprintf("%x", r.hash.Sum(nil))})
+}
+
+// compressedStream presents the compressed content of a layer through the given reader, allowing the compressed
+// bytes to be hashed while they are decompressed.
+type compressedStream struct {
+	v1.Layer
+	reader io.ReadCloser
+}
+
+func (s compressedStream) Compressed() (io.ReadCloser, error) {
+	return s.reader, nil
+}
+
+// verifiedUncompressed opens the uncompressed content of the layer. When layer digests are verified, the compressed
+// content is hashed as it is decompressed and the returned function verifies the digest once all content is read.
+func (l *Layer) verifiedUncompressed() (io.ReadCloser, func() error, error) {
+	if !l.verification.Digests {
+		reader, err := l.layer.Uncompressed()
+		return reader, func() error { return nil }, err
+	}
+
+	expected, err := l.layer.Digest()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get digest for layer=%q: %w", l.Metadata.Digest, err)
+	}
+
+	compressed, err := l.layer.Compressed()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	digester, err := newDigestingReader(compressed, expected.Algorithm)
+	if err != nil {
+		compressed.Close()
+		return nil, nil, err
+	}
+
+	decompressor, err := partial.CompressedToLayer(compressedStream{Layer: l.layer, reader: digester})
+	if err != nil {
+		compressed.Close()
+		return nil, nil, err
+	}
+
+	reader, err := decompressor.Uncompressed()
+	if err != nil {
+		compressed.Close()
+		return nil, nil, err
+	}
+	return reader, func() error { return digester.verify(l, LayerBlobDigest, expected) }, nil
+}
+
+// verifyCachedContent confirms that existing layer cache content hashes to the layer diff ID. Content already
+// verified by this process is not hashed again (unless the file changed since).
+func (l *Layer) verifyCachedContent(path string) error {
+	if isVerifiedCache(path) {
+		return nil
+	}
+
+	diffID, err := l.diffIDDigester()
+	if err != nil {
+		return err
+	}
+
+	fh, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	if _, err := io.Copy(diffID.hash, fh); err != nil {
+		return err
+	}
+	if err := l.checkDigest(LayerDiffID, diffID.expected, diffID.actual()); err != nil {
+		return err
+	}
+	return recordVerifiedCache(path)
+}
+
+func (l *Layer) checkDigest(kind LayerDigestKind, expected, actual v1.Hash) error {
+	if expected == actual {
+		return nil
+	}
+	return &ErrLayerDigestMismatch{
+		LayerIndex: l.Metadata.Index,
+		Kind:       kind,
+		Expected:   expected.String(),
+		Actual:     actual.String(),
+	}
+}
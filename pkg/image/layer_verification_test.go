@@ -0,0 +1,183 @@
+package image
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var tamperedHash = v1.Hash{Algorithm: "sha256", Hex: "0000000000000000000000000000000000000000000000000000000000000000"}
+
+// tamperedLayer decorates a real v1.Layer, reporting digests that do not match the layer content.
+type tamperedLayer struct {
+	v1.Layer
+	digest *v1.Hash
+	diffID *v1.Hash
+}
+
+func (l tamperedLayer) Digest() (v1.Hash, error) {
+	if l.digest != nil {
+		return *l.digest, nil
+	}
+	return l.Layer.Digest()
+}
+
+func (l tamperedLayer) DiffID() (v1.Hash, error) {
+	if l.diffID != nil {
+		return *l.diffID, nil
+	}
+	return l.Layer.DiffID()
+}
+
+func randomLayer(t *testing.T) v1.Layer {
+	t.Helper()
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+
+	layers, err := img.Layers()
+	require.NoError(t, err)
+	require.Len(t, layers, 1)
+	return layers[0]
+}
+
+func newVerifiedLayer(t *testing.T, layer v1.Layer, verification LayerVerification) *Layer {
+	t.Helper()
+	diffID, err := layer.DiffID()
+	require.NoError(t, err)
+
+	l := NewLayer(layer)
+	l.Metadata.Index = 3
+	l.Metadata.Digest = diffID.String()
+	l.verification = verification
+	return l
+}
+
+func TestUncompressedCache_Verification(t *testing.T) {
+	all := LayerVerification{DiffIDs: true, Digests: true}
+
+	tests := []struct {
+		name         string
+		digest       *v1.Hash
+		diffID       *v1.Hash
+		verification LayerVerification
+		wantKind     LayerDigestKind
+	}{
+		{
+			name:         "valid layer",
+			verification: all,
+		},
+		{
+			name:         "tampered diff ID",
+			diffID:       &tamperedHash,
+			verification: all,
+			wantKind:     LayerDiffID,
+		},
+		{
+			name:         "tampered digest",
+			digest:       &tamperedHash,
+			verification: all,
+			wantKind:     LayerBlobDigest,
+		},
+		{
+			name:         "tampered digest without digest verification",
+			digest:       &tamperedHash,
+			verification: LayerVerification{DiffIDs: true},
+		},
+		{
+			name:         "tampered diff ID without verification",
+			diffID:       &tamperedHash,
+			verification: LayerVerification{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layer := newVerifiedLayer(t, tamperedLayer{Layer: randomLayer(t), digest: tt.digest, diffID: tt.diffID}, tt.verification)
+
+			cacheDir := t.TempDir()
+			path, err := layer.uncompressedCache(context.Background(), cacheDir)
+			if tt.wantKind == "" {
+				require.NoError(t, err)
+				assert.FileExists(t, path)
+				return
+			}
+
+			var mismatch *ErrLayerDigestMismatch
+			require.ErrorAs(t, err, &mismatch)
+			assert.Equal(t, uint(3), mismatch.LayerIndex)
+			assert.Equal(t, tt.wantKind, mismatch.Kind)
+			assert.Equal(t, tamperedHash.String(), mismatch.Expected)
+			assert.NotEqual(t, mismatch.Expected, mismatch.Actual)
+
+			// unverified content must not be left behind, otherwise it would be used on the next read
+			entries, err := os.ReadDir(cacheDir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
+
+func TestUncompressedCache_CorruptCache(t *testing.T) {
+	tests := []struct {
+		name         string
+		verification LayerVerification
+		wantCorrupt  bool
+	}{
+		{
+			name:         "corrupt cache is replaced",
+			verification: LayerVerification{DiffIDs: true},
+		},
+		{
+			name:        "corrupt cache is used without verification",
+			wantCorrupt: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layer := newVerifiedLayer(t, randomLayer(t), tt.verification)
+
+			cacheDir := t.TempDir()
+			corrupt := []byte("corrupt")
+			require.NoError(t, os.WriteFile(filepath.Join(cacheDir, layer.Metadata.Digest), corrupt, 0o600))
+
+			path, err := layer.uncompressedCache(context.Background(), cacheDir)
+			require.NoError(t, err)
+
+			contents, err := os.ReadFile(path)
+			require.NoError(t, err)
+			if tt.wantCorrupt {
+				assert.Equal(t, corrupt, contents)
+				return
+			}
+			require.NoError(t, layer.verifyCachedContent(path))
+		})
+	}
+}
+
+func TestUncompressedCache_VerifiedCacheNotRehashed(t *testing.T) {
+	layer := newVerifiedLayer(t, randomLayer(t), LayerVerification{DiffIDs: true})
+
+	path, err := layer.uncompressedCache(context.Background(), t.TempDir())
+	require.NoError(t, err)
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+
+	// replace the content without changing the size or modification time, which is only detected by hashing
+	require.NoError(t, os.WriteFile(path, make([]byte, fi.Size()), 0o600))
+	require.NoError(t, os.Chtimes(path, fi.ModTime(), fi.ModTime()))
+	require.NoError(t, layer.verifyCachedContent(path), "content verified by this process should not be hashed again")
+
+	// any change to the file since verification requires the content to be hashed again
+	later := fi.ModTime().Add(time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+	var mismatch *ErrLayerDigestMismatch
+	require.ErrorAs(t, layer.verifyCachedContent(path), &mismatch)
+	assert.Equal(t, LayerDiffID, mismatch.Kind)
+}
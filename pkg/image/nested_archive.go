@@ -0,0 +1,667 @@
+package image
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/anchore/stereoscope/internal/log"
+	"github.com/anchore/stereoscope/pkg/file"
+	"github.com/anchore/stereoscope/pkg/filetree"
+)
+
+// defaultNestedArchiveMaxDepth is the maximum depth of nested archives when no depth is configured.
+const defaultNestedArchiveMaxDepth = 3
+
+// ErrUnsupportedArchive is returned when mounting a file that is not a supported archive format (zip-based archives
+// such as jar, war, ear, and wheel files, or tar files that are optionally gzip compressed).
+var ErrUnsupportedArchive = errors.New("unsupported archive format")
+
+// NestedArchiveLimit identifies a single resource limit enforced while mounting nested archives.
+type NestedArchiveLimit string
+
+const (
+	// NestedArchiveDepthLimit bounds the number of archives containing an archive (including itself).
+	NestedArchiveDepthLimit NestedArchiveLimit = "depth"
+	// NestedArchiveBytesLimit bounds the size of a single archive file.
+	NestedArchiveBytesLimit NestedArchiveLimit = "archive-bytes"
+	// NestedArchiveEntriesLimit bounds the number of entries within a single archive.
+	NestedArchiveEntriesLimit NestedArchiveLimit = "entries"
+	// NestedArchiveUncompressedBytesLimit bounds the uncompressed size of all entries within a single archive.
+	NestedArchiveUncompressedBytesLimit NestedArchiveLimit = "uncompressed-bytes"
+)
+
+// NestedArchiveConfig bounds the resources consumed while mounting nested archives. A zero value for any byte or
+// entry limit means that limit is not enforced.
+type NestedArchiveConfig struct {
+	// MaxDepth is the maximum number of archives containing any mounted archive, including itself (a depth of 1
+	// allows mounting archives within the image filesystem only). Defaults to 3 when zero.
+	MaxDepth int
+	// MaxArchiveBytes is the maximum size of a single archive file
+	MaxArchiveBytes int64
+	// MaxEntries is the maximum number of entries within a single archive
+	MaxEntries int
+	// MaxUncompressedBytes is the maximum uncompressed size of all entries within a single archive
+	MaxUncompressedBytes int64
+}
+
+// DefaultNestedArchiveConfig returns a NestedArchiveConfig with conservative limits.
+func DefaultNestedArchiveConfig() NestedArchiveConfig {
+	return NestedArchiveConfig{
+		MaxDepth:             defaultNestedArchiveMaxDepth,
+		MaxArchiveBytes:      1 << 30, // 1 GiB
+		MaxEntries:           100_000,
+		MaxUncompressedBytes: 4 << 30, // 4 GiB
+	}
+}
+
+// ErrNestedArchiveLimitExceeded is returned when mounting a nested archive exceeds one of the configured
+// NestedArchiveConfig limits.
+type ErrNestedArchiveLimitExceeded struct {
+	// Limit is the limit that was exceeded
+	Limit NestedArchiveLimit
+	// Path is the nested path of the archive being mounted
+	Path file.Path
+	// Value is the observed value that exceeded the limit
+	Value int64
+	// Max is the maximum allowed value
+	Max int64
+}
+
+func (e *ErrNestedArchiveLimitExceeded) Error() string {
+	return fmt.Sprintf("nested archive limit %q exceeded (archive=%q): %d > %d", e.Limit, e.Path, e.Value, e.Max)
+}
+
+// NestedArchive is an archive file found within an image (or within another nested archive), mounted as a file tree
+// with its own index and content openers.
+type NestedArchive struct {
+	// Resolution is the archive file within the containing file tree
+	Resolution file.Resolution
+	// Parent is the archive containing this archive (nil when this archive is within the image filesystem)
+	Parent *NestedArchive
+	// Depth is the number of archives containing this archive, including itself (starting at 1)
+	Depth int
+	// Tree is the file tree of all entries within the archive
+	Tree *filetree.FileTree
+	// FileCatalog contains the metadata and content openers for all entries within the archive. Entries are
+	// attributed to the image layer providing the outermost archive.
+	FileCatalog *FileCatalog
+	// SearchContext allows for searching for entries within the archive
+	SearchContext filetree.Searcher
+
+	// chain is the archive chain for all entries within this archive
+	chain  []file.Resolution
+	reader file.SeekableReader
+}
+
+// NestedArchives mounts archives found within a file tree (and archives within those archives) on demand, allowing
+// for path and glob searches that descend into archives. Nested paths are separated by file.NestedArchiveSeparator,
+// for example "/app/lib/foo.jar!/META-INF/MANIFEST.MF". Close must be called to release all mounted archives.
+type NestedArchives struct {
+	tree    filetree.Reader
+	catalog FileCatalogReader
+	config  NestedArchiveConfig
+	lock    sync.Mutex
+	// mounts are all mounted archives, keyed by the ID of the archive file reference
+	mounts map[file.ID]*NestedArchive
+	// spool is where archive contents are copied when random access is needed (shared with the given catalog)
+	spool *spoolDir
+}
+
+// NestedArchives returns a NestedArchives for mounting archives found within the image squash tree.
+func (i *Image) NestedArchives(config NestedArchiveConfig) *NestedArchives {
+	return NewNestedArchives(i.SquashedTree(), i.FileCatalog, config)
+}
+
+// NewNestedArchives creates a NestedArchives for mounting archives found within the given tree, fetching archive
+// contents from the given catalog.
+func NewNestedArchives(tree filetree.Reader, catalog FileCatalogReader, config NestedArchiveConfig) *NestedArchives {
+	if config.MaxDepth <= 0 {
+		config.MaxDepth = defaultNestedArchiveMaxDepth
+	}
+	var spool *spoolDir
+	if c, ok := catalog.(*FileCatalog); ok {
+		c.RLock()
+		spool = c.spool
+		c.RUnlock()
+	}
+	return &NestedArchives{
+		tree:    tree,
+		catalog: catalog,
+		config:  config,
+		mounts:  make(map[file.ID]*NestedArchive),
+		spool:   spool,
+	}
+}
+
+// File resolves the given nested path (e.g. "/app/lib/foo.jar!/META-INF/MANIFEST.MF"), mounting all archives along
+// the path as needed. Paths without a separator are resolved relative to the root tree.
+func (n *NestedArchives) File(p file.Path) (bool, *file.Resolution, error) {
+	segments := splitNestedPath(string(p))
+
+	tree := n.tree
+	var parent *NestedArchive
+	for idx, segment := range segments {
+		exists, resolution, err := tree.File(file.Path(segment), filetree.FollowBasenameLinks)
+		if err != nil {
+			return false, nil, err
+		}
+		if !exists || !resolution.HasReference() {
+			return false, nil, nil
+		}
+		if parent != nil {
+			resolution.ArchiveChain = parent.chain
+		}
+		if idx == len(segments)-1 {
+			return true, resolution, nil
+		}
+
+		archive, err := n.mount(*resolution, parent)
+		if err != nil {
+			return false, nil, err
+		}
+		tree = archive.Tree
+		parent = archive
+	}
+	return false, nil, nil
+}
+
+// FilesByGlob returns all files matching the given nested glob pattern (e.g. "/app/**/*.jar!/META-INF/*.MF"),
+// mounting all matching archives as needed. Matching files that are not supported archives, or that exceed any
+// configured limit, are skipped.
+func (n *NestedArchives) FilesByGlob(pattern string) ([]file.Resolution, error) {
+	return n.filesByGlob(n.tree, nil, splitNestedPath(pattern))
+}
+
+func (n *NestedArchives) filesByGlob(tree filetree.Reader, parent *NestedArchive, segments []string) ([]file.Resolution, error) {
+	resolutions, err := tree.FilesByGlob(segments[0], filetree.FollowBasenameLinks)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(segments) == 1 {
+		if parent != nil {
+			for idx := range resolutions {
+				resolutions[idx].ArchiveChain = parent.chain
+			}
+		}
+		return resolutions, nil
+	}
+
+	var results []file.Resolution
+	for _, resolution := range resolutions {
+		if !resolution.HasReference() {
+			continue
+		}
+		if parent != nil {
+			resolution.ArchiveChain = parent.chain
+		}
+
+		archive, err := n.mount(resolution, parent)
+		if err != nil {
+			var limitErr *ErrNestedArchiveLimitExceeded
+			if errors.Is(err, ErrUnsupportedArchive) || errors.As(err, &limitErr) {
+				log.WithFields("path", resolution.NestedRequestPath(), "error", err).Debug("skipping nested archive")
+				continue
+			}
+			return nil, err
+		}
+
+		children, err := n.filesByGlob(archive.Tree, archive, segments[1:])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, children...)
+	}
+	return results, nil
+}
+
+// Mount mounts the archive at the given nested path (e.g. "/app/app.war!/WEB-INF/lib/foo.jar").
+func (n *NestedArchives) Mount(p file.Path) (*NestedArchive, error) {
+	exists, resolution, err := n.File(p)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("could not find archive path: %s", p)
+	}
+
+	var parent *NestedArchive
+	if len(resolution.ArchiveChain) > 0 {
+		parent = n.mountedArchive(resolution.ArchiveChain[len(resolution.ArchiveChain)-1])
+	}
+	return n.mount(*resolution, parent)
+}
+
+// Open returns the contents of the given file, as returned from File or FilesByGlob.
+func (n *NestedArchives) Open(resolution file.Resolution) (io.ReadCloser, error) {
+	if !resolution.HasReference() {
+		return nil, fmt.Errorf("no file reference for path: %s", resolution.RequestPath)
+	}
+	if len(resolution.ArchiveChain) == 0 {
+		return n.catalog.Open(*resolution.Reference)
+	}
+
+	archive := n.mountedArchive(resolution.ArchiveChain[len(resolution.ArchiveChain)-1])
+	if archive == nil {
+		return nil, fmt.Errorf("archive is not mounted for path: %s", resolution.NestedRequestPath())
+	}
+	return archive.FileCatalog.Open(*resolution.Reference)
+}
+
+// Close releases all mounted archives. Contents of mounted archives can no longer be read after this call.
+func (n *NestedArchives) Close() error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	var errs []error
+	for id, archive := range n.mounts {
+		if err := archive.reader.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(n.mounts, id)
+	}
+	return errors.Join(errs...)
+}
+
+func (n *NestedArchives) mountedArchive(resolution file.Resolution) *NestedArchive {
+	if !resolution.HasReference() {
+		return nil
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.mounts[resolution.ID()]
+}
+
+// mount mounts the archive for the given resolution within the parent archive (or the root tree when nil).
+func (n *NestedArchives) mount(resolution file.Resolution, parent *NestedArchive) (*NestedArchive, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if archive, ok := n.mounts[resolution.ID()]; ok {
+		return archive, nil
+	}
+
+	depth := 1
+	var catalog FileCatalogReader = n.catalog
+	if parent != nil {
+		depth = parent.Depth + 1
+		catalog = parent.FileCatalog
+	}
+
+	nestedPath := resolution.NestedRequestPath()
+	if depth > n.config.MaxDepth {
+		return nil, n.limitExceeded(NestedArchiveDepthLimit, nestedPath, int64(depth), int64(n.config.MaxDepth))
+	}
+
+	if entry, err := catalog.Get(*resolution.Reference); err == nil && entry.Type != file.TypeRegular {
+		return nil, fmt.Errorf("%w: %s is not a regular file", ErrUnsupportedArchive, nestedPath)
+	}
+
+	reader, err := openSeekable(catalog, *resolution.Reference)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open archive %q: %w", nestedPath, err)
+	}
+
+	archive := &NestedArchive{
+		Resolution:  resolution,
+		Parent:      parent,
+		Depth:       depth,
+		Tree:        filetree.New(),
+		FileCatalog: NewFileCatalog(),
+		chain:       append(append([]file.Resolution{}, resolution.ArchiveChain...), archiveLink(resolution)),
+	}
+	archive.FileCatalog.spool = n.spool
+	archive.SearchContext = filetree.NewSearchContext(archive.Tree, archive.FileCatalog.Index)
+
+	reader, err = n.index(archive, catalog.Layer(*resolution.Reference), reader)
+	if err != nil {
+		return nil, err
+	}
+	archive.reader = reader
+
+	n.mounts[resolution.ID()] = archive
+	return archive, nil
+}
+
+// index adds all entries of the archive to the archive tree and catalog, returning the reader that backs the
+// contents of all entries (which may differ from the given reader when the archive is compressed).
+func (n *NestedArchives) index(archive *NestedArchive, layer *Layer, reader file.SeekableReader) (file.SeekableReader, error) {
+	nestedPath := archive.Resolution.NestedRequestPath()
+	if n.config.MaxArchiveBytes > 0 && reader.Size() > n.config.MaxArchiveBytes {
+		_ = reader.Close()
+		return nil, n.limitExceeded(NestedArchiveBytesLimit, nestedPath, reader.Size(), n.config.MaxArchiveBytes)
+	}
+
+	var err error
+	switch archiveFormat(reader) {
+	case zipArchiveFormat:
+		err = n.indexZip(archive, layer, reader)
+	case gzipArchiveFormat:
+		reader, err = n.decompressGzip(nestedPath, reader)
+		if err != nil {
+			return nil, err
+		}
+		if archiveFormat(reader) != tarArchiveFormat {
+			_ = reader.Close()
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedArchive, nestedPath)
+		}
+		err = n.indexTar(archive, layer, reader)
+	case tarArchiveFormat:
+		err = n.indexTar(archive, layer, reader)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnsupportedArchive, nestedPath)
+	}
+	if err != nil {
+		_ = reader.Close()
+		return nil, err
+	}
+	return reader, nil
+}
+
+func (n *NestedArchives) indexZip(archive *NestedArchive, layer *Layer, reader file.SeekableReader) error {
+	nestedPath := archive.Resolution.NestedRequestPath()
+	zr, err := zip.NewReader(reader, reader.Size())
+	if err != nil {
+		return fmt.Errorf("unable to read zip archive %q: %w", nestedPath, err)
+	}
+
+	if err := n.checkEntries(nestedPath, len(zr.File)); err != nil {
+		return err
+	}
+
+	builder := filetree.NewBuilder(archive.Tree, archive.FileCatalog.Index)
+	tracker := n.newUncompressedBytesTracker(nestedPath)
+	var uncompressed int64
+	for idx, f := range zr.File {
+		// note: the sizes declared within the zip headers are only used to reject archives early, the limit is
+		// enforced on the bytes actually read from the entries (see uncompressedBytesTracker)
+		uncompressed += int64(f.UncompressedSize64)
+		if err := n.checkUncompressedBytes(nestedPath, uncompressed); err != nil {
+			return err
+		}
+
+		info := f.FileInfo()
+		metadata := file.Metadata{
+			FileInfo: info,
+			Path:     path.Clean(file.DirSeparator + f.Name),
+			Type:     file.TypeFromMode(info.Mode()),
+		}
+
+		switch metadata.Type {
+		case file.TypeSymLink:
+			destination, err := readZipEntry(f, 4096)
+			if err != nil {
+				return fmt.Errorf("unable to read symlink %q in zip archive %q: %w", f.Name, nestedPath, err)
+			}
+			metadata.LinkDestination = string(destination)
+		case file.TypeRegular:
+			metadata.MIMEType = zipEntryMIMEType(f)
+		}
+
+		ref, err := builder.Add(metadata)
+		if err != nil {
+			return err
+		}
+		archive.FileCatalog.Add(*ref, metadata, layer, tracker.opener(idx, f.Open))
+	}
+	return nil
+}
+
+func (n *NestedArchives) indexTar(archive *NestedArchive, layer *Layer, reader file.SeekableReader) error {
+	nestedPath := archive.Resolution.NestedRequestPath()
+	section := io.NewSectionReader(reader, 0, reader.Size())
+	tr := tar.NewReader(section)
+	builder := filetree.NewBuilder(archive.Tree, archive.FileCatalog.Index)
+
+	var entries int
+	var uncompressed int64
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read tar archive %q: %w", nestedPath, err)
+		}
+
+		entries++
+		if err := n.checkEntries(nestedPath, entries); err != nil {
+			return err
+		}
+		uncompressed += header.Size
+		if err := n.checkUncompressedBytes(nestedPath, uncompressed); err != nil {
+			return err
+		}
+
+		// the tar reader is positioned at the start of the entry contents after reading the header
+		offset, err := section.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		contents := io.NewSectionReader(reader, offset, header.Size)
+
+		metadata := file.NewMetadata(*header, contents)
+		ref, err := builder.Add(metadata)
+		if err != nil {
+			return err
+		}
+		archive.FileCatalog.Add(*ref, metadata, layer, func() (io.ReadCloser, error) {
+			return io.NopCloser(io.NewSectionReader(reader, offset, header.Size)), nil
+		})
+	}
+}
+
+// decompressGzip returns a seekable reader for the decompressed contents of the given reader (closing the given reader).
+func (n *NestedArchives) decompressGzip(nestedPath file.Path, reader file.SeekableReader) (file.SeekableReader, error) {
+	defer reader.Close()
+
+	gz, err := gzip.NewReader(io.NewSectionReader(reader, 0, reader.Size()))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read gzip archive %q: %w", nestedPath, err)
+	}
+
+	var contents io.Reader = gz
+	if n.config.MaxUncompressedBytes > 0 {
+		contents = &boundedReader{
+			reader:    gz,
+			remaining: n.config.MaxUncompressedBytes,
+			err:       n.limitExceeded(NestedArchiveUncompressedBytesLimit, nestedPath, n.config.MaxUncompressedBytes+1, n.config.MaxUncompressedBytes),
+		}
+	}
+
+	tempDir, err := n.spool.get()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create spool directory: %w", err)
+	}
+
+	decompressed, err := file.NewSeekableReader(io.NopCloser(contents), -1, tempDir)
+	if err != nil {
+		return nil, err
+	}
+	return decompressed, gz.Close()
+}
+
+func (n *NestedArchives) checkEntries(nestedPath file.Path, entries int) error {
+	if n.config.MaxEntries > 0 && entries > n.config.MaxEntries {
+		return n.limitExceeded(NestedArchiveEntriesLimit, nestedPath, int64(entries), int64(n.config.MaxEntries))
+	}
+	return nil
+}
+
+func (n *NestedArchives) checkUncompressedBytes(nestedPath file.Path, size int64) error {
+	if n.config.MaxUncompressedBytes > 0 && size > n.config.MaxUncompressedBytes {
+		return n.limitExceeded(NestedArchiveUncompressedBytesLimit, nestedPath, size, n.config.MaxUncompressedBytes)
+	}
+	return nil
+}
+
+func (n *NestedArchives) newUncompressedBytesTracker(nestedPath file.Path) *uncompressedBytesTracker {
+	return &uncompressedBytesTracker{
+		maxBytes: n.config.MaxUncompressedBytes,
+		read:     make(map[int]int64),
+		exceeded: func(value int64) error {
+			return n.limitExceeded(NestedArchiveUncompressedBytesLimit, nestedPath, value, n.config.MaxUncompressedBytes)
+		},
+	}
+}
+
+func (n *NestedArchives) limitExceeded(limit NestedArchiveLimit, nestedPath file.Path, value, maxValue int64) *ErrNestedArchiveLimitExceeded {
+	return &ErrNestedArchiveLimitExceeded{
+		Limit: limit,
+		Path:  nestedPath,
+		Value: value,
+		Max:   maxValue,
+	}
+}
+
+// archiveLink returns the given archive resolution as an element of an archive chain.
+func archiveLink(resolution file.Resolution) file.Resolution {
+	return file.Resolution{
+		RequestPath:     resolution.RequestPath,
+		Reference:       resolution.Reference,
+		LinkResolutions: resolution.LinkResolutions,
+	}
+}
+
+// splitNestedPath splits a nested path into the path (or glob) for each containing archive followed by the path
+// within the innermost archive.
+func splitNestedPath(p string) []string {
+	segments := strings.Split(p, file.NestedArchiveSeparator+file.DirSeparator)
+	for idx := 1; idx < len(segments); idx++ {
+		segments[idx] = file.DirSeparator + segments[idx]
+	}
+	return segments
+}
+
+type nestedArchiveFormat int
+
+const (
+	unknownArchiveFormat nestedArchiveFormat = iota
+	zipArchiveFormat
+	gzipArchiveFormat
+	tarArchiveFormat
+)
+
+// archiveFormat determines the archive format from the leading bytes of the given contents.
+func archiveFormat(r io.ReaderAt) nestedArchiveFormat {
+	header := make([]byte, 262)
+	n, _ := r.ReadAt(header, 0)
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")), bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return zipArchiveFormat
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return gzipArchiveFormat
+	case len(header) >= 262 && bytes.Equal(header[257:262], []byte("ustar")):
+		return tarArchiveFormat
+	}
+	return unknownArchiveFormat
+}
+
+func readZipEntry(f *zip.File, maxBytes int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(io.LimitReader(rc, maxBytes))
+}
+
+func zipEntryMIMEType(f *zip.File) string {
+	rc, err := f.Open()
+	if err != nil {
+		log.WithFields("path", f.Name, "error", err).Trace("unable to determine MIME type of zip entry")
+		return ""
+	}
+	defer rc.Close()
+	return file.MIMEType(rc)
+}
+
+// uncompressedBytesTracker enforces the uncompressed bytes limit of an archive on the bytes actually read from its
+// entries, since the entry sizes declared by the archive cannot be trusted. Reading the same entry content more than
+// once is only counted once.
+type uncompressedBytesTracker struct {
+	lock     sync.Mutex
+	maxBytes int64
+	total    int64
+	// read is the furthest offset read for each entry (keyed by entry index)
+	read     map[int]int64
+	exceeded func(value int64) error
+}
+
+// opener wraps the given entry opener such that reads fail once the archive limit is exceeded.
+func (t *uncompressedBytesTracker) opener(entry int, opener file.Opener) file.Opener {
+	if t.maxBytes <= 0 {
+		return opener
+	}
+	return func() (io.ReadCloser, error) {
+		rc, err := opener()
+		if err != nil {
+			return nil, err
+		}
+		return &trackedReader{ReadCloser: rc, tracker: t, entry: entry}, nil
+	}
+}
+
+// add records that the given entry has been read up to the given offset.
+func (t *uncompressedBytesTracker) add(entry int, offset int64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if prev := t.read[entry]; offset > prev {
+		t.total += offset - prev
+		t.read[entry] = offset
+	}
+	if t.total > t.maxBytes {
+		return t.exceeded(t.total)
+	}
+	return nil
+}
+
+// trackedReader reports the bytes read from a single archive entry to an uncompressedBytesTracker.
+type trackedReader struct {
+	io.ReadCloser
+	tracker *uncompressedBytesTracker
+	entry   int
+	offset  int64
+}
+
+func (r *trackedReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.offset += int64(n)
+	if limitErr := r.tracker.add(r.entry, r.offset); limitErr != nil {
+		return n, limitErr
+	}
+	return n, err
+}
+
+// boundedReader is a reader that returns the given error once more than the remaining number of bytes are read.
+type boundedReader struct {
+	reader    io.Reader
+	remaining int64
+	err       error
+}
+
+func (r *boundedReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, r.err
+	}
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n, r.err
+	}
+	return n, err
+}
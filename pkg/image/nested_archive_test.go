@@ -0,0 +1,304 @@
+package image
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anchore/stereoscope/pkg/file"
+)
+
+func zipArchive(t *testing.T, files map[string]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, contents := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.String()
+}
+
+func tarGzArchive(t *testing.T, files map[string]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, contents := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(contents)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.String()
+}
+
+func nestedArchiveTestImage(t *testing.T) *Image {
+	t.Helper()
+	inner := zipArchive(t, map[string]string{
+		"inner.txt": "innermost contents",
+	})
+	jar := zipArchive(t, map[string]string{
+		"META-INF/MANIFEST.MF": "Manifest-Version: 1.0\n",
+		"lib/inner.zip":        inner,
+	})
+	img, err := readTestImage(t, []v1.Layer{
+		tarLayer(t, map[string]string{
+			"app/plain.txt": "not an archive",
+		}),
+		tarLayer(t, map[string]string{
+			"app/lib/foo.jar": jar,
+			"app/data.tar.gz": tarGzArchive(t, map[string]string{"etc/config": "key=value\n"}),
+		}),
+	})
+	require.NoError(t, err)
+	return img
+}
+
+func TestNestedArchives_File(t *testing.T) {
+	img := nestedArchiveTestImage(t)
+	archives := img.NestedArchives(DefaultNestedArchiveConfig())
+	t.Cleanup(func() {
+		require.NoError(t, archives.Close())
+	})
+
+	tests := []struct {
+		name      string
+		path      file.Path
+		want      string
+		wantChain []file.Path
+		wantErr   require.ErrorAssertionFunc
+		wantNil   bool
+	}{
+		{
+			name: "not nested",
+			path: "/app/plain.txt",
+			want: "not an archive",
+		},
+		{
+			name:      "zip entry",
+			path:      "/app/lib/foo.jar!/META-INF/MANIFEST.MF",
+			want:      "Manifest-Version: 1.0\n",
+			wantChain: []file.Path{"/app/lib/foo.jar"},
+		},
+		{
+			name:      "zip within zip",
+			path:      "/app/lib/foo.jar!/lib/inner.zip!/inner.txt",
+			want:      "innermost contents",
+			wantChain: []file.Path{"/app/lib/foo.jar", "/lib/inner.zip"},
+		},
+		{
+			name:      "gzip compressed tar entry",
+			path:      "/app/data.tar.gz!/etc/config",
+			want:      "key=value\n",
+			wantChain: []file.Path{"/app/data.tar.gz"},
+		},
+		{
+			name:    "missing entry",
+			path:    "/app/lib/foo.jar!/missing",
+			wantNil: true,
+		},
+		{
+			name:    "not an archive",
+			path:    "/app/plain.txt!/something",
+			wantErr: func(t require.TestingT, err error, _ ...interface{}) { require.ErrorIs(t, err, ErrUnsupportedArchive) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr == nil {
+				tt.wantErr = require.NoError
+			}
+			exists, resolution, err := archives.File(tt.path)
+			tt.wantErr(t, err)
+			if err != nil {
+				return
+			}
+			if tt.wantNil {
+				assert.False(t, exists)
+				return
+			}
+			require.True(t, exists)
+			assert.Equal(t, tt.path, resolution.NestedRequestPath())
+
+			var chain []file.Path
+			for _, link := range resolution.ArchiveChain {
+				chain = append(chain, link.RequestPath)
+			}
+			assert.Equal(t, tt.wantChain, chain)
+
+			rc, err := archives.Open(*resolution)
+			require.NoError(t, err)
+			defer rc.Close()
+			contents, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(contents))
+		})
+	}
+}
+
+func TestNestedArchives_FilesByGlob(t *testing.T) {
+	img := nestedArchiveTestImage(t)
+	archives := img.NestedArchives(DefaultNestedArchiveConfig())
+	t.Cleanup(func() {
+		require.NoError(t, archives.Close())
+	})
+
+	resolutions, err := archives.FilesByGlob("/app/**!/**/*.txt")
+	require.NoError(t, err)
+	assert.Empty(t, resolutions, "text files only exist within the innermost archive")
+
+	resolutions, err = archives.FilesByGlob("/app/**/*.jar!/**/*.zip!/*.txt")
+	require.NoError(t, err)
+	require.Len(t, resolutions, 1)
+	assert.Equal(t, file.Path("/app/lib/foo.jar!/lib/inner.zip!/inner.txt"), resolutions[0].NestedRequestPath())
+
+	// non-archives matching the outer glob are skipped
+	resolutions, err = archives.FilesByGlob("/app/*!/**/config")
+	require.NoError(t, err)
+	require.Len(t, resolutions, 1)
+	assert.Equal(t, file.Path("/app/data.tar.gz!/etc/config"), resolutions[0].NestedRequestPath())
+}
+
+func TestNestedArchives_Mount(t *testing.T) {
+	img := nestedArchiveTestImage(t)
+	archives := img.NestedArchives(DefaultNestedArchiveConfig())
+	t.Cleanup(func() {
+		require.NoError(t, archives.Close())
+	})
+
+	archive, err := archives.Mount("/app/lib/foo.jar!/lib/inner.zip")
+	require.NoError(t, err)
+	assert.Equal(t, 2, archive.Depth)
+	require.NotNil(t, archive.Parent)
+	assert.Equal(t, file.Path("/app/lib/foo.jar"), archive.Parent.Resolution.RequestPath)
+	assert.ElementsMatch(t, []file.Path{"/", "/inner.txt"}, archive.Tree.AllRealPaths())
+
+	// entries are attributed to the layer providing the outermost archive
+	_, ref, err := archive.Tree.File("/inner.txt")
+	require.NoError(t, err)
+	assert.Equal(t, img.Layers[1], archive.FileCatalog.Layer(*ref.Reference))
+
+	// mounts are reused
+	again, err := archives.Mount("/app/lib/foo.jar!/lib/inner.zip")
+	require.NoError(t, err)
+	assert.Same(t, archive, again)
+}
+
+func TestNestedArchives_Limits(t *testing.T) {
+	img := nestedArchiveTestImage(t)
+
+	tests := []struct {
+		name      string
+		config    NestedArchiveConfig
+		path      file.Path
+		wantLimit NestedArchiveLimit
+	}{
+		{
+			name:      "depth",
+			config:    NestedArchiveConfig{MaxDepth: 1},
+			path:      "/app/lib/foo.jar!/lib/inner.zip!/inner.txt",
+			wantLimit: NestedArchiveDepthLimit,
+		},
+		{
+			name:      "archive bytes",
+			config:    NestedArchiveConfig{MaxArchiveBytes: 10},
+			path:      "/app/lib/foo.jar!/META-INF/MANIFEST.MF",
+			wantLimit: NestedArchiveBytesLimit,
+		},
+		{
+			name:      "entries",
+			config:    NestedArchiveConfig{MaxEntries: 1},
+			path:      "/app/lib/foo.jar!/META-INF/MANIFEST.MF",
+			wantLimit: NestedArchiveEntriesLimit,
+		},
+		{
+			name:      "uncompressed zip bytes",
+			config:    NestedArchiveConfig{MaxUncompressedBytes: 5},
+			path:      "/app/lib/foo.jar!/META-INF/MANIFEST.MF",
+			wantLimit: NestedArchiveUncompressedBytesLimit,
+		},
+		{
+			name:      "uncompressed gzip bytes",
+			config:    NestedArchiveConfig{MaxUncompressedBytes: 100},
+			path:      "/app/data.tar.gz!/etc/config",
+			wantLimit: NestedArchiveUncompressedBytesLimit,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archives := img.NestedArchives(tt.config)
+			t.Cleanup(func() {
+				require.NoError(t, archives.Close())
+			})
+
+			_, _, err := archives.File(tt.path)
+			var limitErr *ErrNestedArchiveLimitExceeded
+			require.ErrorAs(t, err, &limitErr)
+			assert.Equal(t, tt.wantLimit, limitErr.Limit)
+		})
+	}
+}
+
+func Test_uncompressedBytesTracker(t *testing.T) {
+	archives := NewNestedArchives(nil, nil, NestedArchiveConfig{MaxUncompressedBytes: 10})
+	tracker := archives.newUncompressedBytesTracker("/archive.zip")
+
+	open := func(entry int, contents string) io.ReadCloser {
+		opener := tracker.opener(entry, func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader([]byte(contents))), nil
+		})
+		rc, err := opener()
+		require.NoError(t, err)
+		return rc
+	}
+
+	_, err := io.ReadAll(open(0, "123456"))
+	require.NoError(t, err)
+
+	// reading the same entry again is not counted twice
+	_, err = io.ReadAll(open(0, "123456"))
+	require.NoError(t, err)
+
+	// the limit applies to the bytes read across all entries, regardless of any declared entry sizes
+	_, err = io.ReadAll(open(1, "123456"))
+	var limitErr *ErrNestedArchiveLimitExceeded
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, NestedArchiveUncompressedBytesLimit, limitErr.Limit)
+	assert.Equal(t, file.Path("/archive.zip"), limitErr.Path)
+}
+
+func TestNestedArchives_SpoolsWithinImageTempDir(t *testing.T) {
+	img := nestedArchiveTestImage(t)
+	archives := img.NestedArchives(DefaultNestedArchiveConfig())
+	t.Cleanup(func() {
+		require.NoError(t, archives.Close())
+	})
+
+	archive, err := archives.Mount("/app/data.tar.gz")
+	require.NoError(t, err)
+
+	spooled, ok := archive.reader.(interface{ Name() string })
+	require.True(t, ok, "decompressed archive contents should be spooled")
+
+	root, err := img.tmpDirGen.NewDirectory()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Dir(root), filepath.Dir(filepath.Dir(spooled.Name())))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/layout"
@@ -21,20 +22,23 @@ func NewDirectoryProvider(tmpDirGen *file.TempDirGenerator, path string) image.P
 }
 
 // NewDirectoryProviderWithPlatform creates a new provider instance for the specific image already at the given path,
-// with the given platform information to use when loading a multiplatform image.
-func NewDirectoryProviderWithPlatform(tmpDirGen *file.TempDirGenerator, path string, platform *image.Platform) image.Provider {
+// with the given platform information to use when loading a multiplatform image. Any additional metadata is applied
+// to the image before it is read, overriding any provider defaults.
+func NewDirectoryProviderWithPlatform(tmpDirGen *file.TempDirGenerator, path string, platform *image.Platform, additionalMetadata ...image.AdditionalMetadata) image.Provider {
 	return &directoryImageProvider{
-		tmpDirGen: tmpDirGen,
-		path:      path,
-		platform:  platform,
+		tmpDirGen:          tmpDirGen,
+		path:               path,
+		platform:           platform,
+		additionalMetadata: additionalMetadata,
 	}
 }
 
 // directoryImageProvider is an image.Provider for an OCI image (V1) for an existing tar on disk (from a buildah push <img> oci:<img> command).
 type directoryImageProvider struct {
-	tmpDirGen *file.TempDirGenerator
-	path      string
-	platform  *image.Platform
+	tmpDirGen          *file.TempDirGenerator
+	path               string
+	platform           *image.Platform
+	additionalMetadata []image.AdditionalMetadata
 }
 
 func (p *directoryImageProvider) Name() string {
@@ -42,7 +46,11 @@ func (p *directoryImageProvider) Name() string {
 }
 
 // Provide an image object that represents the OCI image as a directory.
-func (p *directoryImageProvider) Provide(_ context.Context) (*image.Image, error) {
+func (p *directoryImageProvider) Provide(ctx context.Context) (*image.Image, error) {
+	if fi, err := os.Stat(p.path); err != nil || !fi.IsDir() {
+		return nil, fmt.Errorf("unable to read image from OCI directory path %q: %w: not a directory", p.path, image.ErrProviderNotApplicable)
+	}
+
 	if _, err := layout.FromPath(p.path); err != nil {
 		return nil, fmt.Errorf("unable to read image from OCI directory path %q: %w", p.path, err)
 	}
@@ -64,7 +72,7 @@ func (p *directoryImageProvider) Provide(_ context.Context) (*image.Image, error
 	log.Debugf("found %d total images in OCI directory", len(allImages))
 
 	if len(allImages) == 0 {
-		return nil, fmt.Errorf("no images found in OCI directory at path %q", p.path)
+		return nil, fmt.Errorf("%w: no images found in OCI directory at path %q", image.ErrImageNotFound, p.path)
 	}
 
 	var selectedImage v1.Image
@@ -79,8 +87,11 @@ func (p *directoryImageProvider) Provide(_ context.Context) (*image.Image, error
 			return nil, fmt.Errorf("error converting platform: %v", p.platform)
 		}
 		matchedImages := imagesForPlatform(allImages, *platform)
-		if len(matchedImages) != 1 {
-			return nil, fmt.Errorf("unexpected number of images matching platform %q in OCI directory (expected 1, found %d)", platform.String(), len(matchedImages))
+		if len(matchedImages) == 0 {
+			return nil, newErrPlatformMismatch(defaultPlatformIfNil(p.platform), fmt.Errorf("unexpected number of images matching platform %q in OCI directory (expected 1, found 0)", platform.String()))
+		}
+		if len(matchedImages) > 1 {
+			return nil, fmt.Errorf("%w: unexpected number of images matching platform %q in OCI directory (expected 1, found %d)", image.ErrMultipleImages, platform.String(), len(matchedImages))
 		}
 		selectedImage = matchedImages[0]
 	}
@@ -94,6 +105,7 @@ func (p *directoryImageProvider) Provide(_ context.Context) (*image.Image, error
 
 	metadata := []image.AdditionalMetadata{
 		image.WithManifestDigest(selectedImageDigest.String()),
+		image.WithLayerVerification(image.LayerVerification{DiffIDs: true, Digests: true}),
 	}
 
 	// make a best-effort attempt at getting the raw indexManifest
@@ -102,13 +114,16 @@ func (p *directoryImageProvider) Provide(_ context.Context) (*image.Image, error
 		metadata = append(metadata, image.WithManifest(rawManifest))
 	}
 
+	// apply user-supplied metadata last to override any default behavior
+	metadata = append(metadata, p.additionalMetadata...)
+
 	contentTempDir, err := p.tmpDirGen.NewDirectory("oci-dir-image")
 	if err != nil {
 		return nil, err
 	}
 
 	out := image.New(selectedImage, p.tmpDirGen, contentTempDir, metadata...)
-	err = out.Read()
+	err = out.ReadWithContext(ctx)
 	if err != nil {
 		cleanErr := out.Cleanup()
 		return nil, errors.Join(err, cleanErr)
@@ -9,17 +9,20 @@ import (
 	"github.com/anchore/stereoscope/pkg/image"
 )
 
-func validatePlatform(platform *image.Platform, givenOs, givenArch, givenVariant string) error {
+func validatePlatform(platform *image.Platform, givenOs, givenArch, givenVariant, givenOSVersion string) error {
 	if platform == nil {
 		return nil
 	}
 	if givenArch == "" || givenOs == "" {
-		return newErrPlatformMismatch(platform, fmt.Errorf("missing architecture or OS from image config when user specified platform=%q", platform.String()))
+		return newErrPlatformMismatch(platform, fmt.Errorf("missing architecture or OS from image config when user specified platform=%q", toContainerRegistryPlatform(platform).String()))
 	}
 	platformStr := fmt.Sprintf("%s/%s", givenOs, givenArch)
 	if givenVariant != "" {
 		platformStr += "/" + givenVariant
 	}
+	if givenOSVersion != "" {
+		platformStr += ":" + givenOSVersion
+	}
 	actualPlatform, err := containerregistryV1.ParsePlatform(platformStr)
 	if err != nil {
 		return newErrPlatformMismatch(platform, fmt.Errorf("failed to parse platform from image config: %w", err))
@@ -28,7 +31,7 @@ func validatePlatform(platform *image.Platform, givenOs, givenArch, givenVariant
 		return newErrPlatformMismatch(platform, fmt.Errorf("not platform from image config (from %q)", platformStr))
 	}
 	if !matchesPlatform(*actualPlatform, *toContainerRegistryPlatform(platform)) {
-		return newErrPlatformMismatch(platform, fmt.Errorf("image platform=%q does not match user specified platform=%q", actualPlatform.String(), platform.String()))
+		return newErrPlatformMismatch(platform, fmt.Errorf("image platform=%q does not match user specified platform=%q", actualPlatform.String(), toContainerRegistryPlatform(platform).String()))
 	}
 	return nil
 }
@@ -48,6 +51,7 @@ func toContainerRegistryPlatform(p *image.Platform) *containerregistryV1.Platfor
 		Architecture: p.Architecture,
 		OS:           p.OS,
 		Variant:      p.Variant,
+		OSVersion:    p.OSVersion,
 	}
 }
 
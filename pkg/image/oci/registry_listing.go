@@ -0,0 +1,185 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/anchore/stereoscope/internal/log"
+	"github.com/anchore/stereoscope/pkg/image"
+)
+
+// ListOptions configures the listing of tags within a repository or repositories within a registry.
+type ListOptions struct {
+	// PageSize is the number of entries requested from the registry per page (0 uses the registry default).
+	PageSize int
+	// Limit is the maximum number of entries returned (0 for no limit). No further pages are requested from the
+	// registry once the limit is reached.
+	Limit int
+	// Filter selects the entries to return (nil returns all entries). Entries that are filtered out do not count
+	// towards the limit.
+	Filter func(string) bool
+}
+
+// TagDigest describes the digest a tag resolves to.
+type TagDigest struct {
+	// Tag is the fully qualified tag (e.g. "registry.example.com/repo:1.0").
+	Tag string
+	// Digest is the digest of the manifest (or manifest list) the tag refers to.
+	Digest string
+	// MediaType is the media type of the manifest (or manifest list) the tag refers to.
+	MediaType string
+}
+
+// ListTags returns the tags of the given repository (e.g. "registry.example.com/repo"), following registry pagination
+// as needed. The registry options are used for authentication and TLS configuration.
+func ListTags(ctx context.Context, registryOptions image.RegistryOptions, repository string, opts ListOptions) ([]string, error) {
+	repo, err := name.NewRepository(repository, prepareReferenceOptions(registryOptions)...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse repository=%q: %w", repository, err)
+	}
+
+	puller, err := newListingPuller(ctx, repo.RegistryStr(), registryOptions, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	lister, err := puller.Lister(ctx, repo)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list tags for repository=%q: %w", repository, classifyRegistryError(err))
+	}
+
+	var tags []string
+	for lister.HasNext() {
+		page, err := lister.Next(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list tags for repository=%q: %w", repository, classifyRegistryError(err))
+		}
+		var done bool
+		if tags, done = opts.collect(tags, page.Tags); done {
+			break
+		}
+	}
+
+	log.WithFields("repository", repository, "tags", len(tags)).Trace("listed repository tags")
+
+	return tags, nil
+}
+
+// ListRepositories returns the repositories within the given registry (e.g. "registry.example.com") using the registry
+// catalog API, following registry pagination as needed. Note: many registries do not permit listing the catalog, in
+// which case image.ErrUnauthorized is returned.
+func ListRepositories(ctx context.Context, registryOptions image.RegistryOptions, registry string, opts ListOptions) ([]string, error) {
+	reg, err := name.NewRegistry(registry, prepareReferenceOptions(registryOptions)...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse registry=%q: %w", registry, err)
+	}
+
+	puller, err := newListingPuller(ctx, reg.RegistryStr(), registryOptions, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	catalogger, err := puller.Catalogger(ctx, reg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list repositories for registry=%q: %w", registry, classifyRegistryError(err))
+	}
+
+	var repos []string
+	for catalogger.HasNext() {
+		page, err := catalogger.Next(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list repositories for registry=%q: %w", registry, classifyRegistryError(err))
+		}
+		var done bool
+		if repos, done = opts.collect(repos, page.Repos); done {
+			break
+		}
+	}
+
+	log.WithFields("registry", registry, "repositories", len(repos)).Trace("listed registry repositories")
+
+	return repos, nil
+}
+
+// ResolveTags returns the digest each of the given tags refers to, using HEAD requests (thus without fetching the
+// manifests). Tags may either be fully qualified (e.g. "registry.example.com/repo:1.0") or, when a repository is given,
+// relative to the repository (e.g. "1.0").
+func ResolveTags(ctx context.Context, registryOptions image.RegistryOptions, repository string, tags ...string) ([]TagDigest, error) {
+	var repo *name.Repository
+	if repository != "" {
+		r, err := name.NewRepository(repository, prepareReferenceOptions(registryOptions)...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse repository=%q: %w", repository, err)
+		}
+		repo = &r
+	}
+
+	pullers := make(map[string]*remote.Puller)
+	var results []TagDigest
+	for _, t := range tags {
+		var tag name.Tag
+		var err error
+		if repo != nil {
+			tag = repo.Tag(t)
+		} else {
+			tag, err = name.NewTag(t, prepareReferenceOptions(registryOptions)...)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse tag=%q: %w", t, err)
+		}
+
+		registryName := tag.RegistryStr()
+		puller, ok := pullers[registryName]
+		if !ok {
+			puller, err = newListingPuller(ctx, registryName, registryOptions, ListOptions{})
+			if err != nil {
+				return nil, err
+			}
+			pullers[registryName] = puller
+		}
+
+		descriptor, err := puller.Head(ctx, tag)
+		if err != nil {
+			return nil, fmt.Errorf("unable to resolve tag=%q: %w", tag.String(), classifyRegistryError(err))
+		}
+
+		results = append(results, TagDigest{
+			Tag:       tag.String(),
+			Digest:    descriptor.Digest.String(),
+			MediaType: string(descriptor.MediaType),
+		})
+	}
+
+	return results, nil
+}
+
+func newListingPuller(ctx context.Context, registryName string, registryOptions image.RegistryOptions, opts ListOptions) (*remote.Puller, error) {
+	options := prepareRemoteOptions(ctx, registryName, registryOptions, nil, newEffectiveURLTransport(nil))
+	if opts.PageSize > 0 {
+		options = append(options, remote.WithPageSize(opts.PageSize))
+	}
+
+	puller, err := remote.NewPuller(options...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure registry client for registry=%q: %w", registryName, err)
+	}
+	return puller, nil
+}
+
+// collect adds the entries selected by the filter to the given set of results, returning true when the limit has
+// been reached.
+func (o ListOptions) collect(results, entries []string) ([]string, bool) {
+	for _, entry := range entries {
+		if o.Filter != nil && !o.Filter(entry) {
+			continue
+		}
+		results = append(results, entry)
+		if o.Limit > 0 && len(results) >= o.Limit {
+			return results, true
+		}
+	}
+	return results, false
+}
@@ -0,0 +1,170 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anchore/stereoscope/pkg/image"
+)
+
+// makePaginatingRegistry returns an in-process registry which provides Link headers for paginated tag listings (which
+// the in-process registry does not do on its own).
+func makePaginatingRegistry(t *testing.T) (registryHost string) {
+	t.Helper()
+	registryInstance := registry.New(registry.WithBlobHandler(registry.NewInMemoryBlobHandler()))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := r.URL.Query().Get("n")
+		if !strings.HasSuffix(r.URL.Path, "/tags/list") || n == "" {
+			registryInstance.ServeHTTP(w, r)
+			return
+		}
+
+		rec := httptest.NewRecorder()
+		registryInstance.ServeHTTP(rec, r)
+
+		var page struct {
+			Name string   `json:"name"`
+			Tags []string `json:"tags"`
+		}
+		body := rec.Body.Bytes()
+		if err := json.Unmarshal(body, &page); err == nil && len(page.Tags) > 0 {
+			// the in-process registry returns the first page again when there are no tags after "last"
+			if last := r.URL.Query().Get("last"); last != "" && page.Tags[0] <= last {
+				page.Tags = []string{}
+				body, _ = json.Marshal(page)
+			} else if strconv.Itoa(len(page.Tags)) == n {
+				next := url.URL{Path: r.URL.Path, RawQuery: url.Values{"n": {n}, "last": {page.Tags[len(page.Tags)-1]}}.Encode()}
+				w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.String()))
+			}
+		}
+		w.Header().Set("Content-Type", rec.Header().Get("Content-Type"))
+		w.WriteHeader(rec.Code)
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(ts.Close)
+	return strings.TrimPrefix(ts.URL, "http://")
+}
+
+func TestListTags(t *testing.T) {
+	registryHost := makePaginatingRegistry(t)
+	for _, tag := range []string{"1.0", "1.1", "2.0", "latest"} {
+		pushRandomRegistryImage(t, registryHost, "my-image", tag)
+	}
+	repository := fmt.Sprintf("%s/my-image", registryHost)
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want []string
+	}{
+		{
+			name: "all tags",
+			want: []string{"1.0", "1.1", "2.0", "latest"},
+		},
+		{
+			name: "paginated",
+			opts: ListOptions{PageSize: 1},
+			want: []string{"1.0", "1.1", "2.0", "latest"},
+		},
+		{
+			name: "filtered",
+			opts: ListOptions{Filter: func(tag string) bool { return strings.HasPrefix(tag, "1.") }},
+			want: []string{"1.0", "1.1"},
+		},
+		{
+			name: "limited",
+			opts: ListOptions{PageSize: 1, Limit: 2},
+			want: []string{"1.0", "1.1"},
+		},
+		{
+			name: "filtered and limited",
+			opts: ListOptions{
+				Limit:  1,
+				Filter: func(tag string) bool { return tag != "1.0" },
+			},
+			want: []string{"1.1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags, err := ListTags(context.Background(), image.RegistryOptions{}, repository, tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tags)
+		})
+	}
+}
+
+func TestListTags_RepositoryNotFound(t *testing.T) {
+	registryHost := makeRegistry(t)
+
+	_, err := ListTags(context.Background(), image.RegistryOptions{}, fmt.Sprintf("%s/missing", registryHost), ListOptions{})
+	require.ErrorIs(t, err, image.ErrImageNotFound)
+}
+
+func TestListRepositories(t *testing.T) {
+	registryHost := makeRegistry(t)
+	for _, repo := range []string{"team/app", "team/db", "tools"} {
+		pushRandomRegistryImage(t, registryHost, repo, "latest")
+	}
+
+	repos, err := ListRepositories(context.Background(), image.RegistryOptions{}, registryHost, ListOptions{})
+	require.NoError(t, err)
+	assert.Subset(t, repos, []string{"team/app", "team/db", "tools"})
+
+	repos, err = ListRepositories(context.Background(), image.RegistryOptions{}, registryHost, ListOptions{
+		Filter: func(repo string) bool { return strings.HasPrefix(repo, "team/") },
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"team/app", "team/db"}, repos)
+
+	repos, err = ListRepositories(context.Background(), image.RegistryOptions{}, registryHost, ListOptions{Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, repos, 1)
+}
+
+func TestResolveTags(t *testing.T) {
+	registryHost := makeRegistry(t)
+	pushRandomRegistryImage(t, registryHost, "my-image", "1.0")
+	pushRandomRegistryImage(t, registryHost, "my-image", "2.0")
+	repository := fmt.Sprintf("%s/my-image", registryHost)
+
+	expectedDigest := func(tag string) string {
+		ref, err := name.ParseReference(repository + ":" + tag)
+		require.NoError(t, err)
+		desc, err := remote.Get(ref)
+		require.NoError(t, err)
+		return desc.Digest.String()
+	}
+
+	results, err := ResolveTags(context.Background(), image.RegistryOptions{}, repository, "1.0", "2.0")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, repository+":1.0", results[0].Tag)
+	assert.Equal(t, expectedDigest("1.0"), results[0].Digest)
+	assert.Equal(t, repository+":2.0", results[1].Tag)
+	assert.Equal(t, expectedDigest("2.0"), results[1].Digest)
+	assert.NotEqual(t, results[0].Digest, results[1].Digest)
+	assert.NotEmpty(t, results[0].MediaType)
+
+	// fully qualified tags do not need a repository
+	results, err = ResolveTags(context.Background(), image.RegistryOptions{}, "", repository+":1.0")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, expectedDigest("1.0"), results[0].Digest)
+
+	_, err = ResolveTags(context.Background(), image.RegistryOptions{}, repository, "missing")
+	require.ErrorIs(t, err, image.ErrImageNotFound)
+}
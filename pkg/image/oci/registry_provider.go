@@ -12,7 +12,9 @@ import (
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
+	containerregistryV1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 	containerregistryV1Types "github.com/google/go-containerregistry/pkg/v1/types"
 
 	"github.com/anchore/stereoscope/internal/log"
@@ -70,12 +72,14 @@ func (t *effectiveURLTransport) getEffectiveHost(originalHost string) string {
 }
 
 // NewRegistryProvider creates a new provider instance for a specific image that will later be cached to the given directory.
-func NewRegistryProvider(tmpDirGen *file.TempDirGenerator, registryOptions image.RegistryOptions, imageStr string, platform *image.Platform) image.Provider {
+// Any additional metadata is applied to the image before it is read, overriding any provider defaults.
+func NewRegistryProvider(tmpDirGen *file.TempDirGenerator, registryOptions image.RegistryOptions, imageStr string, platform *image.Platform, additionalMetadata ...image.AdditionalMetadata) image.Provider {
 	return &registryImageProvider{
-		tmpDirGen:       tmpDirGen,
-		imageStr:        imageStr,
-		platform:        platform,
-		registryOptions: registryOptions,
+		tmpDirGen:          tmpDirGen,
+		imageStr:           imageStr,
+		platform:           platform,
+		registryOptions:    registryOptions,
+		additionalMetadata: additionalMetadata,
 	}
 }
 
@@ -86,6 +90,7 @@ type registryImageProvider struct {
 	platform           *image.Platform
 	registryOptions    image.RegistryOptions
 	effectiveTransport *effectiveURLTransport
+	additionalMetadata []image.AdditionalMetadata
 }
 
 func (p *registryImageProvider) Name() string {
@@ -94,6 +99,10 @@ func (p *registryImageProvider) Name() string {
 
 // Provide an image object that represents the cached docker image tar fetched a registry.
 func (p *registryImageProvider) Provide(ctx context.Context) (*image.Image, error) {
+	if p.registryOptions.Offline {
+		return nil, fmt.Errorf("%w: unable to pull image=%q from registry", image.ErrOffline, p.imageStr)
+	}
+
 	log.Debugf("pulling image info directly from registry image=%q", p.imageStr)
 
 	startTime := time.Now()
@@ -102,9 +111,9 @@ func (p *registryImageProvider) Provide(ctx context.Context) (*image.Image, erro
 		return nil, err
 	}
 
-	ref, err := name.ParseReference(p.imageStr, prepareReferenceOptions(p.registryOptions)...)
+	sources, err := p.registryOptions.PullSources(p.imageStr)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse registry reference=%q: %+v", p.imageStr, err)
+		return nil, err
 	}
 
 	platform := defaultPlatformIfNil(p.platform)
@@ -114,26 +123,29 @@ func (p *registryImageProvider) Provide(ctx context.Context) (*image.Image, erro
 		p.effectiveTransport = newEffectiveURLTransport(nil)
 	}
 
-	options := prepareRemoteOptions(ctx, ref, p.registryOptions, platform, p.effectiveTransport)
-
-	descriptor, err := remote.Get(ref, options...)
+	descriptor, ref, err := p.getDescriptor(ctx, sources, platform)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get image descriptor from registry: %+v", err)
+		return nil, err
 	}
 
 	p.finalizePlatform(descriptor, &platform)
 
+	// fail before any layer content is downloaded (e.g. a mirror serving content for another digest)
+	if err := verifyPinnedDescriptor(p.imageStr, descriptor.Digest); err != nil {
+		return nil, err
+	}
+
 	img, err := descriptor.Image()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get image from registry: %+v", err)
+		return nil, fmt.Errorf("failed to get image from registry: %w", classifyRegistryError(err))
 	}
 
 	c, err := img.ConfigFile()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get image config from registry: %+v", err)
+		return nil, fmt.Errorf("failed to get image config from registry: %w", classifyRegistryError(err))
 	}
 
-	if err := validatePlatform(platform, c.OS, c.Architecture, c.Variant); err != nil {
+	if err := validatePlatform(platform, c.OS, c.Architecture, c.Variant, c.OSVersion); err != nil {
 		return nil, err
 	}
 
@@ -162,8 +174,11 @@ func (p *registryImageProvider) Provide(ctx context.Context) (*image.Image, erro
 		)
 	}
 
+	// apply user-supplied metadata last to override any default behavior
+	metadata = append(metadata, p.additionalMetadata...)
+
 	out := image.New(img, p.tmpDirGen, imageTempDir, metadata...)
-	err = out.Read()
+	err = out.ReadWithContext(ctx)
 	if err != nil {
 		cleanErr := out.Cleanup()
 		return nil, errors.Join(err, cleanErr)
@@ -171,6 +186,92 @@ func (p *registryImageProvider) Provide(ctx context.Context) (*image.Image, erro
 	return out, err
 }
 
+// getDescriptor fetches the image descriptor from the first of the given sources that provides it, returning the
+// canonical reference for the source (so that mirrors do not leak into the image metadata).
+func (p *registryImageProvider) getDescriptor(ctx context.Context, sources []image.PullSource, platform *image.Platform) (*remote.Descriptor, name.Reference, error) {
+	var errs []error
+	for _, source := range sources {
+		sourceOptions := p.registryOptions
+		if source.Insecure {
+			sourceOptions.InsecureUseHTTP = true
+			sourceOptions.InsecureSkipTLSVerify = true
+		}
+
+		ref, err := name.ParseReference(source.Reference, prepareReferenceOptions(sourceOptions)...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to parse registry reference=%q: %+v", source.Reference, err))
+			continue
+		}
+
+		canonical := ref
+		if source.Canonical != source.Reference {
+			canonical, err = name.ParseReference(source.Canonical, prepareReferenceOptions(p.registryOptions)...)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to parse registry reference=%q: %+v", source.Canonical, err))
+				continue
+			}
+		}
+
+		options := prepareRemoteOptions(ctx, ref.Context().RegistryStr(), sourceOptions, platform, p.effectiveTransport)
+
+		descriptor, err := remote.Get(ref, options...)
+		if err != nil {
+			log.WithFields("image", source.Reference, "mirror", source.Mirror, "error", err).Debug("unable to get image descriptor from source")
+			errs = append(errs, fmt.Errorf("failed to get image descriptor from registry: %w", classifyRegistryError(err)))
+			continue
+		}
+
+		if source.Mirror {
+			log.WithFields("image", source.Canonical, "mirror", source.Reference).Debug("using registry mirror")
+		}
+		return descriptor, canonical, nil
+	}
+	return nil, nil, errors.Join(errs...)
+}
+
+// classifyRegistryError wraps the given registry error with the matching stereoscope error (e.g. image.ErrUnauthorized)
+// based on the registry response. Errors that cannot be classified are returned as-is.
+func classifyRegistryError(err error) error {
+	var transportErr *transport.Error
+	if !errors.As(err, &transportErr) {
+		return err
+	}
+
+	for _, diagnostic := range transportErr.Errors {
+		switch diagnostic.Code {
+		case transport.ManifestUnknownErrorCode, transport.NameUnknownErrorCode:
+			return fmt.Errorf("%w: %w", image.ErrImageNotFound, err)
+		case transport.UnauthorizedErrorCode, transport.DeniedErrorCode:
+			return fmt.Errorf("%w: %w", image.ErrUnauthorized, err)
+		case transport.TooManyRequestsErrorCode:
+			return fmt.Errorf("%w: %w", image.ErrRateLimited, err)
+		}
+	}
+
+	switch transportErr.StatusCode {
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %w", image.ErrImageNotFound, err)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: %w", image.ErrUnauthorized, err)
+	case http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %w", image.ErrRateLimited, err)
+	}
+	return err
+}
+
+// verifyPinnedDescriptor confirms that the descriptor fetched for a digest-pinned reference has the pinned digest.
+func verifyPinnedDescriptor(reference string, digest containerregistryV1.Hash) error {
+	expected, ok := image.PinnedDigest(reference)
+	if !ok || expected == digest.String() {
+		return nil
+	}
+	return &image.DigestMismatchError{
+		Reference: reference,
+		Expected:  expected,
+		Actual:    []string{digest.String()},
+	}
+}
+
 func (p *registryImageProvider) finalizePlatform(descriptor *remote.Descriptor, platform **image.Platform) {
 	if p.platform != nil {
 		return
@@ -196,7 +297,7 @@ func prepareReferenceOptions(registryOptions image.RegistryOptions) []name.Optio
 	return options
 }
 
-func prepareRemoteOptions(ctx context.Context, ref name.Reference, registryOptions image.RegistryOptions, p *image.Platform, effectiveTransport *effectiveURLTransport) (options []remote.Option) {
+func prepareRemoteOptions(ctx context.Context, registryName string, registryOptions image.RegistryOptions, p *image.Platform, effectiveTransport *effectiveURLTransport) (options []remote.Option) {
 	options = append(options, remote.WithContext(ctx))
 
 	// Set the user agent to indicate what binary is making the request
@@ -207,8 +308,6 @@ func prepareRemoteOptions(ctx context.Context, ref name.Reference, registryOptio
 		options = append(options, remote.WithPlatform(*toContainerRegistryPlatform(p)))
 	}
 
-	registryName := ref.Context().RegistryStr()
-
 	// note: the authn.Authenticator and authn.Keychain options are mutually exclusive, only one may be provided.
 	// If no explicit authenticator can be found, check if explicit Keychain has been provided, and if not, then
 	// fallback to the default keychain. With the authenticator also comes the option to configure TLS transport.
@@ -230,10 +329,22 @@ func prepareRemoteOptions(ctx context.Context, ref name.Reference, registryOptio
 		log.Warn("unable to configure TLS transport: %w", err)
 	}
 
-	// Use our custom transport that captures effective URLs after redirects
-	transport := getTransportWithEffectiveURL(tlsConfig, effectiveTransport)
+	var baseTransport http.RoundTripper = newRetryTransport(getTransport(tlsConfig), registryName, registryOptions.Retry)
+	if registryOptions.Offline {
+		baseTransport = offlineTransport{}
+	}
+
+	// Use our custom transport that captures effective URLs after redirects and retries failed requests
+	transport := getTransportWithEffectiveURL(baseTransport, effectiveTransport)
 	options = append(options, remote.WithTransport(transport))
 
+	// retries are handled by our own transport (which is aware of rate limits and resumes blob downloads), so the
+	// go-containerregistry retries are disabled to avoid compounding the number of attempts
+	options = append(options,
+		remote.WithRetryStatusCodes(),
+		remote.WithRetryPredicate(func(error) bool { return false }),
+	)
+
 	return options
 }
 
@@ -244,11 +355,15 @@ func getTransport(tlsConfig *tls.Config) *http.Transport {
 	return transport
 }
 
-func getTransportWithEffectiveURL(tlsConfig *tls.Config, effectiveTransport *effectiveURLTransport) http.RoundTripper {
-	// create base transport with TLS config
-	baseTransport := getTransport(tlsConfig)
+// offlineTransport fails every request, guaranteeing that no registry is contacted while offline.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("%w: refusing to contact registry=%q", image.ErrOffline, req.URL.Host)
+}
 
-	// wrap it with our effective URL capturing transport
+func getTransportWithEffectiveURL(baseTransport http.RoundTripper, effectiveTransport *effectiveURLTransport) http.RoundTripper {
+	// wrap the base transport with our effective URL capturing transport
 	effectiveTransport.base = baseTransport
 	return effectiveTransport
 }
@@ -2,6 +2,7 @@ package oci
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -9,15 +10,18 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/registry"
+	containerregistryV1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
 	"github.com/google/go-containerregistry/pkg/v1/random"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -36,6 +40,7 @@ func TestValidatePlatform(t *testing.T) {
 		givenOs        string
 		givenArch      string
 		givenVariant   string
+		givenOSVersion string
 		expectedErrMsg string
 		expectedErr    require.ErrorAssertionFunc
 	}{
@@ -119,6 +124,23 @@ func TestValidatePlatform(t *testing.T) {
 			expectedErr:    isFetchError,
 			expectedErrMsg: `image platform="linux/arm64" does not match user specified platform="linux/arm64/v8"`,
 		},
+		{
+			name:           "matching windows platform with os version",
+			platform:       &image.Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.17763.5576"},
+			givenOs:        "windows",
+			givenArch:      "amd64",
+			givenOSVersion: "10.0.17763.5576",
+			expectedErr:    require.NoError,
+		},
+		{
+			name:           "mismatched windows os version",
+			platform:       &image.Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.17763.5576"},
+			givenOs:        "windows",
+			givenArch:      "amd64",
+			givenOSVersion: "10.0.20348.2340",
+			expectedErr:    isFetchError,
+			expectedErrMsg: `image platform="windows/amd64:10.0.20348.2340" does not match user specified platform="windows/amd64:10.0.17763.5576"`,
+		},
 		{
 			name:         "image has variant, user does not specify one",
 			platform:     &image.Platform{OS: "linux", Architecture: "arm64"},
@@ -131,7 +153,7 @@ func TestValidatePlatform(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validatePlatform(tt.platform, tt.givenOs, tt.givenArch, tt.givenVariant)
+			err := validatePlatform(tt.platform, tt.givenOs, tt.givenArch, tt.givenVariant, tt.givenOSVersion)
 			tt.expectedErr(t, err)
 			if err != nil {
 				assert.ErrorContains(t, err, tt.expectedErrMsg)
@@ -157,6 +179,164 @@ func Test_RegistryProvider(t *testing.T) {
 	assert.NotNil(t, img)
 }
 
+func Test_RegistryProvider_ImageNotFound(t *testing.T) {
+	registryHost := makeRegistry(t)
+	pushRandomRegistryImage(t, registryHost, "my-image", "the-tag")
+
+	generator := file.TempDirGenerator{}
+	defer generator.Cleanup()
+
+	provider := NewRegistryProvider(&generator, image.RegistryOptions{}, fmt.Sprintf("%s/%s:%s", registryHost, "my-image", "missing-tag"), nil)
+	img, err := provider.Provide(context.TODO())
+	assert.Nil(t, img)
+	require.ErrorIs(t, err, image.ErrImageNotFound)
+}
+
+func Test_RegistryProvider_Mirrors(t *testing.T) {
+	primaryHost := makeRegistry(t)
+	pushRandomRegistryImage(t, primaryHost, "team/app", "1.0")
+	emptyMirrorHost := makeRegistry(t)
+	mirrorHost := makeRegistry(t)
+	pushRandomRegistryImage(t, mirrorHost, "cache/app", "1.0")
+
+	digestOf := func(ref string) string {
+		r, err := name.ParseReference(ref)
+		require.NoError(t, err)
+		desc, err := remote.Head(r)
+		require.NoError(t, err)
+		return desc.Digest.String()
+	}
+
+	tests := []struct {
+		name           string
+		mirrors        []image.RegistryMirror
+		wantRepoDigest string
+	}{
+		{
+			name:           "falls back to the location",
+			mirrors:        []image.RegistryMirror{{Location: emptyMirrorHost}},
+			wantRepoDigest: primaryHost + "/team/app@" + digestOf(primaryHost+"/team/app:1.0"),
+		},
+		{
+			name:           "falls back to the next mirror",
+			mirrors:        []image.RegistryMirror{{Location: emptyMirrorHost}, {Location: mirrorHost + "/cache"}},
+			wantRepoDigest: primaryHost + "/team/app@" + digestOf(mirrorHost+"/cache/app:1.0"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator := file.TempDirGenerator{}
+			defer generator.Cleanup()
+
+			options := image.RegistryOptions{
+				Registries: []image.RegistryConfig{
+					{
+						Prefix:   "registry.example.com/team",
+						Location: primaryHost + "/team",
+						Mirrors:  tt.mirrors,
+					},
+				},
+			}
+			provider := NewRegistryProvider(&generator, options, "registry.example.com/team/app:1.0", nil)
+			img, err := provider.Provide(context.TODO())
+			require.NoError(t, err)
+			assert.Equal(t, []string{tt.wantRepoDigest}, img.Metadata.RepoDigests)
+		})
+	}
+}
+
+func Test_RegistryProvider_Blocked(t *testing.T) {
+	registryHost := makeRegistry(t)
+	pushRandomRegistryImage(t, registryHost, "my-image", "the-tag")
+
+	generator := file.TempDirGenerator{}
+	defer generator.Cleanup()
+
+	options := image.RegistryOptions{
+		Registries: []image.RegistryConfig{{Prefix: registryHost, Blocked: true}},
+	}
+	provider := NewRegistryProvider(&generator, options, fmt.Sprintf("%s/%s:%s", registryHost, "my-image", "the-tag"), nil)
+	img, err := provider.Provide(context.TODO())
+	assert.Nil(t, img)
+	require.ErrorIs(t, err, image.ErrRegistryBlocked)
+}
+
+func Test_RegistryProvider_Offline(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	generator := file.TempDirGenerator{}
+	defer generator.Cleanup()
+
+	options := image.RegistryOptions{Offline: true}
+	provider := NewRegistryProvider(&generator, options, fmt.Sprintf("%s/my-image:the-tag", host), nil)
+	img, err := provider.Provide(context.TODO())
+	assert.Nil(t, img)
+	require.ErrorIs(t, err, image.ErrOffline)
+
+	// any other registry access is refused by the transport
+	_, err = ListTags(context.TODO(), options, fmt.Sprintf("%s/my-image", host), ListOptions{})
+	require.ErrorIs(t, err, image.ErrOffline)
+
+	_, err = ResolveTags(context.TODO(), options, "", fmt.Sprintf("%s/my-image:the-tag", host))
+	require.ErrorIs(t, err, image.ErrOffline)
+
+	assert.Zero(t, requests.Load())
+}
+
+func Test_classifyRegistryError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{
+			name:    "manifest unknown",
+			err:     &transport.Error{StatusCode: http.StatusNotFound, Errors: []transport.Diagnostic{{Code: transport.ManifestUnknownErrorCode}}},
+			wantErr: image.ErrImageNotFound,
+		},
+		{
+			name:    "denied diagnostic",
+			err:     &transport.Error{StatusCode: http.StatusNotFound, Errors: []transport.Diagnostic{{Code: transport.DeniedErrorCode}}},
+			wantErr: image.ErrUnauthorized,
+		},
+		{
+			name:    "unauthorized status",
+			err:     &transport.Error{StatusCode: http.StatusUnauthorized},
+			wantErr: image.ErrUnauthorized,
+		},
+		{
+			name:    "rate limited status",
+			err:     fmt.Errorf("wrapped: %w", &transport.Error{StatusCode: http.StatusTooManyRequests}),
+			wantErr: image.ErrRateLimited,
+		},
+		{
+			name: "unclassified status",
+			err:  &transport.Error{StatusCode: http.StatusInternalServerError},
+		},
+		{
+			name: "not a transport error",
+			err:  errors.New("boom"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyRegistryError(tt.err)
+			require.ErrorIs(t, got, tt.err)
+			if tt.wantErr == nil {
+				assert.Equal(t, tt.err, got)
+				return
+			}
+			assert.ErrorIs(t, got, tt.wantErr)
+		})
+	}
+}
+
 func Test_NewProviderFromRegistry(t *testing.T) {
 	//GIVEN
 	imageStr := "image"
@@ -426,3 +606,41 @@ type mockRoundTripper struct {
 func (m *mockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	return m.resp, m.err
 }
+
+func Test_verifyPinnedDescriptor(t *testing.T) {
+	const (
+		pinned = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		other  = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	)
+	pinnedHash, err := containerregistryV1.NewHash(pinned)
+	require.NoError(t, err)
+	otherHash, err := containerregistryV1.NewHash(other)
+	require.NoError(t, err)
+
+	require.NoError(t, verifyPinnedDescriptor("alpine@"+pinned, pinnedHash))
+	require.NoError(t, verifyPinnedDescriptor("alpine:latest", otherHash), "unpinned references are not verified")
+
+	err = verifyPinnedDescriptor("alpine@"+pinned, otherHash)
+	var mismatch *image.DigestMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, pinned, mismatch.Expected)
+	assert.Equal(t, []string{other}, mismatch.Actual)
+}
+
+func Test_RegistryProvider_PinnedDigest(t *testing.T) {
+	registryHost := makeRegistry(t)
+	pushRandomRegistryImage(t, registryHost, "my-image", "the-tag")
+
+	ref, err := name.ParseReference(fmt.Sprintf("%s/my-image:the-tag", registryHost), name.Insecure)
+	require.NoError(t, err)
+	desc, err := remote.Head(ref)
+	require.NoError(t, err)
+
+	generator := file.TempDirGenerator{}
+	defer generator.Cleanup()
+
+	provider := NewRegistryProvider(&generator, image.RegistryOptions{}, fmt.Sprintf("%s/my-image@%s", registryHost, desc.Digest), nil)
+	img, err := provider.Provide(context.TODO())
+	require.NoError(t, err)
+	require.NotNil(t, img)
+}
@@ -0,0 +1,286 @@
+package oci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/wagoodman/go-partybus"
+
+	"github.com/anchore/stereoscope/internal/bus"
+	"github.com/anchore/stereoscope/internal/log"
+	"github.com/anchore/stereoscope/pkg/event"
+	"github.com/anchore/stereoscope/pkg/image"
+)
+
+// retryStatusCodes are the response status codes considered transient.
+var retryStatusCodes = map[int]bool{
+	http.StatusRequestTimeout:      true,
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// retryTransport retries idempotent registry requests that fail transiently (5xx responses, connection resets, and
+// rate limiting) with exponential backoff and jitter, honoring any "Retry-After" response headers. Interrupted blob
+// downloads are resumed with HTTP range requests instead of restarting the download.
+type retryTransport struct {
+	base     http.RoundTripper
+	registry string
+	options  image.RetryOptions
+	sleep    func(ctx context.Context, d time.Duration) error
+	random   func() float64
+}
+
+func newRetryTransport(base http.RoundTripper, registry string, options image.RetryOptions) *retryTransport {
+	return &retryTransport{
+		base:     base,
+		registry: registry,
+		options:  options.WithDefaults(),
+		sleep:    sleepWithContext,
+		random:   rand.Float64,
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		// only idempotent requests (without a body) can safely be retried
+		return t.base.RoundTrip(req)
+	}
+
+	resp, err := t.roundTripWithRetries(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if isResumableBlobResponse(req, resp) {
+		resp.Body = &resumableBody{
+			transport: t,
+			req:       req,
+			body:      resp.Body,
+		}
+	}
+	return resp, nil
+}
+
+func (t *retryTransport) roundTripWithRetries(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			if attempt >= t.options.MaxAttempts || !isRetryableError(err) {
+				return nil, err
+			}
+			if err := t.wait(req, attempt, t.backoff(attempt), err.Error()); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		status, hasStatus := rateLimitStatus(t.registry, resp)
+		if hasStatus {
+			bus.Publish(partybus.Event{
+				Type:   event.RegistryRateLimit,
+				Source: t.registry,
+				Value:  status,
+			})
+		}
+
+		if !retryStatusCodes[resp.StatusCode] {
+			return resp, nil
+		}
+
+		delay := t.backoff(attempt)
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests && (attempt >= t.options.MaxAttempts || retryAfter > t.options.MaxRetryAfter):
+			drainAndClose(resp)
+			return nil, &image.RateLimitError{Status: status}
+		case attempt >= t.options.MaxAttempts:
+			return resp, nil
+		case retryAfter > delay && retryAfter <= t.options.MaxRetryAfter:
+			delay = retryAfter
+		}
+
+		drainAndClose(resp)
+		if err := t.wait(req, attempt, delay, resp.Status); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *retryTransport) wait(req *http.Request, attempt int, delay time.Duration, reason string) error {
+	log.WithFields("url", req.URL.Redacted(), "attempt", attempt, "delay", delay, "reason", reason).Debug("retrying registry request")
+	return t.sleep(req.Context(), delay)
+}
+
+// backoff returns the delay before the retry following the given (1-based) attempt.
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	delay := float64(t.options.InitialBackoff) * math.Pow(t.options.Multiplier, float64(attempt-1))
+	delay = math.Min(delay, float64(t.options.MaxBackoff))
+	// randomize within [-jitter, +jitter] of the delay
+	delay *= 1 + t.options.Jitter*(2*t.random()-1)
+	return time.Duration(delay)
+}
+
+// resumableBody is a blob response body that transparently continues an interrupted download with a range request
+// starting at the last byte read.
+type resumableBody struct {
+	transport *retryTransport
+	req       *http.Request
+	body      io.ReadCloser
+	offset    int64
+	resumes   int
+}
+
+func (b *resumableBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.offset += int64(n)
+	if err == nil || errors.Is(err, io.EOF) || !isRetryableError(err) {
+		return n, err
+	}
+
+	if resumeErr := b.resume(err); resumeErr != nil {
+		return n, resumeErr
+	}
+	if n > 0 {
+		return n, nil
+	}
+	return b.Read(p)
+}
+
+func (b *resumableBody) resume(cause error) error {
+	if b.resumes >= b.transport.options.MaxAttempts-1 {
+		return cause
+	}
+	b.resumes++
+	_ = b.body.Close()
+
+	if err := b.transport.wait(b.req, b.resumes, b.transport.backoff(b.resumes), cause.Error()); err != nil {
+		return err
+	}
+
+	req := b.req.Clone(b.req.Context())
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", b.offset))
+
+	resp, err := b.transport.roundTripWithRetries(req)
+	if err != nil {
+		return fmt.Errorf("unable to resume blob download at offset=%d: %w", b.offset, err)
+	}
+
+	if resp.StatusCode != http.StatusPartialContent || !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", b.offset)) {
+		drainAndClose(resp)
+		return fmt.Errorf("unable to resume blob download at offset=%d (status=%d): %w", b.offset, resp.StatusCode, cause)
+	}
+
+	log.WithFields("url", req.URL.Redacted(), "offset", b.offset).Debug("resumed blob download")
+	b.body = resp.Body
+	return nil
+}
+
+func (b *resumableBody) Close() error {
+	return b.body.Close()
+}
+
+// isResumableBlobResponse indicates if the given response is a complete blob download (either directly from the
+// registry or from wherever the registry redirected to).
+func isResumableBlobResponse(req *http.Request, resp *http.Response) bool {
+	if req.Method != http.MethodGet || resp.StatusCode != http.StatusOK || req.Header.Get("Range") != "" {
+		return false
+	}
+	for r := req; r != nil; {
+		if strings.Contains(r.URL.Path, "/blobs/") {
+			return true
+		}
+		if r.Response == nil {
+			break
+		}
+		r = r.Response.Request
+	}
+	return false
+}
+
+// isRetryableError indicates if the given transport (or body read) error is transient.
+func isRetryableError(err error) bool {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF), errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE), errors.Is(err, net.ErrClosed):
+		return true
+	case errors.As(err, &netErr):
+		return netErr.Timeout()
+	}
+	return false
+}
+
+// rateLimitStatus returns the rate limit state reported by the response, if any (via the "ratelimit-limit" and
+// "ratelimit-remaining" headers, or a 429 status).
+func rateLimitStatus(registry string, resp *http.Response) (image.RateLimitStatus, bool) {
+	status := image.RateLimitStatus{
+		Registry:  registry,
+		Limit:     parseRateLimitHeader(resp.Header.Get("ratelimit-limit")),
+		Remaining: parseRateLimitHeader(resp.Header.Get("ratelimit-remaining")),
+		Limited:   resp.StatusCode == http.StatusTooManyRequests,
+	}
+	if status.Limited {
+		status.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+	return status, status.Limited || status.Limit >= 0 || status.Remaining >= 0
+}
+
+// parseRateLimitHeader parses values such as "100;w=21600" (returning 100), returning -1 if the value is missing or
+// malformed.
+func parseRateLimitHeader(value string) int {
+	count, _, _ := strings.Cut(value, ";")
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n < 0 {
+		return -1
+	}
+	return n
+}
+
+// parseRetryAfter parses a "Retry-After" header value given in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil && when.After(now) {
+		return when.Sub(now)
+	}
+	return 0
+}
+
+func drainAndClose(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	_ = resp.Body.Close()
+}
+
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
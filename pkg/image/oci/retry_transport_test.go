@@ -0,0 +1,364 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anchore/stereoscope/pkg/file"
+	"github.com/anchore/stereoscope/pkg/image"
+)
+
+// newTestRetryTransport returns a retryTransport without jitter that records delays instead of sleeping.
+func newTestRetryTransport(options image.RetryOptions) (*retryTransport, *[]time.Duration) {
+	var delays []time.Duration
+	t := newRetryTransport(http.DefaultTransport, "registry.example.com", options)
+	t.random = func() float64 { return 0.5 }
+	t.sleep = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	return t, &delays
+}
+
+func Test_retryTransport_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		options       image.RetryOptions
+		responses     []func(w http.ResponseWriter)
+		wantStatus    int
+		wantRequests  int
+		wantDelays    []time.Duration
+		wantRateLimit *image.RateLimitStatus
+	}{
+		{
+			name:    "retries transient failures with exponential backoff",
+			options: image.RetryOptions{InitialBackoff: time.Second, Multiplier: 2},
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusServiceUnavailable),
+				status(http.StatusBadGateway),
+				status(http.StatusOK),
+			},
+			wantStatus:   http.StatusOK,
+			wantRequests: 3,
+			wantDelays:   []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:    "backoff is capped",
+			options: image.RetryOptions{MaxAttempts: 3, InitialBackoff: time.Second, Multiplier: 10, MaxBackoff: 5 * time.Second},
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusInternalServerError),
+				status(http.StatusInternalServerError),
+				status(http.StatusInternalServerError),
+			},
+			wantStatus:   http.StatusInternalServerError,
+			wantRequests: 3,
+			wantDelays:   []time.Duration{time.Second, 5 * time.Second},
+		},
+		{
+			name: "client errors are not retried",
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusNotFound),
+			},
+			wantStatus:   http.StatusNotFound,
+			wantRequests: 1,
+		},
+		{
+			name:    "non-idempotent requests are not retried",
+			method:  http.MethodPost,
+			options: image.RetryOptions{InitialBackoff: time.Second},
+			responses: []func(w http.ResponseWriter){
+				status(http.StatusServiceUnavailable),
+			},
+			wantStatus:   http.StatusServiceUnavailable,
+			wantRequests: 1,
+		},
+		{
+			name:    "honors retry-after",
+			options: image.RetryOptions{InitialBackoff: time.Second},
+			responses: []func(w http.ResponseWriter){
+				rateLimited("7", "0"),
+				status(http.StatusOK),
+			},
+			wantStatus:   http.StatusOK,
+			wantRequests: 2,
+			wantDelays:   []time.Duration{7 * time.Second},
+		},
+		{
+			name:    "rate limited after all attempts",
+			options: image.RetryOptions{MaxAttempts: 2, InitialBackoff: time.Second},
+			responses: []func(w http.ResponseWriter){
+				rateLimited("1", "0"),
+				rateLimited("1", "0"),
+			},
+			wantRequests:  2,
+			wantDelays:    []time.Duration{time.Second},
+			wantRateLimit: &image.RateLimitStatus{Registry: "registry.example.com", Limit: 100, Remaining: 0, RetryAfter: time.Second, Limited: true},
+		},
+		{
+			name:    "retry-after beyond the maximum fails immediately",
+			options: image.RetryOptions{MaxRetryAfter: time.Minute},
+			responses: []func(w http.ResponseWriter){
+				rateLimited("3600", "0"),
+			},
+			wantRequests:  1,
+			wantRateLimit: &image.RateLimitStatus{Registry: "registry.example.com", Limit: 100, Remaining: 0, RetryAfter: time.Hour, Limited: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				idx := int(requests.Add(1)) - 1
+				require.Less(t, idx, len(tt.responses), "unexpected request")
+				tt.responses[idx](w)
+			}))
+			defer server.Close()
+
+			transport, delays := newTestRetryTransport(tt.options)
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req, err := http.NewRequest(method, server.URL+"/v2/repo/manifests/latest", nil)
+			require.NoError(t, err)
+
+			resp, err := transport.RoundTrip(req)
+			if tt.wantRateLimit != nil {
+				require.ErrorIs(t, err, image.ErrRateLimited)
+				var rateLimitErr *image.RateLimitError
+				require.ErrorAs(t, err, &rateLimitErr)
+				assert.Equal(t, *tt.wantRateLimit, rateLimitErr.Status)
+			} else {
+				require.NoError(t, err)
+				defer resp.Body.Close()
+				assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			}
+
+			assert.Equal(t, tt.wantRequests, int(requests.Load()))
+			assert.Equal(t, tt.wantDelays, *delays)
+		})
+	}
+}
+
+func Test_retryTransport_ResumesBlobDownloads(t *testing.T) {
+	blob := bytes.Repeat([]byte("0123456789"), 10*1024)
+
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if len(ranges) == 1 {
+			// send part of the blob then drop the connection
+			w.Header().Set("Content-Length", fmt.Sprintf("%d", len(blob)))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(blob[:len(blob)/3])
+			w.(http.Flusher).Flush()
+			conn, _, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			_ = conn.Close()
+			return
+		}
+		http.ServeContent(w, r, "blob", time.Time{}, bytes.NewReader(blob))
+	}))
+	defer server.Close()
+
+	transport, delays := newTestRetryTransport(image.RetryOptions{InitialBackoff: time.Second})
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/v2/repo/blobs/sha256:abc", nil)
+	require.NoError(t, err)
+
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	contents, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, blob, contents)
+
+	require.Len(t, ranges, 2)
+	assert.Empty(t, ranges[0])
+	assert.Equal(t, fmt.Sprintf("bytes=%d-", len(blob)/3), ranges[1])
+	assert.Equal(t, []time.Duration{time.Second}, *delays)
+}
+
+func Test_retryTransport_DoesNotResumeWithoutRangeSupport(t *testing.T) {
+	blob := bytes.Repeat([]byte("0123456789"), 10*1024)
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", len(blob)))
+		w.WriteHeader(http.StatusOK)
+		if requests > 1 {
+			// ignores the range request
+			_, _ = w.Write(blob)
+			return
+		}
+		_, _ = w.Write(blob[:len(blob)/3])
+		w.(http.Flusher).Flush()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		_ = conn.Close()
+	}))
+	defer server.Close()
+
+	transport, _ := newTestRetryTransport(image.RetryOptions{})
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/v2/repo/blobs/sha256:abc", nil)
+	require.NoError(t, err)
+
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	_, err = io.ReadAll(resp.Body)
+	require.ErrorContains(t, err, "unable to resume blob download")
+}
+
+func Test_RegistryProvider_RetriesTransientFailures(t *testing.T) {
+	registryHost := makeRegistry(t)
+	pushRandomRegistryImage(t, registryHost, "my-image", "the-tag")
+
+	var failures atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/manifests/") && failures.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		r.URL.Scheme = "http"
+		r.URL.Host = registryHost
+		r.RequestURI = ""
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		for k, v := range resp.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	}))
+	defer proxy.Close()
+	proxyHost := strings.TrimPrefix(proxy.URL, "http://")
+
+	generator := file.TempDirGenerator{}
+	defer generator.Cleanup()
+
+	options := image.RegistryOptions{Retry: image.RetryOptions{InitialBackoff: time.Millisecond}}
+	provider := NewRegistryProvider(&generator, options, fmt.Sprintf("%s/my-image:the-tag", proxyHost), nil)
+	img, err := provider.Provide(context.TODO())
+	require.NoError(t, err)
+	assert.NotNil(t, img)
+}
+
+func Test_RegistryProvider_RateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		rateLimited("1", "0")(w)
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	generator := file.TempDirGenerator{}
+	defer generator.Cleanup()
+
+	options := image.RegistryOptions{Retry: image.RetryOptions{MaxAttempts: 1}}
+	provider := NewRegistryProvider(&generator, options, fmt.Sprintf("%s/my-image:the-tag", host), nil)
+	img, err := provider.Provide(context.TODO())
+	assert.Nil(t, img)
+	require.ErrorIs(t, err, image.ErrRateLimited)
+
+	var rateLimitErr *image.RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	assert.Equal(t, host, rateLimitErr.Status.Registry)
+}
+
+func Test_rateLimitStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		headers    map[string]string
+		want       image.RateLimitStatus
+		wantOK     bool
+	}{
+		{
+			name:       "no rate limit information",
+			statusCode: http.StatusOK,
+			want:       image.RateLimitStatus{Registry: "docker.io", Limit: -1, Remaining: -1},
+		},
+		{
+			name:       "docker hub headers",
+			statusCode: http.StatusOK,
+			headers:    map[string]string{"ratelimit-limit": "100;w=21600", "ratelimit-remaining": "76;w=21600"},
+			want:       image.RateLimitStatus{Registry: "docker.io", Limit: 100, Remaining: 76},
+			wantOK:     true,
+		},
+		{
+			name:       "rate limited",
+			statusCode: http.StatusTooManyRequests,
+			headers:    map[string]string{"Retry-After": "30"},
+			want:       image.RateLimitStatus{Registry: "docker.io", Limit: -1, Remaining: -1, RetryAfter: 30 * time.Second, Limited: true},
+			wantOK:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.statusCode, Header: http.Header{}}
+			for k, v := range tt.headers {
+				resp.Header.Set(k, v)
+			}
+			got, ok := rateLimitStatus("docker.io", resp)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: 0},
+		{value: "120", want: 2 * time.Minute},
+		{value: "-5", want: 0},
+		{value: "Mon, 01 Jan 2024 12:00:30 GMT", want: 30 * time.Second},
+		{value: "Mon, 01 Jan 2024 11:00:00 GMT", want: 0},
+		{value: "soon", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseRetryAfter(tt.value, now))
+		})
+	}
+}
+
+func status(code int) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.WriteHeader(code)
+	}
+}
+
+func rateLimited(retryAfter, remaining string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", retryAfter)
+		w.Header().Set("ratelimit-limit", "100;w=21600")
+		w.Header().Set("ratelimit-remaining", remaining+";w=21600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
+}
@@ -19,20 +19,23 @@ func NewArchiveProvider(tmpDirGen *file.TempDirGenerator, path string) image.Pro
 }
 
 // NewArchiveProviderWithPlatform creates a new provider instance for the specific image tarball already at the given path,
-// with the given platform information to use when loading a multiplatform image.
-func NewArchiveProviderWithPlatform(tmpDirGen *file.TempDirGenerator, path string, platform *image.Platform) image.Provider {
+// with the given platform information to use when loading a multiplatform image. Any additional metadata is applied
+// to the image before it is read, overriding any provider defaults.
+func NewArchiveProviderWithPlatform(tmpDirGen *file.TempDirGenerator, path string, platform *image.Platform, additionalMetadata ...image.AdditionalMetadata) image.Provider {
 	return &tarballImageProvider{
-		tmpDirGen: tmpDirGen,
-		path:      path,
-		platform:  platform,
+		tmpDirGen:          tmpDirGen,
+		path:               path,
+		platform:           platform,
+		additionalMetadata: additionalMetadata,
 	}
 }
 
 // tarballImageProvider is an image.Provider for an OCI image (V1) for an existing tar on disk (from a buildah push <img> oci-archive:<name>.tar command).
 type tarballImageProvider struct {
-	tmpDirGen *file.TempDirGenerator
-	path      string
-	platform  *image.Platform
+	tmpDirGen          *file.TempDirGenerator
+	path               string
+	platform           *image.Platform
+	additionalMetadata []image.AdditionalMetadata
 }
 
 func (p *tarballImageProvider) Name() string {
@@ -43,6 +46,10 @@ func (p *tarballImageProvider) Name() string {
 func (p *tarballImageProvider) Provide(ctx context.Context) (*image.Image, error) {
 	// note: we are untaring the image and using the existing directory provider, we could probably enhance the google
 	// container registry lib to do this without needing to untar to a temp dir (https://github.com/google/go-containerregistry/issues/726)
+	if fi, err := os.Stat(p.path); err != nil || fi.IsDir() {
+		return nil, fmt.Errorf("%w: %q is not a file", image.ErrProviderNotApplicable, p.path)
+	}
+
 	f, err := os.Open(p.path)
 	if err != nil {
 		return nil, fmt.Errorf("unable to open OCI tarball: %w", err)
@@ -63,5 +70,5 @@ func (p *tarballImageProvider) Provide(ctx context.Context) (*image.Image, error
 
 	log.WithFields("file", p.path, "tempDir", tempDir, "time", time.Since(startTime)).Debug("extracted OCI tar file to tempdir")
 
-	return NewDirectoryProviderWithPlatform(p.tmpDirGen, tempDir, p.platform).Provide(ctx)
+	return NewDirectoryProviderWithPlatform(p.tmpDirGen, tempDir, p.platform, p.additionalMetadata...).Provide(ctx)
 }
@@ -23,13 +23,21 @@ type Platform struct {
 	// Variant is an optional field specifying a variant of the CPU, for
 	// example `v7` to specify ARMv7 when architecture is `arm`.
 	Variant string `json:"variant,omitempty"`
+
+	// OSVersion is an optional field specifying the operating system version, for example `10.0.17763.5576` for
+	// Windows images (which only run on hosts with a matching OS version).
+	OSVersion string `json:"os.version,omitempty"`
 }
 
+// NewPlatform parses the given platform specifier in the form "os/arch/variant", where the OS version may optionally
+// be given as a suffix (e.g. "windows/amd64:10.0.17763.5576").
 func NewPlatform(specifier string) (*Platform, error) {
+	specifier, osVersion, _ := strings.Cut(specifier, ":")
 	p, err := parse(specifier)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse platform %q: %w", specifier, err)
 	}
+	p.OSVersion = osVersion
 
 	// if no OS is provided, assume linux
 	if p.OS == "" {
@@ -39,6 +47,8 @@ func NewPlatform(specifier string) (*Platform, error) {
 	return p, nil
 }
 
+// String returns the platform in the form "os/arch/variant". Note: the OS version is not included, since platform
+// specifiers with an OS version are not accepted by containerd.
 func (p *Platform) String() string {
 	if p == nil {
 		return ""
@@ -89,6 +89,14 @@ func TestNewPlatform(t *testing.T) {
 				Variant:      "valpha",
 			},
 		},
+		{
+			specifier: "windows/amd64:10.0.17763.5576",
+			want: &Platform{
+				OS:           "windows",
+				Architecture: "amd64",
+				OSVersion:    "10.0.17763.5576",
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.specifier, func(t *testing.T) {
@@ -103,3 +111,23 @@ func TestNewPlatform(t *testing.T) {
 		})
 	}
 }
+
+func TestPlatform_String(t *testing.T) {
+	tests := []struct {
+		platform *Platform
+		want     string
+	}{
+		{platform: nil, want: ""},
+		{platform: &Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}, want: "linux/arm64/v8"},
+		{
+			// the OS version must not be included, since containerd rejects such platform specifiers
+			platform: &Platform{OS: "windows", Architecture: "amd64", OSVersion: "10.0.17763.5576"},
+			want:     "windows/amd64",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.platform.String())
+		})
+	}
+}
@@ -2,9 +2,52 @@ package image
 
 import (
 	"context"
+	"errors"
 	"fmt"
 )
 
+// The following errors are wrapped by all providers (where possible) to allow callers to make decisions about
+// retrying or falling back to other sources using errors.Is.
+var (
+	// ErrProviderNotApplicable indicates that a provider has determined that the user input does not describe something
+	// the provider is able to resolve (e.g. a file-based provider given a path that does not exist). This is not
+	// considered a failure to provide the image, only an indication that other providers should be consulted.
+	ErrProviderNotApplicable = errors.New("provider is not applicable for the given input")
+
+	// ErrImageNotFound indicates that the source was reachable but the requested image does not exist.
+	ErrImageNotFound = errors.New("image not found")
+
+	// ErrUnauthorized indicates that the source rejected the request due to missing, invalid, or insufficient credentials.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrRateLimited indicates that the source rejected the request due to rate limiting.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrDaemonUnavailable indicates that the daemon backing a provider (docker, podman, containerd) could not be reached.
+	ErrDaemonUnavailable = errors.New("daemon unavailable")
+
+	// ErrUnsupportedMediaType indicates that the image (or one of its layers) has a media type that cannot be processed.
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+	// ErrMultipleImages indicates that the source contains more than one image and no single image could be selected.
+	ErrMultipleImages = errors.New("multiple images found")
+
+	// ErrOffline indicates that the request requires network access (e.g. contacting a registry or pulling an image
+	// into a daemon), which has been disabled.
+	ErrOffline = errors.New("network access is disabled (offline)")
+
+	// ErrRegistryBlocked indicates that the registry configuration (see RegistryOptions.Registries) does not permit
+	// pulling the requested image.
+	ErrRegistryBlocked = errors.New("registry is blocked")
+
+	// ErrDigestMismatch indicates that the image obtained for a digest-pinned reference does not have the requested
+	// manifest (or index) digest (see DigestMismatchError for details).
+	ErrDigestMismatch = errors.New("image digest mismatch")
+
+	// ErrReferenceNotPinned indicates that an image reference is required to be pinned to a digest but is not.
+	ErrReferenceNotPinned = errors.New("image reference is not pinned to a digest")
+)
+
 // ErrPlatformMismatch is meant to be used when a provider has positively resolved the image but the image OS or
 // architecture does not match with what was requested.
 type ErrPlatformMismatch struct {
@@ -0,0 +1,107 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ProviderAttempt describes the outcome of a single provider attempting to provide an image.
+type ProviderAttempt struct {
+	// Provider is the name of the provider that was attempted
+	Provider string
+	// Applicable indicates if the provider considered the user input to be something it could resolve
+	Applicable bool
+	// Duration is how long the attempt took
+	Duration time.Duration
+	// Err is the error returned by the provider (nil if the image was provided). When the provider is not
+	// applicable this describes why the provider was skipped.
+	Err error
+}
+
+// Succeeded indicates if the provider attempt resulted in an image.
+func (a ProviderAttempt) Succeeded() bool {
+	return a.Err == nil
+}
+
+// NewProviderAttempt creates a ProviderAttempt for the given provider result, determining applicability from the error
+// (providers that are not applicable or whose daemon is unavailable are considered skipped).
+func NewProviderAttempt(provider string, duration time.Duration, err error) ProviderAttempt {
+	return ProviderAttempt{
+		Provider:   provider,
+		Applicable: !errors.Is(err, ErrProviderNotApplicable) && !errors.Is(err, ErrDaemonUnavailable),
+		Duration:   duration,
+		Err:        err,
+	}
+}
+
+// ProviderResolutionReport describes every provider attempted while resolving user input to an image, in the order
+// the providers were attempted.
+type ProviderResolutionReport struct {
+	// UserInput is the image reference, path, or other user input being resolved
+	UserInput string
+	// Source is the explicitly requested source (empty if the source was automatically detected)
+	Source Source
+	// Attempts contains the outcome of each provider tried
+	Attempts []ProviderAttempt
+}
+
+// Provider returns the name of the provider that successfully provided the image (empty if no provider succeeded).
+func (r ProviderResolutionReport) Provider() string {
+	for _, a := range r.Attempts {
+		if a.Succeeded() {
+			return a.Provider
+		}
+	}
+	return ""
+}
+
+// DecisiveError returns the error that most likely explains why the image could not be provided: the first error from
+// a provider that considered the user input applicable. If no provider was applicable then nil is returned.
+func (r ProviderResolutionReport) DecisiveError() error {
+	for _, a := range r.Attempts {
+		if a.Applicable && a.Err != nil {
+			return a.Err
+		}
+	}
+	return nil
+}
+
+// ErrProviderResolution is returned when no provider was able to provide an image for the user input.
+type ErrProviderResolution struct {
+	Report ProviderResolutionReport
+}
+
+func (e *ErrProviderResolution) Error() string {
+	var sb strings.Builder
+	if decisive := e.Report.DecisiveError(); decisive != nil {
+		fmt.Fprintf(&sb, "unable to detect input for '%s': %v", e.Report.UserInput, decisive)
+	} else {
+		fmt.Fprintf(&sb, "unable to detect input for '%s': no provider was applicable", e.Report.UserInput)
+	}
+
+	if len(e.Report.Attempts) > 0 {
+		sb.WriteString(" (providers tried:")
+		for _, a := range e.Report.Attempts {
+			status := "failed"
+			if !a.Applicable {
+				status = "skipped"
+			}
+			fmt.Fprintf(&sb, "\n  - %s %s after %s: %v", a.Provider, status, a.Duration.Round(time.Millisecond), a.Err)
+		}
+		sb.WriteString("\n)")
+	}
+	return sb.String()
+}
+
+// Unwrap returns all provider errors, allowing errors.Is and errors.As to match against any provider error.
+func (e *ErrProviderResolution) Unwrap() []error {
+	var errs []error
+	for _, a := range e.Report.Attempts {
+		if a.Err != nil {
+			errs = append(errs, a.Err)
+		}
+	}
+	return errs
+}
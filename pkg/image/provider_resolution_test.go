@@ -0,0 +1,92 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderResolutionReport(t *testing.T) {
+	unauthorized := errors.New("401 unauthorized")
+	notAFile := fmt.Errorf("%w: %q is not a file", ErrProviderNotApplicable, "alpine:latest")
+
+	tests := []struct {
+		name         string
+		attempts     []ProviderAttempt
+		wantProvider string
+		wantDecisive error
+		wantErr      []string
+	}{
+		{
+			name: "decisive error skips non-applicable providers",
+			attempts: []ProviderAttempt{
+				NewProviderAttempt(DockerTarballSource, time.Millisecond, notAFile),
+				NewProviderAttempt(OciRegistrySource, time.Second, unauthorized),
+			},
+			wantDecisive: unauthorized,
+			wantErr: []string{
+				"unable to detect input for 'alpine:latest': 401 unauthorized",
+				"docker-archive skipped after 1ms",
+				"oci-registry failed after 1s: 401 unauthorized",
+			},
+		},
+		{
+			name: "no applicable providers",
+			attempts: []ProviderAttempt{
+				NewProviderAttempt(DockerTarballSource, time.Millisecond, notAFile),
+			},
+			wantErr: []string{
+				"unable to detect input for 'alpine:latest': no provider was applicable",
+			},
+		},
+		{
+			name: "successful provider",
+			attempts: []ProviderAttempt{
+				NewProviderAttempt(DockerTarballSource, time.Millisecond, notAFile),
+				NewProviderAttempt(DockerDaemonSource, time.Millisecond, nil),
+			},
+			wantProvider: DockerDaemonSource,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := ProviderResolutionReport{
+				UserInput: "alpine:latest",
+				Attempts:  tt.attempts,
+			}
+
+			assert.Equal(t, tt.wantProvider, report.Provider())
+			assert.Equal(t, tt.wantDecisive, report.DecisiveError())
+
+			if len(tt.wantErr) == 0 {
+				return
+			}
+
+			var err error = &ErrProviderResolution{Report: report}
+			for _, want := range tt.wantErr {
+				assert.Contains(t, err.Error(), want)
+			}
+
+			var resolutionErr *ErrProviderResolution
+			require.ErrorAs(t, err, &resolutionErr)
+			assert.ErrorIs(t, err, ErrProviderNotApplicable)
+			if tt.wantDecisive != nil {
+				assert.ErrorIs(t, err, tt.wantDecisive)
+			}
+		})
+	}
+}
+
+func TestNewProviderAttempt(t *testing.T) {
+	assert.False(t, NewProviderAttempt("p", 0, fmt.Errorf("wrapped: %w", ErrProviderNotApplicable)).Applicable)
+	assert.True(t, NewProviderAttempt("p", 0, errors.New("boom")).Applicable)
+
+	success := NewProviderAttempt("p", 0, nil)
+	assert.True(t, success.Applicable)
+	assert.True(t, success.Succeeded())
+}
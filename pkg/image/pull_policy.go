@@ -0,0 +1,33 @@
+package image
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// PullIfMissing pulls the image only when it is not present in the daemon (or does not match the requested
+	// platform). This is the default policy.
+	PullIfMissing PullPolicy = "if-missing"
+
+	// PullAlways pulls the image even when it is already present in the daemon, ensuring that a tag is current.
+	PullAlways PullPolicy = "always"
+
+	// PullNever never pulls the image, only images already present in the daemon are used.
+	PullNever PullPolicy = "never"
+)
+
+// PullPolicy determines when daemon providers (docker, podman and containerd) pull images into the daemon.
+type PullPolicy string
+
+// ParsePullPolicy returns the PullPolicy for the given (case-insensitive) value. An empty value returns PullIfMissing.
+func ParsePullPolicy(value string) (PullPolicy, error) {
+	switch policy := PullPolicy(strings.ToLower(strings.TrimSpace(value))); policy {
+	case "":
+		return PullIfMissing, nil
+	case PullIfMissing, PullAlways, PullNever:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid pull policy %q (expected one of %q, %q or %q)", value, PullAlways, PullIfMissing, PullNever)
+	}
+}
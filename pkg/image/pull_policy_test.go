@@ -0,0 +1,32 @@
+package image
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePullPolicy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    PullPolicy
+		wantErr require.ErrorAssertionFunc
+	}{
+		{value: "", want: PullIfMissing},
+		{value: "if-missing", want: PullIfMissing},
+		{value: "Always", want: PullAlways},
+		{value: " never ", want: PullNever},
+		{value: "sometimes", wantErr: require.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if tt.wantErr == nil {
+				tt.wantErr = require.NoError
+			}
+			got, err := ParsePullPolicy(tt.value)
+			tt.wantErr(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package image
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync/atomic"
+
+	"github.com/anchore/stereoscope/internal/log"
+)
+
+// ReadLimit identifies a single resource limit enforced while reading an image.
+type ReadLimit string
+
+const (
+	// LayerBytesReadLimit bounds the number of uncompressed bytes of a single layer.
+	LayerBytesReadLimit ReadLimit = "layer-bytes"
+	// ImageBytesReadLimit bounds the number of uncompressed bytes across all layers of an image.
+	ImageBytesReadLimit ReadLimit = "image-bytes"
+	// CompressionRatioReadLimit bounds the number of uncompressed bytes of a layer relative to its compressed size.
+	CompressionRatioReadLimit ReadLimit = "compression-ratio"
+	// FileCountReadLimit bounds the number of files across all layers of an image.
+	FileCountReadLimit ReadLimit = "file-count"
+	// PathDepthReadLimit bounds the number of path segments of any single file.
+	PathDepthReadLimit ReadLimit = "path-depth"
+	// PathLengthReadLimit bounds the number of bytes of the path of any single file.
+	PathLengthReadLimit ReadLimit = "path-length"
+)
+
+// ReadLimits bounds the resources consumed while reading an image, protecting against decompression bombs and other
+// untrusted content meant to exhaust disk or memory. A zero value for any limit means that limit is not enforced.
+type ReadLimits struct {
+	// MaxLayerBytes is the maximum number of uncompressed bytes for a single layer
+	MaxLayerBytes int64
+	// MaxImageBytes is the maximum number of uncompressed bytes for all layers combined
+	MaxImageBytes int64
+	// MaxCompressionRatio is the maximum ratio of uncompressed to compressed bytes for a single layer
+	MaxCompressionRatio float64
+	// MaxFiles is the maximum number of files for all layers combined
+	MaxFiles int64
+	// MaxPathDepth is the maximum number of path segments for any file
+	MaxPathDepth int
+	// MaxPathLength is the maximum length of the path for any file
+	MaxPathLength int
+	// PartialRead indicates that exceeding a limit should not fail the read. Instead, the offending content is
+	// skipped and recorded in Image.SkippedByReadLimits (and Layer.SkippedByReadLimits).
+	PartialRead bool
+}
+
+// defaultReadLimits are the limits used for images read without explicit limits (see WithReadLimits). The limits are
+// set and read concurrently, so they are only accessed atomically (nil when unbounded).
+var defaultReadLimits atomic.Pointer[ReadLimits]
+
+// SetDefaultReadLimits sets the limits used for all images read without explicit limits given via WithReadLimits.
+func SetDefaultReadLimits(limits ReadLimits) {
+	defaultReadLimits.Store(&limits)
+}
+
+// getDefaultReadLimits returns the limits set with SetDefaultReadLimits (the zero value when unbounded).
+func getDefaultReadLimits() ReadLimits {
+	if limits := defaultReadLimits.Load(); limits != nil {
+		return *limits
+	}
+	return ReadLimits{}
+}
+
+// WithReadLimits bounds the resources consumed while reading the image, overriding any default limits. This only takes
+// effect when given to New or to a provider (see stereoscope.WithReadLimits when using stereoscope.GetImage).
+func WithReadLimits(limits ReadLimits) AdditionalMetadata {
+	return func(image *Image) error {
+		image.readLimits = &readBudget{limits: limits}
+		return nil
+	}
+}
+
+// ErrReadLimitExceeded is returned when reading an image exceeds one of the configured ReadLimits. When
+// ReadLimits.PartialRead is set, these are instead recorded as the reason content was skipped.
+type ErrReadLimitExceeded struct {
+	// Limit is the limit that was exceeded
+	Limit ReadLimit
+	// LayerDigest is the digest of the layer being read when the limit was exceeded
+	LayerDigest string
+	// Path is the file path being read when the limit was exceeded (empty for layer and image limits)
+	Path string
+	// Value is the observed value that exceeded the limit
+	Value int64
+	// Max is the maximum allowed value
+	Max int64
+}
+
+func (e *ErrReadLimitExceeded) Error() string {
+	var where string
+	if e.Path != "" {
+		where = fmt.Sprintf(" path=%q", e.Path)
+	}
+	return fmt.Sprintf("read limit %q exceeded (layer=%s%s): %d > %d", e.Limit, e.LayerDigest, where, e.Value, e.Max)
+}
+
+// readBudget tracks resource usage against the configured ReadLimits across all layers of an image.
+type readBudget struct {
+	limits ReadLimits
+	// imageBytes is the number of uncompressed bytes read for all layers so far
+	imageBytes int64
+	// files is the number of files admitted for all layers so far
+	files int64
+}
+
+// checkLayerBytes returns an error if the given number of uncompressed layer bytes exceeds any byte limit.
+func (b *readBudget) checkLayerBytes(l *Layer, n, compressedSize int64) *ErrReadLimitExceeded {
+	if b == nil {
+		return nil
+	}
+	if b.limits.MaxLayerBytes > 0 && n > b.limits.MaxLayerBytes {
+		return l.limitExceeded(LayerBytesReadLimit, "", n, b.limits.MaxLayerBytes)
+	}
+	if b.limits.MaxImageBytes > 0 && b.imageBytes+n > b.limits.MaxImageBytes {
+		return l.limitExceeded(ImageBytesReadLimit, "", b.imageBytes+n, b.limits.MaxImageBytes)
+	}
+	if b.limits.MaxCompressionRatio > 0 && compressedSize > 0 {
+		maxBytes := int64(b.limits.MaxCompressionRatio * float64(compressedSize))
+		if n > maxBytes {
+			return l.limitExceeded(CompressionRatioReadLimit, "", n, maxBytes)
+		}
+	}
+	return nil
+}
+
+// consume records the given number of uncompressed layer bytes as read.
+func (b *readBudget) consume(n int64) {
+	if b == nil {
+		return
+	}
+	b.imageBytes += n
+}
+
+// checkPath returns an error if the given file path exceeds any path limit.
+func (b *readBudget) checkPath(l *Layer, p string) *ErrReadLimitExceeded {
+	if b.limits.MaxPathLength > 0 && len(p) > b.limits.MaxPathLength {
+		return l.limitExceeded(PathLengthReadLimit, p, int64(len(p)), int64(b.limits.MaxPathLength))
+	}
+	if b.limits.MaxPathDepth > 0 {
+		depth := pathDepth(p)
+		if depth > b.limits.MaxPathDepth {
+			return l.limitExceeded(PathDepthReadLimit, p, int64(depth), int64(b.limits.MaxPathDepth))
+		}
+	}
+	return nil
+}
+
+func pathDepth(p string) int {
+	cleaned := path.Clean("/" + p)
+	if cleaned == "/" {
+		return 0
+	}
+	return strings.Count(cleaned, "/")
+}
+
+func (l *Layer) limitExceeded(limit ReadLimit, p string, value, maxValue int64) *ErrReadLimitExceeded {
+	return &ErrReadLimitExceeded{
+		Limit:       limit,
+		LayerDigest: l.Metadata.Digest,
+		Path:        p,
+		Value:       value,
+		Max:         maxValue,
+	}
+}
+
+// skip records that content was skipped due to the given exceeded limit.
+func (l *Layer) skip(err *ErrReadLimitExceeded) {
+	log.WithFields("layer", err.LayerDigest, "limit", err.Limit, "path", err.Path).Warn("skipping content that exceeds read limit")
+	l.SkippedByReadLimits = append(l.SkippedByReadLimits, *err)
+}
+
+// AdmitFile checks the file at the given path against the configured read limits, returning true if the file should
+// be added to the layer. LayerReaders should call this before adding each file. When a limit is exceeded and partial
+// reads are allowed, the skipped file is recorded and false is returned; if the file count limit has been reached then
+// fs.SkipAll is returned, indicating no further files should be read from the layer. Otherwise an
+// ErrReadLimitExceeded is returned.
+func (l *Layer) AdmitFile(p string) (bool, error) {
+	b := l.readLimits
+	if b == nil {
+		return true, nil
+	}
+
+	if b.limits.MaxFiles > 0 && b.files >= b.limits.MaxFiles {
+		err := l.limitExceeded(FileCountReadLimit, p, b.files+1, b.limits.MaxFiles)
+		if !b.limits.PartialRead {
+			return false, err
+		}
+		l.skip(err)
+		return false, fs.SkipAll
+	}
+
+	if err := b.checkPath(l, p); err != nil {
+		if !b.limits.PartialRead {
+			return false, err
+		}
+		l.skip(err)
+		return false, nil
+	}
+
+	b.files++
+	return true, nil
+}
+
+// limitedLayerReader is an io.Reader that fails once the bytes read exceed the byte limits of the read budget.
+type limitedLayerReader struct {
+	budget         *readBudget
+	layer          *Layer
+	reader         io.Reader
+	compressedSize int64
+	n              int64
+}
+
+func (r *limitedLayerReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += int64(n)
+	if limitErr := r.budget.checkLayerBytes(r.layer, r.n, r.compressedSize); limitErr != nil {
+		return n, limitErr
+	}
+	return n, err
+}
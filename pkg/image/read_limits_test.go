@@ -0,0 +1,208 @@
+package image
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anchore/stereoscope/pkg/file"
+)
+
+func TestReadLimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		layers      func(t *testing.T) []v1.Layer
+		limits      ReadLimits
+		wantLimit   ReadLimit
+		wantSkipped []ReadLimit
+		wantPaths   []string
+	}{
+		{
+			name: "no limits",
+			layers: func(t *testing.T) []v1.Layer {
+				return []v1.Layer{tarLayer(t, map[string]string{"a.txt": "a", "b/c.txt": "c"})}
+			},
+			wantPaths: []string{"/a.txt", "/b/c.txt"},
+		},
+		{
+			name: "file count exceeded",
+			layers: func(t *testing.T) []v1.Layer {
+				return []v1.Layer{
+					tarLayer(t, map[string]string{"a.txt": "a"}),
+					tarLayer(t, map[string]string{"b.txt": "b"}),
+				}
+			},
+			limits:    ReadLimits{MaxFiles: 1},
+			wantLimit: FileCountReadLimit,
+		},
+		{
+			name: "file count exceeded with partial read",
+			layers: func(t *testing.T) []v1.Layer {
+				return []v1.Layer{
+					tarLayer(t, map[string]string{"a.txt": "a"}),
+					tarLayer(t, map[string]string{"b.txt": "b"}),
+				}
+			},
+			limits:      ReadLimits{MaxFiles: 1, PartialRead: true},
+			wantSkipped: []ReadLimit{FileCountReadLimit},
+			wantPaths:   []string{"/a.txt"},
+		},
+		{
+			name: "path depth exceeded",
+			layers: func(t *testing.T) []v1.Layer {
+				return []v1.Layer{tarLayer(t, map[string]string{"a/b/c/d.txt": "d"})}
+			},
+			limits:    ReadLimits{MaxPathDepth: 3},
+			wantLimit: PathDepthReadLimit,
+		},
+		{
+			name: "path depth exceeded with partial read",
+			layers: func(t *testing.T) []v1.Layer {
+				return []v1.Layer{tarLayer(t, map[string]string{"a/b/c/d.txt": "d", "a/b/c.txt": "c"})}
+			},
+			limits:      ReadLimits{MaxPathDepth: 3, PartialRead: true},
+			wantSkipped: []ReadLimit{PathDepthReadLimit},
+			wantPaths:   []string{"/a/b/c.txt"},
+		},
+		{
+			name: "path length exceeded",
+			layers: func(t *testing.T) []v1.Layer {
+				return []v1.Layer{tarLayer(t, map[string]string{strings.Repeat("a", 50): "a"})}
+			},
+			limits:    ReadLimits{MaxPathLength: 20},
+			wantLimit: PathLengthReadLimit,
+		},
+		{
+			name: "layer bytes exceeded",
+			layers: func(t *testing.T) []v1.Layer {
+				return []v1.Layer{tarLayer(t, map[string]string{"a.txt": "a"})}
+			},
+			limits:    ReadLimits{MaxLayerBytes: 100},
+			wantLimit: LayerBytesReadLimit,
+		},
+		{
+			name: "layer bytes exceeded with partial read",
+			layers: func(t *testing.T) []v1.Layer {
+				return []v1.Layer{
+					tarLayer(t, map[string]string{"a.txt": "a"}),
+					tarLayer(t, map[string]string{"b.txt": strings.Repeat("b", 10*1024)}),
+				}
+			},
+			limits:      ReadLimits{MaxLayerBytes: 4 * 1024, PartialRead: true},
+			wantSkipped: []ReadLimit{LayerBytesReadLimit},
+			wantPaths:   []string{"/a.txt"},
+		},
+		{
+			name: "image bytes exceeded",
+			layers: func(t *testing.T) []v1.Layer {
+				return []v1.Layer{
+					tarLayer(t, map[string]string{"a.txt": "a"}),
+					tarLayer(t, map[string]string{"b.txt": "b"}),
+				}
+			},
+			// each layer is 2 KB uncompressed (a header block, a content block, and two trailing zero blocks)
+			limits:    ReadLimits{MaxImageBytes: 3 * 1024},
+			wantLimit: ImageBytesReadLimit,
+		},
+		{
+			name: "compression ratio exceeded",
+			layers: func(t *testing.T) []v1.Layer {
+				return []v1.Layer{tarLayer(t, map[string]string{"zeros": strings.Repeat("\x00", 1024*1024)})}
+			},
+			limits:    ReadLimits{MaxCompressionRatio: 10},
+			wantLimit: CompressionRatioReadLimit,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := readTestImage(t, tt.layers(t), WithReadLimits(tt.limits))
+			if tt.wantLimit != "" {
+				var limitErr *ErrReadLimitExceeded
+				require.ErrorAs(t, err, &limitErr)
+				assert.Equal(t, tt.wantLimit, limitErr.Limit)
+				return
+			}
+			require.NoError(t, err)
+
+			var skipped []ReadLimit
+			for _, s := range img.SkippedByReadLimits {
+				skipped = append(skipped, s.Limit)
+			}
+			assert.Equal(t, tt.wantSkipped, skipped)
+
+			var paths []string
+			for _, ref := range img.SquashedTree().AllFiles(file.TypeRegular) {
+				paths = append(paths, string(ref.RealPath))
+			}
+			assert.ElementsMatch(t, tt.wantPaths, paths)
+		})
+	}
+}
+
+func TestSetDefaultReadLimits(t *testing.T) {
+	original := getDefaultReadLimits()
+	t.Cleanup(func() {
+		SetDefaultReadLimits(original)
+	})
+
+	SetDefaultReadLimits(ReadLimits{MaxFiles: 1})
+
+	layers := []v1.Layer{tarLayer(t, map[string]string{"a.txt": "a", "b.txt": "b"})}
+
+	_, err := readTestImage(t, layers)
+	var limitErr *ErrReadLimitExceeded
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, FileCountReadLimit, limitErr.Limit)
+
+	// explicit limits take precedence over the defaults
+	_, err = readTestImage(t, layers, WithReadLimits(ReadLimits{}))
+	require.NoError(t, err)
+}
+
+func TestSetDefaultReadLimits_Concurrent(t *testing.T) {
+	original := getDefaultReadLimits()
+	t.Cleanup(func() {
+		SetDefaultReadLimits(original)
+	})
+
+	layers := []v1.Layer{tarLayer(t, map[string]string{"a.txt": "a"})}
+
+	// note: this is meant to be run with the race detector
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			SetDefaultReadLimits(ReadLimits{MaxFiles: 10})
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := readTestImage(t, layers)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+}
+
+func Test_pathDepth(t *testing.T) {
+	tests := []struct {
+		path string
+		want int
+	}{
+		{path: "/", want: 0},
+		{path: ".", want: 0},
+		{path: "a.txt", want: 1},
+		{path: "/a/b/c.txt", want: 3},
+		{path: "./a/b/", want: 2},
+		{path: "a/../../b", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, pathDepth(tt.path))
+		})
+	}
+}
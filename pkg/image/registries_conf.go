@@ -0,0 +1,310 @@
+package image
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+
+	"github.com/anchore/stereoscope/internal/log"
+)
+
+const (
+	// PullFromMirrorAll indicates that a mirror is used for references by tag and by digest.
+	PullFromMirrorAll = "all"
+
+	// PullFromMirrorDigestOnly indicates that a mirror is only used for references by digest.
+	PullFromMirrorDigestOnly = "digest-only"
+
+	// PullFromMirrorTagOnly indicates that a mirror is only used for references by tag.
+	PullFromMirrorTagOnly = "tag-only"
+
+	dockerHubRegistry = "docker.io"
+)
+
+// RegistriesConf is the subset of a containers registries.conf file (v2 format) that is relevant for pulling images.
+// The values are meant to be used as RegistryOptions.Registries and RegistryOptions.UnqualifiedSearchRegistries.
+type RegistriesConf struct {
+	UnqualifiedSearchRegistries []string         `toml:"unqualified-search-registries"`
+	Registries                  []RegistryConfig `toml:"registry"`
+}
+
+// RegistryConfig describes how image references matching a prefix are pulled (a "[[registry]]" table within a
+// registries.conf file).
+type RegistryConfig struct {
+	// Prefix selects the image references this configuration applies to: a registry hostname (e.g. "docker.io"), a
+	// repository namespace (e.g. "docker.io/library"), or a wildcard subdomain (e.g. "*.example.com"). When empty the
+	// Location is used as the prefix.
+	Prefix string `toml:"prefix"`
+	// Location rewrites the matched prefix (e.g. "internal.example.com/dockerhub"). When empty the prefix is used as-is.
+	// Wildcard prefixes cannot be rewritten.
+	Location string `toml:"location"`
+	// Insecure allows HTTP and unverified TLS connections to the location.
+	Insecure bool `toml:"insecure"`
+	// Blocked prevents pulling any image reference matching the prefix.
+	Blocked bool `toml:"blocked"`
+	// MirrorByDigestOnly restricts all mirrors to references by digest (unless overridden per mirror).
+	MirrorByDigestOnly bool `toml:"mirror-by-digest-only"`
+	// Mirrors are attempted (in order) before the location.
+	Mirrors []RegistryMirror `toml:"mirror"`
+}
+
+// RegistryMirror is an alternate location (e.g. a pull-through cache) that images may be pulled from.
+type RegistryMirror struct {
+	// Location replaces the matched prefix (e.g. "mirror.example.com/dockerhub").
+	Location string `toml:"location"`
+	// Insecure allows HTTP and unverified TLS connections to the mirror.
+	Insecure bool `toml:"insecure"`
+	// PullFromMirror restricts the references the mirror is used for (PullFromMirrorAll, PullFromMirrorDigestOnly or
+	// PullFromMirrorTagOnly). When empty all references are pulled from the mirror (unless MirrorByDigestOnly is set).
+	PullFromMirror string `toml:"pull-from-mirror"`
+}
+
+// PullSource is a location an image reference may be pulled from.
+type PullSource struct {
+	// Reference is the fully qualified image reference to pull (e.g. "mirror.example.com/library/alpine:3.18").
+	Reference string
+	// Canonical is the fully qualified image reference the source stands in for, that is, after any rewrites but
+	// without any mirror applied (e.g. "docker.io/library/alpine:3.18").
+	Canonical string
+	// Insecure indicates that HTTP and unverified TLS connections are allowed for this source.
+	Insecure bool
+	// Mirror indicates that the reference was derived from a configured mirror.
+	Mirror bool
+}
+
+// ReadRegistriesConf reads and validates a registries.conf file (v2 format).
+func ReadRegistriesConf(path string) (*RegistriesConf, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read registries config %q: %w", path, err)
+	}
+
+	conf, err := ParseRegistriesConf(contents)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registries config %q: %w", path, err)
+	}
+	return conf, nil
+}
+
+// ParseRegistriesConf parses and validates the contents of a registries.conf file (v2 format).
+func ParseRegistriesConf(contents []byte) (*RegistriesConf, error) {
+	var conf RegistriesConf
+	if err := toml.Unmarshal(contents, &conf); err != nil {
+		return nil, fmt.Errorf("unable to parse registries config: %w", err)
+	}
+
+	// the v1 format describes registries within "[registries.search]", "[registries.insecure]" (etc.) tables
+	var v1 struct {
+		Registries map[string]any `toml:"registries"`
+	}
+	if err := toml.Unmarshal(contents, &v1); err == nil && len(v1.Registries) > 0 {
+		return nil, fmt.Errorf("the v1 registries config format is not supported")
+	}
+
+	for _, r := range conf.Registries {
+		if err := r.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return &conf, nil
+}
+
+// PullSources returns the locations to pull the given image reference from, in the order they should be attempted.
+// Unqualified references (e.g. "alpine") are qualified with each of the UnqualifiedSearchRegistries (or docker.io if
+// none are configured), then the most specific matching RegistryConfig is applied: the mirrors come first, followed
+// by the (possibly rewritten) location. ErrRegistryBlocked is returned if every candidate is blocked.
+func (r RegistryOptions) PullSources(imageStr string) ([]PullSource, error) {
+	for _, c := range r.Registries {
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var sources []PullSource
+	var blocked []string
+	for _, candidate := range r.qualifiedReferences(imageStr) {
+		c := r.registryConfig(candidate)
+		if c == nil {
+			sources = append(sources, PullSource{Reference: candidate, Canonical: candidate})
+			continue
+		}
+
+		if c.config.Blocked {
+			log.WithFields("image", candidate, "prefix", c.config.prefix()).Debug("registry is blocked, skipping")
+			blocked = append(blocked, candidate)
+			continue
+		}
+
+		sources = append(sources, c.sources(candidate)...)
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrRegistryBlocked, strings.Join(blocked, ", "))
+	}
+	return sources, nil
+}
+
+// qualifiedReferences returns the fully qualified references to consider for the given image reference.
+func (r RegistryOptions) qualifiedReferences(imageStr string) []string {
+	if isQualifiedReference(imageStr) {
+		return []string{imageStr}
+	}
+
+	registries := r.UnqualifiedSearchRegistries
+	if len(registries) == 0 {
+		registries = []string{dockerHubRegistry}
+	}
+
+	var refs []string
+	for _, registry := range registries {
+		registry = strings.TrimSuffix(registry, "/")
+		name := imageStr
+		if registry == dockerHubRegistry && !strings.Contains(imageStr, "/") {
+			// official docker hub images live within the "library" namespace
+			name = "library/" + imageStr
+		}
+		refs = append(refs, registry+"/"+name)
+	}
+	return refs
+}
+
+type matchedRegistryConfig struct {
+	config  RegistryConfig
+	matched string
+}
+
+// registryConfig returns the most specific configuration for the given (qualified) reference. Exact prefixes are
+// preferred over wildcard prefixes, then longer prefixes over shorter ones.
+func (r RegistryOptions) registryConfig(ref string) *matchedRegistryConfig {
+	var matches []matchedRegistryConfig
+	for _, c := range r.Registries {
+		if matched, ok := c.match(ref); ok {
+			matches = append(matches, matchedRegistryConfig{config: c, matched: matched})
+		}
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		iWildcard := matches[i].config.isWildcard()
+		jWildcard := matches[j].config.isWildcard()
+		if iWildcard != jWildcard {
+			return !iWildcard
+		}
+		return len(matches[i].config.prefix()) > len(matches[j].config.prefix())
+	})
+	return &matches[0]
+}
+
+// sources returns the mirrors applicable to the given reference followed by the location.
+func (m matchedRegistryConfig) sources(ref string) []PullSource {
+	remainder := strings.TrimPrefix(ref, m.matched)
+
+	canonical := ref
+	if m.config.Location != "" {
+		canonical = m.config.Location + remainder
+	}
+
+	byDigest := strings.Contains(ref, "@")
+
+	var sources []PullSource
+	for _, mirror := range m.config.Mirrors {
+		mode := mirror.PullFromMirror
+		if mode == "" && m.config.MirrorByDigestOnly {
+			mode = PullFromMirrorDigestOnly
+		}
+		if (mode == PullFromMirrorDigestOnly && !byDigest) || (mode == PullFromMirrorTagOnly && byDigest) {
+			continue
+		}
+
+		sources = append(sources, PullSource{
+			Reference: mirror.Location + remainder,
+			Canonical: canonical,
+			Insecure:  mirror.Insecure,
+			Mirror:    true,
+		})
+	}
+
+	return append(sources, PullSource{
+		Reference: canonical,
+		Canonical: canonical,
+		Insecure:  m.config.Insecure,
+	})
+}
+
+// match returns the portion of the given reference matched by the prefix.
+func (c RegistryConfig) match(ref string) (string, bool) {
+	prefix := c.prefix()
+	if c.isWildcard() {
+		host, _, _ := strings.Cut(ref, "/")
+		if strings.HasSuffix(host, prefix[1:]) {
+			return host, true
+		}
+		return "", false
+	}
+
+	if !strings.HasPrefix(ref, prefix) {
+		return "", false
+	}
+
+	rest := ref[len(prefix):]
+	switch {
+	case rest == "", strings.HasPrefix(rest, "/"):
+		return prefix, true
+	case strings.Contains(prefix, "/") && (strings.HasPrefix(rest, ":") || strings.HasPrefix(rest, "@")):
+		// a repository prefix may be followed by a tag or digest (but a hostname prefix may not be followed by a port)
+		return prefix, true
+	}
+	return "", false
+}
+
+func (c RegistryConfig) prefix() string {
+	if c.Prefix != "" {
+		return c.Prefix
+	}
+	return c.Location
+}
+
+func (c RegistryConfig) isWildcard() bool {
+	return strings.HasPrefix(c.prefix(), "*.")
+}
+
+func (c RegistryConfig) validate() error {
+	prefix := c.prefix()
+	switch {
+	case prefix == "":
+		return fmt.Errorf("registry config must have a prefix or location")
+	case strings.Contains(prefix, "*") && (!c.isWildcard() || strings.ContainsAny(prefix[1:], "*/")):
+		return fmt.Errorf("invalid wildcard prefix %q: only a leading \"*.\" on a hostname is supported", prefix)
+	case c.isWildcard() && c.Location != "":
+		return fmt.Errorf("registry config with wildcard prefix %q cannot have a location", prefix)
+	}
+
+	for _, m := range c.Mirrors {
+		if m.Location == "" {
+			return fmt.Errorf("mirror for prefix %q must have a location", prefix)
+		}
+		switch m.PullFromMirror {
+		case "", PullFromMirrorAll, PullFromMirrorDigestOnly, PullFromMirrorTagOnly:
+		default:
+			return fmt.Errorf("invalid pull-from-mirror value %q for mirror %q", m.PullFromMirror, m.Location)
+		}
+		if m.PullFromMirror != "" && c.MirrorByDigestOnly {
+			return fmt.Errorf("mirror %q cannot set pull-from-mirror when mirror-by-digest-only is set for prefix %q", m.Location, prefix)
+		}
+	}
+	return nil
+}
+
+// isQualifiedReference indicates if the given image reference starts with a registry hostname.
+func isQualifiedReference(imageStr string) bool {
+	host, _, found := strings.Cut(imageStr, "/")
+	if !found {
+		return false
+	}
+	return host == "localhost" || strings.ContainsAny(host, ".:")
+}
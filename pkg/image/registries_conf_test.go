@@ -0,0 +1,366 @@
+package image
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadRegistriesConf(t *testing.T) {
+	contents := `
+unqualified-search-registries = ["registry.example.com", "docker.io"]
+
+[[registry]]
+prefix = "docker.io"
+location = "docker.io"
+
+[[registry.mirror]]
+location = "mirror.example.com/dockerhub"
+
+[[registry.mirror]]
+location = "cache.example.com:5000"
+insecure = true
+pull-from-mirror = "digest-only"
+
+[[registry]]
+location = "legacy.example.com"
+blocked = true
+`
+	path := filepath.Join(t.TempDir(), "registries.conf")
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+
+	conf, err := ReadRegistriesConf(path)
+	require.NoError(t, err)
+
+	expected := &RegistriesConf{
+		UnqualifiedSearchRegistries: []string{"registry.example.com", "docker.io"},
+		Registries: []RegistryConfig{
+			{
+				Prefix:   "docker.io",
+				Location: "docker.io",
+				Mirrors: []RegistryMirror{
+					{Location: "mirror.example.com/dockerhub"},
+					{Location: "cache.example.com:5000", Insecure: true, PullFromMirror: PullFromMirrorDigestOnly},
+				},
+			},
+			{
+				Location: "legacy.example.com",
+				Blocked:  true,
+			},
+		},
+	}
+	assert.Equal(t, expected, conf)
+}
+
+func TestParseRegistriesConf_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		wantErr  string
+	}{
+		{
+			name:     "malformed toml",
+			contents: `[[registry]`,
+			wantErr:  "unable to parse registries config",
+		},
+		{
+			name: "v1 format",
+			contents: `
+[registries.search]
+registries = ["docker.io"]
+`,
+			wantErr: "v1 registries config format is not supported",
+		},
+		{
+			name: "missing prefix and location",
+			contents: `
+[[registry]]
+insecure = true
+`,
+			wantErr: "must have a prefix or location",
+		},
+		{
+			name: "wildcard with location",
+			contents: `
+[[registry]]
+prefix = "*.example.com"
+location = "other.example.com"
+`,
+			wantErr: "cannot have a location",
+		},
+		{
+			name: "wildcard within namespace",
+			contents: `
+[[registry]]
+prefix = "example.com/*"
+`,
+			wantErr: "invalid wildcard prefix",
+		},
+		{
+			name: "mirror without location",
+			contents: `
+[[registry]]
+prefix = "docker.io"
+[[registry.mirror]]
+insecure = true
+`,
+			wantErr: "must have a location",
+		},
+		{
+			name: "invalid pull-from-mirror",
+			contents: `
+[[registry]]
+prefix = "docker.io"
+[[registry.mirror]]
+location = "mirror.example.com"
+pull-from-mirror = "sometimes"
+`,
+			wantErr: "invalid pull-from-mirror value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRegistriesConf([]byte(tt.contents))
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestRegistryOptions_PullSources(t *testing.T) {
+	dockerHubMirrors := RegistryConfig{
+		Prefix: "docker.io",
+		Mirrors: []RegistryMirror{
+			{Location: "mirror.example.com/dockerhub"},
+			{Location: "digests.example.com", Insecure: true, PullFromMirror: PullFromMirrorDigestOnly},
+			{Location: "tags.example.com", PullFromMirror: PullFromMirrorTagOnly},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		options RegistryOptions
+		image   string
+		want    []PullSource
+		wantErr error
+	}{
+		{
+			name:  "no configuration",
+			image: "registry.example.com/app:1.0",
+			want: []PullSource{
+				{Reference: "registry.example.com/app:1.0", Canonical: "registry.example.com/app:1.0"},
+			},
+		},
+		{
+			name:  "unqualified reference defaults to docker.io",
+			image: "alpine:3.18",
+			want: []PullSource{
+				{Reference: "docker.io/library/alpine:3.18", Canonical: "docker.io/library/alpine:3.18"},
+			},
+		},
+		{
+			name:    "mirrors by tag",
+			options: RegistryOptions{Registries: []RegistryConfig{dockerHubMirrors}},
+			image:   "docker.io/library/alpine:3.18",
+			want: []PullSource{
+				{Reference: "mirror.example.com/dockerhub/library/alpine:3.18", Canonical: "docker.io/library/alpine:3.18", Mirror: true},
+				{Reference: "tags.example.com/library/alpine:3.18", Canonical: "docker.io/library/alpine:3.18", Mirror: true},
+				{Reference: "docker.io/library/alpine:3.18", Canonical: "docker.io/library/alpine:3.18"},
+			},
+		},
+		{
+			name:    "mirrors by digest",
+			options: RegistryOptions{Registries: []RegistryConfig{dockerHubMirrors}},
+			image:   "docker.io/library/alpine@sha256:95cf004f559831017cdf4628aaf1bb30133677be8702a8c5f2994629f637a209",
+			want: []PullSource{
+				{
+					Reference: "mirror.example.com/dockerhub/library/alpine@sha256:95cf004f559831017cdf4628aaf1bb30133677be8702a8c5f2994629f637a209",
+					Canonical: "docker.io/library/alpine@sha256:95cf004f559831017cdf4628aaf1bb30133677be8702a8c5f2994629f637a209",
+					Mirror:    true,
+				},
+				{
+					Reference: "digests.example.com/library/alpine@sha256:95cf004f559831017cdf4628aaf1bb30133677be8702a8c5f2994629f637a209",
+					Canonical: "docker.io/library/alpine@sha256:95cf004f559831017cdf4628aaf1bb30133677be8702a8c5f2994629f637a209",
+					Insecure:  true,
+					Mirror:    true,
+				},
+				{
+					Reference: "docker.io/library/alpine@sha256:95cf004f559831017cdf4628aaf1bb30133677be8702a8c5f2994629f637a209",
+					Canonical: "docker.io/library/alpine@sha256:95cf004f559831017cdf4628aaf1bb30133677be8702a8c5f2994629f637a209",
+				},
+			},
+		},
+		{
+			name: "mirror by digest only",
+			options: RegistryOptions{Registries: []RegistryConfig{
+				{Prefix: "docker.io", MirrorByDigestOnly: true, Mirrors: []RegistryMirror{{Location: "mirror.example.com"}}},
+			}},
+			image: "docker.io/library/alpine:3.18",
+			want: []PullSource{
+				{Reference: "docker.io/library/alpine:3.18", Canonical: "docker.io/library/alpine:3.18"},
+			},
+		},
+		{
+			name: "rewrite location",
+			options: RegistryOptions{Registries: []RegistryConfig{
+				{Prefix: "example.com/team", Location: "internal.example.com/mirrored/team", Insecure: true},
+			}},
+			image: "example.com/team/app:1.0",
+			want: []PullSource{
+				{Reference: "internal.example.com/mirrored/team/app:1.0", Canonical: "internal.example.com/mirrored/team/app:1.0", Insecure: true},
+			},
+		},
+		{
+			name: "most specific prefix wins",
+			options: RegistryOptions{Registries: []RegistryConfig{
+				{Prefix: "*.example.com", Mirrors: []RegistryMirror{{Location: "wildcard.mirror.com"}}},
+				{Prefix: "example.com", Mirrors: []RegistryMirror{{Location: "host.mirror.com"}}},
+				{Prefix: "example.com/team", Mirrors: []RegistryMirror{{Location: "team.mirror.com"}}},
+			}},
+			image: "example.com/team/app:1.0",
+			want: []PullSource{
+				{Reference: "team.mirror.com/app:1.0", Canonical: "example.com/team/app:1.0", Mirror: true},
+				{Reference: "example.com/team/app:1.0", Canonical: "example.com/team/app:1.0"},
+			},
+		},
+		{
+			name: "prefix must match whole path segments",
+			options: RegistryOptions{Registries: []RegistryConfig{
+				{Prefix: "example.com/team", Mirrors: []RegistryMirror{{Location: "team.mirror.com"}}},
+				{Prefix: "example.com", Mirrors: []RegistryMirror{{Location: "host.mirror.com"}}},
+			}},
+			image: "example.com/teammates/app:1.0",
+			want: []PullSource{
+				{Reference: "host.mirror.com/teammates/app:1.0", Canonical: "example.com/teammates/app:1.0", Mirror: true},
+				{Reference: "example.com/teammates/app:1.0", Canonical: "example.com/teammates/app:1.0"},
+			},
+		},
+		{
+			name: "hostname prefix does not match other ports",
+			options: RegistryOptions{Registries: []RegistryConfig{
+				{Prefix: "example.com", Blocked: true},
+			}},
+			image: "example.com:5000/app:1.0",
+			want: []PullSource{
+				{Reference: "example.com:5000/app:1.0", Canonical: "example.com:5000/app:1.0"},
+			},
+		},
+		{
+			name: "wildcard prefix",
+			options: RegistryOptions{Registries: []RegistryConfig{
+				{Prefix: "*.example.com", Mirrors: []RegistryMirror{{Location: "mirror.internal"}}},
+			}},
+			image: "registry.example.com/app:1.0",
+			want: []PullSource{
+				{Reference: "mirror.internal/app:1.0", Canonical: "registry.example.com/app:1.0", Mirror: true},
+				{Reference: "registry.example.com/app:1.0", Canonical: "registry.example.com/app:1.0"},
+			},
+		},
+		{
+			name: "unqualified search registries",
+			options: RegistryOptions{
+				UnqualifiedSearchRegistries: []string{"registry.example.com", "docker.io"},
+				Registries: []RegistryConfig{
+					{Prefix: "docker.io", Mirrors: []RegistryMirror{{Location: "mirror.example.com"}}},
+				},
+			},
+			image: "alpine",
+			want: []PullSource{
+				{Reference: "registry.example.com/alpine", Canonical: "registry.example.com/alpine"},
+				{Reference: "mirror.example.com/library/alpine", Canonical: "docker.io/library/alpine", Mirror: true},
+				{Reference: "docker.io/library/alpine", Canonical: "docker.io/library/alpine"},
+			},
+		},
+		{
+			name: "blocked search registries are skipped",
+			options: RegistryOptions{
+				UnqualifiedSearchRegistries: []string{"registry.example.com", "docker.io"},
+				Registries: []RegistryConfig{
+					{Prefix: "registry.example.com", Blocked: true},
+				},
+			},
+			image: "team/app",
+			want: []PullSource{
+				{Reference: "docker.io/team/app", Canonical: "docker.io/team/app"},
+			},
+		},
+		{
+			name: "blocked",
+			options: RegistryOptions{Registries: []RegistryConfig{
+				{Prefix: "docker.io", Blocked: true, Mirrors: []RegistryMirror{{Location: "mirror.example.com"}}},
+			}},
+			image:   "alpine",
+			wantErr: ErrRegistryBlocked,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.options.PullSources(tt.image)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRegistryOptions_qualifiedReferences(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{
+			image: "alpine:sometag",
+			want:  "docker.io/library/alpine:sometag",
+		},
+		{
+			image: "alpine:latest",
+			want:  "docker.io/library/alpine:latest",
+		},
+		{
+			image: "alpine",
+			want:  "docker.io/library/alpine",
+		},
+		{
+			image: "team/app:1.0",
+			want:  "docker.io/team/app:1.0",
+		},
+		{
+			image: "registry.place.io/thing:version",
+			want:  "registry.place.io/thing:version",
+		},
+		{
+			image: "127.0.0.1/thing:version",
+			want:  "127.0.0.1/thing:version",
+		},
+		{
+			image: "127.0.0.1:1234/thing:version",
+			want:  "127.0.0.1:1234/thing:version",
+		},
+		{
+			image: "localhost/thing:version",
+			want:  "localhost/thing:version",
+		},
+		{
+			image: "localhost:1234/thing:version",
+			want:  "localhost:1234/thing:version",
+		},
+		{
+			image: "alpine@sha256:95cf004f559831017cdf4628aaf1bb30133677be8702a8c5f2994629f637a209",
+			want:  "docker.io/library/alpine@sha256:95cf004f559831017cdf4628aaf1bb30133677be8702a8c5f2994629f637a209",
+		},
+		{
+			image: "alpine:sometag@sha256:95cf004f559831017cdf4628aaf1bb30133677be8702a8c5f2994629f637a209",
+			want:  "docker.io/library/alpine:sometag@sha256:95cf004f559831017cdf4628aaf1bb30133677be8702a8c5f2994629f637a209",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			assert.Equal(t, []string{tt.want}, RegistryOptions{}.qualifiedReferences(tt.image))
+		})
+	}
+}
@@ -24,6 +24,19 @@ type RegistryOptions struct {
 	Credentials           []RegistryCredentials
 	Keychain              authn.Keychain
 	CAFileOrDir           string
+
+	// Registries are the mirror and rewrite rules applied to image references before pulling (see PullSources).
+	Registries []RegistryConfig
+	// UnqualifiedSearchRegistries are the registries (in order) consulted for image references without a registry
+	// hostname (e.g. "alpine"). When empty such references are resolved against docker.io.
+	UnqualifiedSearchRegistries []string
+
+	// Retry configures how failed registry requests are retried (only applies to the OCI registry provider).
+	Retry RetryOptions
+
+	// Offline forbids all registry access: registry requests fail with ErrOffline and the containerd provider will
+	// not pull images that are missing from the daemon.
+	Offline bool
 }
 
 type credentialSelection struct {
@@ -0,0 +1,96 @@
+package image
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRetryMaxAttempts    = 4
+	defaultRetryInitialBackoff = time.Second
+	defaultRetryMaxBackoff     = 30 * time.Second
+	defaultRetryMultiplier     = 2.0
+	defaultRetryJitter         = 0.2
+	defaultRetryMaxRetryAfter  = time.Minute
+)
+
+// RetryOptions configures how registry requests (for manifests and blobs) are retried after transient failures (5xx
+// responses, connection resets, and rate limiting). Zero values are replaced with defaults (see WithDefaults).
+type RetryOptions struct {
+	// MaxAttempts is the total number of attempts made for a single request (1 disables retries).
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, which grows by Multiplier for each subsequent retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries.
+	MaxBackoff time.Duration
+	// Multiplier is the factor the delay grows by after each retry.
+	Multiplier float64
+	// Jitter randomizes each delay by up to the given fraction (e.g. 0.2 for +/-20%).
+	Jitter float64
+	// MaxRetryAfter caps how long a "Retry-After" response header is honored for. Rate limited requests asking for a
+	// longer delay fail immediately with a RateLimitError.
+	MaxRetryAfter time.Duration
+}
+
+// WithDefaults returns a copy of the options with defaults applied to all unset values.
+func (o RetryOptions) WithDefaults() RetryOptions {
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = defaultRetryMaxAttempts
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = defaultRetryInitialBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = defaultRetryMaxBackoff
+	}
+	if o.Multiplier < 1 {
+		o.Multiplier = defaultRetryMultiplier
+	}
+	if o.Jitter <= 0 {
+		o.Jitter = defaultRetryJitter
+	}
+	if o.MaxRetryAfter <= 0 {
+		o.MaxRetryAfter = defaultRetryMaxRetryAfter
+	}
+	return o
+}
+
+// RateLimitStatus describes the rate limit state reported by a registry (e.g. via the docker hub "ratelimit-limit"
+// and "ratelimit-remaining" response headers). This is published with the event.RegistryRateLimit event.
+type RateLimitStatus struct {
+	// Registry is the host the status was reported by.
+	Registry string
+	// Limit is the number of requests permitted within the rate limit window (-1 when not reported).
+	Limit int
+	// Remaining is the number of requests remaining within the rate limit window (-1 when not reported).
+	Remaining int
+	// RetryAfter is the delay requested by the registry before retrying (only set when a request was rejected).
+	RetryAfter time.Duration
+	// Limited indicates that a request was rejected due to rate limiting.
+	Limited bool
+}
+
+// RateLimitError is returned when a registry request is still rate limited after all retries (or when the registry
+// asks for a delay longer than RetryOptions.MaxRetryAfter). It wraps ErrRateLimited.
+type RateLimitError struct {
+	Status RateLimitStatus
+}
+
+func (e *RateLimitError) Error() string {
+	details := []string{fmt.Sprintf("registry=%q", e.Status.Registry)}
+	if e.Status.RetryAfter > 0 {
+		details = append(details, fmt.Sprintf("retry-after=%s", e.Status.RetryAfter))
+	}
+	if e.Status.Remaining >= 0 {
+		details = append(details, fmt.Sprintf("remaining=%d", e.Status.Remaining))
+	}
+	if e.Status.Limit >= 0 {
+		details = append(details, fmt.Sprintf("limit=%d", e.Status.Limit))
+	}
+	return fmt.Sprintf("%s: %s", ErrRateLimited, strings.Join(details, " "))
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
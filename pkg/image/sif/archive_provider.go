@@ -3,6 +3,8 @@ package sif
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
 
 	"github.com/google/go-containerregistry/pkg/v1/partial"
 
@@ -13,18 +15,20 @@ import (
 const ProviderName = image.SingularitySource
 
 // NewArchiveProvider creates a new provider instance for the Singularity Image Format (SIF) image
-// at path.
-func NewArchiveProvider(tmpDirGen *file.TempDirGenerator, path string) image.Provider {
+// at path. Any additional metadata is applied to the image before it is read, overriding any provider defaults.
+func NewArchiveProvider(tmpDirGen *file.TempDirGenerator, path string, additionalMetadata ...image.AdditionalMetadata) image.Provider {
 	return &singularityImageProvider{
-		tmpDirGen: tmpDirGen,
-		path:      path,
+		tmpDirGen:          tmpDirGen,
+		path:               path,
+		additionalMetadata: additionalMetadata,
 	}
 }
 
 // singularityImageProvider is an image.Provider for a Singularity Image Format (SIF) image.
 type singularityImageProvider struct {
-	tmpDirGen *file.TempDirGenerator
-	path      string
+	tmpDirGen          *file.TempDirGenerator
+	path               string
+	additionalMetadata []image.AdditionalMetadata
 }
 
 func (p *singularityImageProvider) Name() string {
@@ -32,7 +36,11 @@ func (p *singularityImageProvider) Name() string {
 }
 
 // Provide returns an Image that represents a Singularity Image Format (SIF) image.
-func (p *singularityImageProvider) Provide(_ context.Context) (*image.Image, error) {
+func (p *singularityImageProvider) Provide(ctx context.Context) (*image.Image, error) {
+	if fi, err := os.Stat(p.path); err != nil || fi.IsDir() {
+		return nil, fmt.Errorf("%w: %q is not a file", image.ErrProviderNotApplicable, p.path)
+	}
+
 	// We need to map the SIF to a GGCR v1.Image. Start with an implementation of the GGCR
 	// partial.UncompressedImageCore interface.
 	si, err := newSIFImage(p.path)
@@ -57,9 +65,10 @@ func (p *singularityImageProvider) Provide(_ context.Context) (*image.Image, err
 		image.WithOS("linux"),
 		image.WithArchitecture(si.arch, ""),
 	}
+	metadata = append(metadata, p.additionalMetadata...)
 
 	out := image.New(ui, p.tmpDirGen, contentCacheDir, metadata...)
-	err = out.Read()
+	err = out.ReadWithContext(ctx)
 	if err != nil {
 		cleanErr := out.Cleanup()
 		return nil, errors.Join(err, cleanErr)
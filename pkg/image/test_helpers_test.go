@@ -0,0 +1,68 @@
+package image
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"sort"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anchore/stereoscope/pkg/file"
+)
+
+// tarLayer creates an in-memory (gzip compressed) layer containing a regular file for each of the given paths.
+func tarLayer(t *testing.T, files map[string]string) v1.Layer {
+	t.Helper()
+	var entries []tar.Header
+	for name := range files {
+		entries = append(entries, tar.Header{Name: name, Mode: 0644, Typeflag: tar.TypeReg})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return tarLayerFromHeaders(t, entries, files)
+}
+
+// tarLayerFromHeaders creates an in-memory (gzip compressed) layer with an entry for each of the given headers (in
+// order). The size of each entry is set from the given contents (keyed by entry name).
+func tarLayerFromHeaders(t *testing.T, entries []tar.Header, contents map[string]string) v1.Layer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range entries {
+		data := contents[hdr.Name]
+		hdr.Size = int64(len(data))
+		require.NoError(t, tw.WriteHeader(&hdr))
+		_, err := tw.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	})
+	require.NoError(t, err)
+	return layer
+}
+
+// readTestImage creates an image from the given layers and reads it, cleaning up any temporary files once the test
+// completes.
+func readTestImage(t *testing.T, layers []v1.Layer, metadata ...AdditionalMetadata) (*Image, error) {
+	t.Helper()
+	img, err := mutate.AppendLayers(empty.Image, layers...)
+	require.NoError(t, err)
+
+	tmpDirGen := file.NewTempDirGenerator("stereoscope-image-test")
+	t.Cleanup(func() {
+		require.NoError(t, tmpDirGen.Cleanup())
+	})
+
+	out := New(img, tmpDirGen, t.TempDir(), metadata...)
+	return out, out.Read()
+}
@@ -0,0 +1,98 @@
+package image
+
+import (
+	"archive/tar"
+	"path"
+	"strings"
+)
+
+const (
+	// windowsFilesDir is the directory within a Windows layer tar that holds the container filesystem content.
+	windowsFilesDir = "Files"
+	// windowsHivesDir is the directory within a Windows layer tar that holds registry hive deltas.
+	windowsHivesDir = "Hives"
+)
+
+// windowsLayerDetector determines if a layer uses the Windows layer layout, either from the OS of the image (when
+// known) or from the first entry of the layer tar otherwise.
+type windowsLayerDetector struct {
+	os       string
+	detected bool
+	windows  bool
+}
+
+func newWindowsLayerDetector(os string) *windowsLayerDetector {
+	return &windowsLayerDetector{os: os}
+}
+
+// observe returns true if the layer the given tar entry belongs to is a Windows layer.
+func (d *windowsLayerDetector) observe(name string) bool {
+	if d.detected {
+		return d.windows
+	}
+	d.detected = true
+	switch d.os {
+	case "":
+		d.windows = isWindowsLayoutEntry(name)
+	default:
+		d.windows = strings.EqualFold(d.os, "windows")
+	}
+	return d.windows
+}
+
+// isWindowsLayoutEntry returns true if the given tar entry name is a top-level entry of the Windows layer layout.
+func isWindowsLayoutEntry(name string) bool {
+	first, _, _ := strings.Cut(cleanWindowsTarPath(name), "/")
+	return strings.EqualFold(first, windowsFilesDir) || strings.EqualFold(first, windowsHivesDir)
+}
+
+// normalizeWindowsHeader rewrites the given tar header of a Windows layer such that the content under `Files/` is
+// relative to the root of the tree. Returns false if the entry is not part of the container filesystem (e.g. registry
+// hives, the `Files/` directory itself, or utility VM content) and should not be indexed.
+func normalizeWindowsHeader(header *tar.Header) bool {
+	name, ok := windowsLayerPath(header.Name)
+	if !ok {
+		return false
+	}
+	header.Name = name
+
+	switch header.Typeflag {
+	case tar.TypeLink:
+		// hardlinks are relative to the root of the tar, so are also found under `Files/`
+		if dest, ok := windowsLayerPath(header.Linkname); ok {
+			header.Linkname = "/" + dest
+		}
+	case tar.TypeSymlink:
+		header.Linkname = windowsLinkDestination(header.Linkname)
+	}
+	return true
+}
+
+// windowsLayerPath returns the path of the given tar entry name relative to the `Files/` directory of a Windows layer.
+func windowsLayerPath(name string) (string, bool) {
+	first, rest, _ := strings.Cut(cleanWindowsTarPath(name), "/")
+	if !strings.EqualFold(first, windowsFilesDir) || rest == "" {
+		return "", false
+	}
+	return rest, true
+}
+
+// windowsLinkDestination converts a Windows symlink target (e.g. `C:\Windows\System32`) into a tree path.
+func windowsLinkDestination(dest string) string {
+	dest = strings.ReplaceAll(dest, `\`, "/")
+	if len(dest) >= 2 && dest[1] == ':' && isDriveLetter(dest[0]) {
+		// absolute paths are relative to the system drive, which is the root of the container filesystem
+		return path.Clean("/" + dest[2:])
+	}
+	return dest
+}
+
+func cleanWindowsTarPath(name string) string {
+	name = strings.ReplaceAll(name, `\`, "/")
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	return name
+}
+
+func isDriveLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
@@ -0,0 +1,111 @@
+package image
+
+import (
+	"archive/tar"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anchore/stereoscope/pkg/file"
+)
+
+func windowsTestLayer(t *testing.T) v1.Layer {
+	t.Helper()
+	return tarLayerFromHeaders(t, []tar.Header{
+		{Name: "Files", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "Files/Windows", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "Files/Windows/System32", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "Files/Windows/System32/kernel32.dll", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "Files/Windows/System32/hardlink.dll", Typeflag: tar.TypeLink, Linkname: "Files/Windows/System32/kernel32.dll"},
+		{Name: "Files/Users/Public/link", Typeflag: tar.TypeSymlink, Linkname: `C:\Windows\System32`},
+		{Name: "Hives", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "Hives/Software_Delta", Typeflag: tar.TypeReg, Mode: 0644},
+		{Name: "UtilityVM/Files/EFI/boot.efi", Typeflag: tar.TypeReg, Mode: 0644},
+	}, map[string]string{
+		"Files/Windows/System32/kernel32.dll": "kernel32",
+		"Hives/Software_Delta":                "registry",
+	})
+}
+
+func TestWindowsLayer(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata []AdditionalMetadata
+	}{
+		{
+			name:     "detected from image OS",
+			metadata: []AdditionalMetadata{WithOS("windows")},
+		},
+		{
+			name: "detected from layer layout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := readTestImage(t, []v1.Layer{windowsTestLayer(t)}, tt.metadata...)
+			require.NoError(t, err)
+
+			require.True(t, img.Layers[0].Windows)
+			assert.ElementsMatch(t, []file.Path{
+				"/",
+				"/Windows",
+				"/Windows/System32",
+				"/Windows/System32/kernel32.dll",
+				"/Windows/System32/hardlink.dll",
+				"/Users",
+				"/Users/Public",
+				"/Users/Public/link",
+			}, img.SquashedTree().AllRealPaths())
+
+			// lookups are case-insensitive
+			contents, err := img.OpenPathFromSquash("/WINDOWS/system32/Kernel32.DLL")
+			require.NoError(t, err)
+			require.NoError(t, contents.Close())
+
+			matches, err := img.SquashedTree().FilesByGlob("/windows/**/*.DLL")
+			require.NoError(t, err)
+			assert.Len(t, matches, 2)
+
+			// Windows link destinations are converted into tree paths
+			_, resolution, err := img.SquashedTree().File("/users/public/LINK/kernel32.dll")
+			require.NoError(t, err)
+			require.NotNil(t, resolution)
+			assert.Equal(t, file.Path("/Windows/System32/kernel32.dll"), resolution.Reference.RealPath)
+
+			_, hardlink, err := img.SquashedTree().File("/Windows/System32/hardlink.dll")
+			require.NoError(t, err)
+			require.NotNil(t, hardlink)
+			entry, err := img.FileCatalog.Get(*hardlink.Reference)
+			require.NoError(t, err)
+			assert.Equal(t, "/Windows/System32/kernel32.dll", entry.LinkDestination)
+		})
+	}
+}
+
+func TestWindowsLayer_LinuxImage(t *testing.T) {
+	img, err := readTestImage(t, []v1.Layer{windowsTestLayer(t)}, WithOS("linux"))
+	require.NoError(t, err)
+
+	assert.False(t, img.Layers[0].Windows)
+	assert.True(t, img.SquashedTree().HasPath("/Files/Windows/System32/kernel32.dll"))
+	assert.False(t, img.SquashedTree().HasPath("/files/windows/system32/kernel32.dll"))
+}
+
+func TestWindowsLinkDestination(t *testing.T) {
+	tests := []struct {
+		dest string
+		want string
+	}{
+		{dest: `C:\Windows\System32`, want: "/Windows/System32"},
+		{dest: `c:/Windows`, want: "/Windows"},
+		{dest: `..\System32\kernel32.dll`, want: "../System32/kernel32.dll"},
+		{dest: "/already/a/path", want: "/already/a/path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dest, func(t *testing.T) {
+			assert.Equal(t, tt.want, windowsLinkDestination(tt.dest))
+		})
+	}
+}
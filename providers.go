@@ -1,8 +1,15 @@
 package stereoscope
 
 import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
 	"github.com/anchore/go-collections"
 	containerdClient "github.com/anchore/stereoscope/internal/containerd"
+	"github.com/anchore/stereoscope/pkg/file"
 	"github.com/anchore/stereoscope/pkg/image"
 	"github.com/anchore/stereoscope/pkg/image/containerd"
 	"github.com/anchore/stereoscope/pkg/image/docker"
@@ -19,29 +26,146 @@ const (
 	RegistryTag = "registry"
 )
 
+// Provider priorities used for ordering providers during auto-detection; providers with lower values are attempted
+// first. Providers that share the same priority are attempted in the order they were registered (built-in providers
+// are always registered first).
+const (
+	FileProviderPriority     = 100
+	DaemonProviderPriority   = 200
+	RegistryProviderPriority = 300
+)
+
+// ImageProviderFactory creates an image.Provider for the given user configuration. Any temporary content created by
+// the provider should be created with the given temp dir generator. Note: factories may be invoked with an empty
+// configuration (e.g. when determining the set of all known provider tags) and should not fail in this case.
+type ImageProviderFactory func(tempDirGenerator *file.TempDirGenerator, cfg ImageProviderConfig) image.Provider
+
+type registeredProvider struct {
+	factory  ImageProviderFactory
+	priority int
+	tags     []string
+}
+
+var providerRegistry = struct {
+	sync.RWMutex
+	providers []registeredProvider
+}{}
+
+// RegisterImageProvider adds a provider to the set of providers returned by ImageProviders (and therefore used by
+// GetImage and GetImageFromSource). The provider name is always added as a tag, which makes the name usable as a
+// source scheme (e.g. "my-store:some/image:latest") as well as a source for GetImageFromSource. Additional tags may
+// be given to participate in existing tag selection (e.g. PullTag). Since source schemes are matched case-insensitively,
+// provider names should be lowercase.
+func RegisterImageProvider(priority int, factory ImageProviderFactory, tags ...string) error {
+	if factory == nil {
+		return fmt.Errorf("no image provider factory given")
+	}
+
+	providerRegistry.Lock()
+	defer providerRegistry.Unlock()
+
+	var normalizedTags []string
+	for _, tag := range tags {
+		normalizedTags = append(normalizedTags, strings.ToLower(strings.TrimSpace(tag)))
+	}
+
+	providerRegistry.providers = append(providerRegistry.providers, registeredProvider{
+		factory:  factory,
+		priority: priority,
+		tags:     normalizedTags,
+	})
+	return nil
+}
+
 // ImageProviderConfig is the user-configuration containing all configuration needed by stereoscope image providers
 type ImageProviderConfig struct {
 	UserInput string
 	Platform  *image.Platform
 	Registry  image.RegistryOptions
+
+	// Offline indicates that providers must not access the network. Providers tagged with RegistryTag are excluded
+	// when offline, all other registered providers are expected to honor this themselves.
+	Offline bool
+
+	// PullPolicy determines when daemon providers pull the image into the daemon (image.PullIfMissing by default).
+	PullPolicy image.PullPolicy
+
+	// RemovePulledImages removes images from the daemon after they have been read when the daemon provider pulled them.
+	RemovePulledImages bool
+
+	// ReadOptions are applied by providers to the image before it is read (e.g. image.WithReadLimits), overriding any
+	// provider defaults. Unlike additional metadata given to GetImage, these affect how the image content is read.
+	ReadOptions []image.AdditionalMetadata
 }
 
+// ImageProviders returns all built-in and registered image providers for the given configuration, ordered by priority.
+// When offline, network-capable providers (those tagged with RegistryTag) are excluded.
 func ImageProviders(cfg ImageProviderConfig) []collections.TaggedValue[image.Provider] {
 	tempDirGenerator := rootTempDirGenerator.NewGenerator()
-	return []collections.TaggedValue[image.Provider]{
+
+	daemonOptions := []docker.DaemonProviderOption{
+		docker.WithPullPolicy(cfg.PullPolicy),
+		docker.WithAdditionalMetadata(cfg.ReadOptions...),
+	}
+	containerdOptions := []containerd.DaemonProviderOption{
+		containerd.WithPullPolicy(cfg.PullPolicy),
+		containerd.WithAdditionalMetadata(cfg.ReadOptions...),
+	}
+	if cfg.Offline {
+		cfg.Registry.Offline = true
+		daemonOptions = append(daemonOptions, docker.WithOffline())
+	}
+	if cfg.RemovePulledImages {
+		daemonOptions = append(daemonOptions, docker.WithRemovePulledImage())
+		containerdOptions = append(containerdOptions, containerd.WithRemovePulledImage())
+	}
+
+	providers := []registeredProvider{
 		// file providers
-		taggedProvider(docker.NewArchiveProvider(tempDirGenerator, cfg.UserInput), FileTag),
-		taggedProvider(oci.NewArchiveProviderWithPlatform(tempDirGenerator, cfg.UserInput, cfg.Platform), FileTag),
-		taggedProvider(oci.NewDirectoryProviderWithPlatform(tempDirGenerator, cfg.UserInput, cfg.Platform), FileTag, DirTag),
-		taggedProvider(sif.NewArchiveProvider(tempDirGenerator, cfg.UserInput), FileTag),
+		builtinProvider(FileProviderPriority, docker.NewArchiveProvider(tempDirGenerator, cfg.UserInput, cfg.ReadOptions...), FileTag),
+		builtinProvider(FileProviderPriority, oci.NewArchiveProviderWithPlatform(tempDirGenerator, cfg.UserInput, cfg.Platform, cfg.ReadOptions...), FileTag),
+		builtinProvider(FileProviderPriority, oci.NewDirectoryProviderWithPlatform(tempDirGenerator, cfg.UserInput, cfg.Platform, cfg.ReadOptions...), FileTag, DirTag),
+		builtinProvider(FileProviderPriority, sif.NewArchiveProvider(tempDirGenerator, cfg.UserInput, cfg.ReadOptions...), FileTag),
 
 		// daemon providers
-		taggedProvider(docker.NewDaemonProvider(tempDirGenerator, cfg.UserInput, cfg.Platform), DaemonTag, PullTag),
-		taggedProvider(podman.NewDaemonProvider(tempDirGenerator, cfg.UserInput, cfg.Platform), DaemonTag, PullTag),
-		taggedProvider(containerd.NewDaemonProvider(tempDirGenerator, cfg.Registry, containerdClient.Namespace(), cfg.UserInput, cfg.Platform), DaemonTag, PullTag),
+		builtinProvider(DaemonProviderPriority, docker.NewDaemonProvider(tempDirGenerator, cfg.UserInput, cfg.Platform, daemonOptions...), DaemonTag, PullTag),
+		builtinProvider(DaemonProviderPriority, podman.NewDaemonProvider(tempDirGenerator, cfg.UserInput, cfg.Platform, daemonOptions...), DaemonTag, PullTag),
+		builtinProvider(DaemonProviderPriority, containerd.NewDaemonProvider(tempDirGenerator, cfg.Registry, containerdClient.Namespace(), cfg.UserInput, cfg.Platform, containerdOptions...), DaemonTag, PullTag),
 
 		// registry providers
-		taggedProvider(oci.NewRegistryProvider(tempDirGenerator, cfg.Registry, cfg.UserInput, cfg.Platform), RegistryTag, PullTag),
+		builtinProvider(RegistryProviderPriority, oci.NewRegistryProvider(tempDirGenerator, cfg.Registry, cfg.UserInput, cfg.Platform, cfg.ReadOptions...), RegistryTag, PullTag),
+	}
+
+	providerRegistry.RLock()
+	providers = append(providers, providerRegistry.providers...)
+	providerRegistry.RUnlock()
+
+	sort.SliceStable(providers, func(i, j int) bool {
+		return providers[i].priority < providers[j].priority
+	})
+
+	var results []collections.TaggedValue[image.Provider]
+	for _, p := range providers {
+		if cfg.Offline && slices.Contains(p.tags, RegistryTag) {
+			continue
+		}
+		provider := p.factory(tempDirGenerator, cfg)
+		if provider == nil {
+			continue
+		}
+		results = append(results, taggedProvider(provider, p.tags...))
+	}
+	return results
+}
+
+// builtinProvider wraps an already constructed built-in provider so that it can be ordered with registered providers.
+func builtinProvider(priority int, provider image.Provider, tags ...string) registeredProvider {
+	return registeredProvider{
+		factory: func(*file.TempDirGenerator, ImageProviderConfig) image.Provider {
+			return provider
+		},
+		priority: priority,
+		tags:     tags,
 	}
 }
 
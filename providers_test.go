@@ -0,0 +1,277 @@
+package stereoscope
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anchore/stereoscope/pkg/file"
+	"github.com/anchore/stereoscope/pkg/image"
+	"github.com/anchore/stereoscope/pkg/image/docker"
+)
+
+type fakeProvider struct {
+	name string
+	img  *image.Image
+	err  error
+}
+
+func (p fakeProvider) Name() string {
+	return p.name
+}
+
+func (p fakeProvider) Provide(context.Context) (*image.Image, error) {
+	return p.img, p.err
+}
+
+func resetProviderRegistry(t *testing.T) {
+	t.Helper()
+	original := providerRegistry.providers
+	t.Cleanup(func() {
+		providerRegistry.providers = original
+	})
+}
+
+func TestRegisterImageProvider_Ordering(t *testing.T) {
+	resetProviderRegistry(t)
+
+	newFactory := func(name string) ImageProviderFactory {
+		return func(*file.TempDirGenerator, ImageProviderConfig) image.Provider {
+			return fakeProvider{name: name}
+		}
+	}
+
+	require.NoError(t, RegisterImageProvider(0, newFactory("first")))
+	require.NoError(t, RegisterImageProvider(DaemonProviderPriority, newFactory("after-daemons"), "Custom"))
+	require.NoError(t, RegisterImageProvider(RegistryProviderPriority+1, newFactory("last"), PullTag))
+
+	var names []string
+	for _, p := range ImageProviders(ImageProviderConfig{UserInput: "some-input"}) {
+		names = append(names, p.Value.Name())
+	}
+
+	assert.Equal(t, []string{
+		"first",
+		image.DockerTarballSource,
+		image.OciTarballSource,
+		image.OciDirectorySource,
+		image.SingularitySource,
+		image.DockerDaemonSource,
+		image.PodmanDaemonSource,
+		image.ContainerdDaemonSource,
+		"after-daemons",
+		image.OciRegistrySource,
+		"last",
+	}, names)
+
+	tags := allProviderTags()
+	assert.Contains(t, tags, "first")
+	assert.Contains(t, tags, "custom")
+	assert.Contains(t, tags, "last")
+}
+
+func TestRegisterImageProvider_CustomScheme(t *testing.T) {
+	resetProviderRegistry(t)
+
+	providerErr := errors.New("not in the artifact store")
+	var gotInput string
+	require.NoError(t, RegisterImageProvider(FileProviderPriority, func(_ *file.TempDirGenerator, cfg ImageProviderConfig) image.Provider {
+		gotInput = cfg.UserInput
+		return fakeProvider{name: "s3cache", err: providerErr}
+	}))
+
+	_, err := GetImage(context.Background(), "s3cache:some/image:latest")
+	require.ErrorIs(t, err, providerErr)
+	assert.Equal(t, "some/image:latest", gotInput)
+}
+
+func TestRegisterImageProvider_NilFactory(t *testing.T) {
+	require.Error(t, RegisterImageProvider(0, nil))
+}
+
+func TestGetImage_ProviderResolutionError(t *testing.T) {
+	resetProviderRegistry(t)
+
+	unauthorized := errors.New("401 unauthorized")
+	register := func(name string, err error) {
+		require.NoError(t, RegisterImageProvider(FileProviderPriority, func(*file.TempDirGenerator, ImageProviderConfig) image.Provider {
+			return fakeProvider{name: name, err: err}
+		}, "fake"))
+	}
+	register("not-applicable", fmt.Errorf("%w: not a file", image.ErrProviderNotApplicable))
+	register("unauthorized", unauthorized)
+
+	_, err := GetImageFromSource(context.Background(), "some/image:latest", "fake")
+	require.ErrorIs(t, err, unauthorized)
+
+	var resolutionErr *image.ErrProviderResolution
+	require.ErrorAs(t, err, &resolutionErr)
+
+	report := resolutionErr.Report
+	assert.Equal(t, "some/image:latest", report.UserInput)
+	assert.Equal(t, "fake", report.Source)
+	require.Len(t, report.Attempts, 2)
+	assert.Equal(t, "not-applicable", report.Attempts[0].Provider)
+	assert.False(t, report.Attempts[0].Applicable)
+	assert.Equal(t, "unauthorized", report.Attempts[1].Provider)
+	assert.True(t, report.Attempts[1].Applicable)
+	assert.Equal(t, unauthorized, report.DecisiveError())
+}
+
+func TestImageProviders_Offline(t *testing.T) {
+	resetProviderRegistry(t)
+
+	var gotOffline bool
+	require.NoError(t, RegisterImageProvider(0, func(_ *file.TempDirGenerator, cfg ImageProviderConfig) image.Provider {
+		gotOffline = cfg.Offline
+		return fakeProvider{name: "local-store"}
+	}))
+	require.NoError(t, RegisterImageProvider(RegistryProviderPriority, func(*file.TempDirGenerator, ImageProviderConfig) image.Provider {
+		return fakeProvider{name: "remote-store"}
+	}, RegistryTag))
+
+	var names []string
+	for _, p := range ImageProviders(ImageProviderConfig{UserInput: "some-input", Offline: true}) {
+		names = append(names, p.Value.Name())
+	}
+
+	assert.True(t, gotOffline)
+	assert.Equal(t, []string{
+		"local-store",
+		image.DockerTarballSource,
+		image.OciTarballSource,
+		image.OciDirectorySource,
+		image.SingularitySource,
+		image.DockerDaemonSource,
+		image.PodmanDaemonSource,
+		image.ContainerdDaemonSource,
+	}, names)
+}
+
+func TestGetImageFromSource_Offline(t *testing.T) {
+	_, err := GetImageFromSource(context.Background(), "some/image:latest", RegistryTag, WithOffline())
+	require.ErrorIs(t, err, image.ErrOffline)
+
+	_, err = GetImageFromSource(context.Background(), "some/image:latest", "not-a-source", WithOffline())
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, image.ErrOffline)
+}
+
+func TestGetImageFromSource_PullPolicy(t *testing.T) {
+	resetProviderRegistry(t)
+
+	var got ImageProviderConfig
+	require.NoError(t, RegisterImageProvider(0, func(_ *file.TempDirGenerator, cfg ImageProviderConfig) image.Provider {
+		got = cfg
+		return fakeProvider{name: "local-store"}
+	}))
+
+	_, err := GetImageFromSource(context.Background(), "some/image:latest", "local-store", WithPullPolicy(image.PullNever), WithRemovePulledImages())
+	require.Error(t, err)
+	assert.Equal(t, image.PullNever, got.PullPolicy)
+	assert.True(t, got.RemovePulledImages)
+
+	_, err = GetImageFromSource(context.Background(), "some/image:latest", "local-store", WithPullPolicy("sometimes"))
+	require.ErrorContains(t, err, "invalid pull policy")
+}
+
+func TestGetImageFromSource_RequireDigest(t *testing.T) {
+	resetProviderRegistry(t)
+
+	const pinnedDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+	require.NoError(t, RegisterImageProvider(0, func(*file.TempDirGenerator, ImageProviderConfig) image.Provider {
+		return fakeProvider{name: "pinned-store", img: &image.Image{Metadata: image.Metadata{ManifestDigest: pinnedDigest}}}
+	}))
+	require.NoError(t, RegisterImageProvider(0, func(*file.TempDirGenerator, ImageProviderConfig) image.Provider {
+		return fakeProvider{name: "file-store", img: &image.Image{}}
+	}, FileTag))
+
+	img, err := GetImageFromSource(context.Background(), "alpine@"+pinnedDigest, "pinned-store", WithRequireDigest())
+	require.NoError(t, err)
+	require.NotNil(t, img)
+
+	_, err = GetImageFromSource(context.Background(), "alpine:latest", "pinned-store", WithRequireDigest())
+	require.ErrorIs(t, err, image.ErrReferenceNotPinned)
+
+	// file providers are given paths rather than references, so pinning does not apply
+	img, err = GetImageFromSource(context.Background(), "some/image.tar", "file-store", WithRequireDigest())
+	require.NoError(t, err)
+	require.NotNil(t, img)
+}
+
+func TestGetImageFromSource_ReadOptions(t *testing.T) {
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+
+	archive := filepath.Join(t.TempDir(), "image.tar")
+	ref, err := name.ParseReference("stereoscope/read-options:latest")
+	require.NoError(t, err)
+	require.NoError(t, tarball.WriteToFile(archive, ref, img))
+
+	getImage := func(t *testing.T, options ...Option) (*image.Image, error) {
+		t.Helper()
+		result, err := GetImageFromSource(context.Background(), archive, docker.Archive, options...)
+		if result != nil {
+			t.Cleanup(func() {
+				require.NoError(t, result.Cleanup())
+			})
+		}
+		return result, err
+	}
+
+	fileIDs := func(img *image.Image) []file.ID {
+		var ids []file.ID
+		for _, ref := range img.SquashedTree().AllFiles(file.TypeRegular) {
+			ids = append(ids, ref.ID())
+		}
+		return ids
+	}
+
+	t.Run("read limits are applied before reading", func(t *testing.T) {
+		_, err := getImage(t, WithReadLimits(image.ReadLimits{MaxFiles: 1}))
+		var limitErr *image.ErrReadLimitExceeded
+		require.ErrorAs(t, err, &limitErr)
+		assert.Equal(t, image.FileCountReadLimit, limitErr.Limit)
+	})
+
+	t.Run("derived file IDs are applied before reading", func(t *testing.T) {
+		first, err := getImage(t, WithDerivedFileIDs())
+		require.NoError(t, err)
+		second, err := getImage(t, WithDerivedFileIDs())
+		require.NoError(t, err)
+		assert.NotEmpty(t, fileIDs(first))
+		assert.ElementsMatch(t, fileIDs(first), fileIDs(second))
+	})
+
+	t.Run("layer verification is applied before reading", func(t *testing.T) {
+		_, err := getImage(t, WithLayerVerification(image.LayerVerification{DiffIDs: true, Digests: true}))
+		require.NoError(t, err)
+	})
+}
+
+func TestGetImageFromSource_ReadOptionsConfig(t *testing.T) {
+	resetProviderRegistry(t)
+
+	var got ImageProviderConfig
+	require.NoError(t, RegisterImageProvider(0, func(_ *file.TempDirGenerator, cfg ImageProviderConfig) image.Provider {
+		got = cfg
+		return fakeProvider{name: "local-store"}
+	}))
+
+	_, err := GetImageFromSource(context.Background(), "some/image:latest", "local-store",
+		WithReadLimits(image.ReadLimits{MaxFiles: 1}),
+		WithDerivedFileIDs(),
+		WithLayerVerification(image.LayerVerification{}),
+	)
+	require.Error(t, err)
+	assert.Len(t, got.ReadOptions, 3)
+}